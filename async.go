@@ -0,0 +1,262 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// errClosed is returned when writing to a closed AsyncWriter.
+var errClosed = errors.New("dbglog: write to closed AsyncWriter")
+
+// defaultQueue is the queue size of an AsyncWriter unless one is provided.
+const defaultQueue = 1024
+
+// defaultNotice is the default interval of the dropped messages notice.
+const defaultNotice = 10 * time.Second
+
+// AsyncOption configures an AsyncWriter.
+type AsyncOption func(*AsyncWriter)
+
+// NonBlocking makes an AsyncWriter drop messages when its queue is full instead
+// of making the writer wait.  Dropped messages are counted and reported with a
+// "dropped N debug messages" notice.
+func NonBlocking() AsyncOption {
+	return func(a *AsyncWriter) {
+		a.nonBlocking = true
+	}
+}
+
+// DropNotice sets how often the dropped messages notice of a non-blocking
+// AsyncWriter is written at most, the default is every 10 seconds.
+func DropNotice(interval time.Duration) AsyncOption {
+	return func(a *AsyncWriter) {
+		a.notice = interval
+	}
+}
+
+// asyncMsg is a queued message of an AsyncWriter.
+type asyncMsg struct {
+	e    *Entry        // entry for a Backend, nil for Write
+	line []byte        // rendered message
+	done chan struct{} // closed when reached, for Flush
+}
+
+// AsyncWriter hands messages to a background goroutine that writes them to an
+// underlying writer so that callers do not wait for slow outputs, such as
+// disks or NFS.  Messages are queued in a bounded queue, a full queue makes
+// writers wait unless the writer is NonBlocking.  It is created with
+// NewAsyncWriter and is used as the output of a DbgLogger, i.e.
+//
+//	a := dbglog.NewAsyncWriter(f, 0)
+//	defer a.Close()
+//	d := dbglog.New(a, "app ", log.LstdFlags)
+//
+// Errors of the underlying writer are returned by the next Flush or Close.
+type AsyncWriter struct {
+	w           io.Writer
+	q           chan asyncMsg
+	stopped     chan struct{} // closed when the goroutine exits
+	nonBlocking bool          // drop messages when q is full
+	notice      time.Duration // interval of the dropped messages notice
+	dropped     atomic.Uint64 // messages dropped because q was full
+
+	mu     sync.RWMutex // protects closed, held for reading while queueing
+	closed bool
+
+	emu sync.Mutex // protects err
+	err error      // first write error since the last Flush
+}
+
+// NewAsyncWriter returns an AsyncWriter that writes to w and queues up to size
+// messages.  A size of 0 or less uses a queue of 1024 messages.  When w is a
+// Backend the entries are handed to it as well.
+func NewAsyncWriter(w io.Writer, size int, opts ...AsyncOption) *AsyncWriter {
+	if size <= 0 {
+		size = defaultQueue
+	}
+	a := &AsyncWriter{
+		w:       w,
+		q:       make(chan asyncMsg, size),
+		stopped: make(chan struct{}),
+		notice:  defaultNotice,
+	}
+	for _, o := range opts {
+		o(a)
+	}
+	go a.run()
+	return a
+}
+
+// run writes the queued messages until the queue is closed.
+func (a *AsyncWriter) run() {
+	defer close(a.stopped)
+
+	var (
+		tick     <-chan time.Time
+		reported uint64
+	)
+	if a.nonBlocking && a.notice > 0 {
+		t := time.NewTicker(a.notice)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	b, _ := a.w.(Backend)
+	for {
+		select {
+		case <-tick:
+			reported = a.report(reported)
+		case m, ok := <-a.q:
+			if !ok {
+				a.report(reported)
+				return
+			}
+			if m.done != nil {
+				close(m.done)
+				continue
+			}
+			var err error
+			if m.e != nil && b != nil {
+				err = b.WriteEntry(m.e, m.line)
+			} else {
+				_, err = a.w.Write(m.line)
+			}
+			a.fail(err)
+		}
+	}
+}
+
+// fail records err if it is the first write error since the last Flush.
+func (a *AsyncWriter) fail(err error) {
+	if err == nil {
+		return
+	}
+	a.emu.Lock()
+	if a.err == nil {
+		a.err = err
+	}
+	a.emu.Unlock()
+}
+
+// report writes the dropped messages notice if messages were dropped since
+// reported and returns the new number of reported drops.
+func (a *AsyncWriter) report(reported uint64) uint64 {
+	n := a.dropped.Load()
+	if n == reported {
+		return n
+	}
+	_, err := a.w.Write([]byte("dbglog: dropped " +
+		strconv.FormatUint(n-reported, 10) + " debug messages\n"))
+	a.fail(err)
+	return n
+}
+
+// queue adds m to the queue.  When the queue is full it waits for room or,
+// when non-blocking, drops m.
+func (a *AsyncWriter) queue(m asyncMsg) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return errClosed
+	}
+	if !a.nonBlocking || m.done != nil {
+		a.q <- m
+		return nil
+	}
+	select {
+	case a.q <- m:
+	default:
+		a.dropped.Add(1)
+	}
+	return nil
+}
+
+// DroppedCount returns the number of messages that were dropped because the
+// queue was full.
+func (a *AsyncWriter) DroppedCount() uint64 {
+	return a.dropped.Load()
+}
+
+// takeErr returns and clears the pending write error.
+func (a *AsyncWriter) takeErr() error {
+	a.emu.Lock()
+	defer a.emu.Unlock()
+	err := a.err
+	a.err = nil
+	return err
+}
+
+// Write queues a copy of p.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	line := append([]byte(nil), p...)
+	if err := a.queue(asyncMsg{line: line}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteEntry queues a copy of e and line.
+func (a *AsyncWriter) WriteEntry(e *Entry, line []byte) error {
+	c := *e
+	c.Fields = append([]Field(nil), e.Fields...)
+	return a.queue(asyncMsg{e: &c, line: append([]byte(nil), line...)})
+}
+
+// Flush waits until all messages queued before it have been written and
+// returns the first write error since the previous Flush.
+func (a *AsyncWriter) Flush() error {
+	done := make(chan struct{})
+	if err := a.queue(asyncMsg{done: done}); err != nil {
+		return err
+	}
+	<-done
+	return a.takeErr()
+}
+
+// Sync flushes the queue and then Syncs the underlying writer if it can.
+func (a *AsyncWriter) Sync() error {
+	if err := a.Flush(); err != nil {
+		return err
+	}
+	if s, ok := a.w.(syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Close writes the queued messages and stops the background goroutine.  It
+// returns the first write error since the last Flush.  The underlying writer
+// is not closed.  Writes after Close fail.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.q)
+	a.mu.Unlock()
+
+	<-a.stopped
+	return a.takeErr()
+}
@@ -0,0 +1,160 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowWriter is a lockedBuffer that takes a while for every Write or fails
+// with err.
+type slowWriter struct {
+	lockedBuffer
+	delay time.Duration
+	err   error
+}
+
+func (s *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	if s.err != nil {
+		return 0, s.err
+	}
+	return s.lockedBuffer.Write(p)
+}
+
+func TestAsyncWriterFlush(t *testing.T) {
+	w := &slowWriter{delay: 5 * time.Millisecond}
+	a := NewAsyncWriter(w, 0)
+	defer a.Close()
+	for _, s := range []string{"a\n", "b\n", "c\n"} {
+		a.Write([]byte(s))
+	}
+	if err := a.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.String(); got != "a\nb\nc\n" {
+		t.Fatalf("got %q after Flush", got)
+	}
+}
+
+func TestAsyncWriterFlushError(t *testing.T) {
+	boom := errors.New("boom")
+	a := NewAsyncWriter(&slowWriter{err: boom}, 0)
+	defer a.Close()
+	a.Write([]byte("a\n"))
+	a.Write([]byte("b\n"))
+	if err := a.Flush(); err != boom {
+		t.Fatalf("got %v, want %v", err, boom)
+	}
+	if err := a.Flush(); err != nil {
+		t.Fatalf("error not cleared: %v", err)
+	}
+}
+
+func TestAsyncWriterClose(t *testing.T) {
+	w := &slowWriter{delay: 5 * time.Millisecond}
+	a := NewAsyncWriter(w, 0)
+	for _, s := range []string{"a\n", "b\n", "c\n"} {
+		a.Write([]byte(s))
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.String(); got != "a\nb\nc\n" {
+		t.Fatalf("not drained: %q", got)
+	}
+	if _, err := a.Write([]byte("late\n")); err == nil {
+		t.Fatal("write after Close succeeded")
+	}
+	if err := a.Flush(); err == nil {
+		t.Fatal("Flush after Close succeeded")
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
+
+func TestFatalfFlushes(t *testing.T) {
+	code := -1
+	osExit = func(c int) { code = c }
+	defer func() { osExit = os.Exit }()
+
+	w := &slowWriter{delay: 20 * time.Millisecond}
+	a := NewAsyncWriter(w, 0)
+	defer a.Close()
+	d := New(a, "", 0)
+	d.Fatalf("bye")
+	if code != 1 {
+		t.Fatalf("exit code %v", code)
+	}
+	if got := w.String(); !strings.Contains(got, "bye") {
+		t.Fatalf("message lost: %q", got)
+	}
+}
+
+// blockingWriter blocks every Write until release is closed and signals
+// entered when the first Write started.
+type blockingWriter struct {
+	lockedBuffer
+	entered chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (b *blockingWriter) Write(p []byte) (int, error) {
+	b.once.Do(func() { close(b.entered) })
+	<-b.release
+	return b.lockedBuffer.Write(p)
+}
+
+func TestAsyncWriterNonBlocking(t *testing.T) {
+	w := &blockingWriter{
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	a := NewAsyncWriter(w, 1, NonBlocking(), DropNotice(time.Millisecond))
+	a.Write([]byte("1\n"))
+	<-w.entered
+	// 2 fills the queue while the writer is stuck on 1.
+	for _, s := range []string{"2\n", "3\n", "4\n"} {
+		if _, err := a.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := a.DroppedCount(); n != 2 {
+		t.Fatalf("dropped %v, want 2", n)
+	}
+	close(w.release)
+
+	notice := "dbglog: dropped 2 debug messages\n"
+	waitFor(t, "notice", func() bool {
+		return strings.Contains(w.String(), notice)
+	})
+	a.Close()
+	// The notice races with 2 but is only written once.
+	got := w.String()
+	if got != "1\n2\n"+notice && got != "1\n"+notice+"2\n" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,31 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"io"
+)
+
+// Backend is an output that receives every message as an Entry along with the
+// rendered line, i.e. to map the level onto the severity of a log service.  A
+// Backend is used like any other io.Writer, with SetOutput, SetRoute or
+// SetBitOutput.  Its Write method receives output that does not come from the
+// Debug functions, such as that of d.Printf.
+type Backend interface {
+	io.Writer
+	WriteEntry(e *Entry, line []byte) error
+}
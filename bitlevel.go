@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// SetBitLevel sets the minimum level of the messages for bit, overriding
+// SetLevel, i.e. d.SetBitLevel(bitNet, LevelTrace) prints every TracefM for
+// bitNet while the other messages stay at the default LevelInfo.  A level
+// above LevelDebug also silences the Debug*M messages for bit even when it is
+// enabled in the mask.  A negative level removes the override.  bit must be a
+// single bit, other values are ignored.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) SetBitLevel(bit uint64, l Level) {
+	if bits.OnesCount64(bit) != 1 {
+		return
+	}
+	v := int32(0)
+	if l >= 0 {
+		v = int32(l) + 1
+	}
+	d.bitLevels[bits.TrailingZeros64(bit)].Store(v)
+}
+
+// ClearBitLevels removes all overrides set with SetBitLevel.
+func (d *DbgLogger) ClearBitLevels() {
+	for i := range d.bitLevels {
+		d.bitLevels[i].Store(0)
+	}
+}
+
+// bitLevel returns the lowest level set with SetBitLevel for the bits in bit.
+func (d *DbgLogger) bitLevel(bit uint64) (Level, bool) {
+	var (
+		l  Level
+		ok bool
+	)
+	for bit != 0 {
+		v := d.bitLevels[bits.TrailingZeros64(bit)].Load()
+		if v != 0 && (!ok || Level(v-1) < l) {
+			l, ok = Level(v-1), true
+		}
+		bit &= bit - 1
+	}
+	return l, ok
+}
+
+// minLevel returns the lowest level that is printed for any bit.
+func (d *DbgLogger) minLevel() Level {
+	l := d.GetLevel()
+	if bl, ok := d.bitLevel(DebugAll); ok && bl < l {
+		l = bl
+	}
+	return l
+}
+
+// levelOK reports whether messages of level l for bit are printed.
+func (d *DbgLogger) levelOK(l Level, bit uint64) bool {
+	if bl, ok := d.bitLevel(bit); ok {
+		return l >= bl
+	}
+	return l >= d.GetLevel()
+}
+
+// debugOK reports whether the level of bit, if set, allows debug messages.
+func (d *DbgLogger) debugOK(bit uint64) bool {
+	l, ok := d.bitLevel(bit)
+	return !ok || l <= LevelDebug
+}
+
+// logfM prints the message if l is at least the level for bit.
+func (d *DbgLogger) logfM(l Level, bit uint64, format string,
+	v ...interface{}) {
+	if !d.levelOK(l, bit) {
+		d.count(l, bit, false)
+		return
+	}
+	d.output(3, l, bit, fmt.Sprintf(format, v...))
+}
+
+// log.Printf equivalent but only prints when the level for bit is LevelTrace.
+func (d *DbgLogger) TracefM(bit uint64, format string, v ...interface{}) {
+	d.logfM(LevelTrace, bit, format, v...)
+}
+
+// log.Printf equivalent but only prints when the level for bit is LevelInfo or
+// lower.
+func (d *DbgLogger) InfofM(bit uint64, format string, v ...interface{}) {
+	d.logfM(LevelInfo, bit, format, v...)
+}
+
+// log.Printf equivalent but only prints when the level for bit is LevelWarn or
+// lower.
+func (d *DbgLogger) WarnfM(bit uint64, format string, v ...interface{}) {
+	d.logfM(LevelWarn, bit, format, v...)
+}
+
+// log.Printf equivalent but only prints when the level for bit is LevelError
+// or lower.
+func (d *DbgLogger) ErrorfM(bit uint64, format string, v ...interface{}) {
+	d.logfM(LevelError, bit, format, v...)
+}
+
+// log.Fatalf equivalent, prints when the level for bit is LevelFatal or lower
+// and then always calls os.Exit(1) like Fatalf.
+func (d *DbgLogger) FatalfM(bit uint64, format string, v ...interface{}) {
+	d.logfM(LevelFatal, bit, format, v...)
+	d.exit()
+}
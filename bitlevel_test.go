@@ -0,0 +1,120 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetBitLevel(t *testing.T) {
+	type set struct {
+		bit uint64
+		l   Level
+	}
+	tests := []struct {
+		name   string
+		levels []set
+		clear  bool
+		bit    uint64
+		want   string // t for TracefM through e for ErrorfM
+	}{
+		{name: "no override", bit: 2, want: "diwe"},
+		{
+			name:   "trace",
+			levels: []set{{2, LevelTrace}},
+			bit:    2,
+			want:   "tdiwe",
+		},
+		{
+			name:   "warn",
+			levels: []set{{2, LevelWarn}},
+			bit:    2,
+			want:   "we",
+		},
+		{
+			name:   "other bits unaffected",
+			levels: []set{{1, LevelTrace}, {4, LevelError}},
+			bit:    2,
+			want:   "diwe",
+		},
+		{
+			name:   "lowest of several bits",
+			levels: []set{{2, LevelTrace}, {4, LevelWarn}},
+			bit:    2 | 4,
+			want:   "tdiwe",
+		},
+		{
+			name:   "removed",
+			levels: []set{{2, LevelTrace}, {2, -1}},
+			bit:    2,
+			want:   "diwe",
+		},
+		{
+			name:   "several bits ignored",
+			levels: []set{{2 | 4, LevelTrace}},
+			bit:    2,
+			want:   "diwe",
+		},
+		{
+			name:   "cleared",
+			levels: []set{{2, LevelTrace}, {4, LevelWarn}},
+			clear:  true,
+			bit:    4,
+			want:   "diwe",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 1|2|4)
+			for _, s := range tt.levels {
+				d.SetBitLevel(s.bit, s.l)
+			}
+			if tt.clear {
+				d.ClearBitLevels()
+			}
+			d.TracefM(tt.bit, "t")
+			d.DebugfM(tt.bit, "d")
+			d.InfofM(tt.bit, "i")
+			d.WarnfM(tt.bit, "w")
+			d.ErrorfM(tt.bit, "e")
+			var got string
+			for _, l := range lines(b) {
+				got += l[len(l)-1:]
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q:\n%v", got, tt.want,
+					strings.TrimSpace(b.String()))
+			}
+		})
+	}
+}
+
+func TestSetBitLevelDefault(t *testing.T) {
+	d, b := newTestLogger("", 0, 1|2)
+	d.SetLevel(LevelError)
+	d.SetBitLevel(2, LevelInfo)
+	d.InfofM(1, "filtered")
+	d.InfofM(2, "printed")
+	d.Infof("filtered too")
+	want := "[INFO] printed\n"
+	if got := b.String(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
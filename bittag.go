@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+// BitTag selects where the name of the mask bit of a message is printed, see
+// SetBitTag.
+type BitTag int
+
+// Placements of the bit name, i.e. "[net]".
+const (
+	BitTagNone    BitTag = iota // not printed, the default
+	BitTagMessage               // in front of the message
+	BitTagLine                  // at the start of the line
+	BitTagEnd                   // at the end of the line
+)
+
+// SetBitTag sets where the registered name of the mask bit of a Debug*M
+// message is printed in the text format.  Bits without a name are printed in
+// hex.  The JSON and logfmt formats add a bit_name field unless the placement
+// is BitTagNone.  Messages without a mask bit are not tagged.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) SetBitTag(t BitTag) {
+	d.mu.Lock()
+	d.bitTag = t
+	d.mu.Unlock()
+}
+
+// bitLabel returns the name of bit or its hex value if it has none.
+func (d *DbgLogger) bitLabel(bit uint64) string {
+	if name := d.BitName(bit); name != "" {
+		return name
+	}
+	return maskString(bit)
+}
+
+// appendBitTag appends "[name]" of the bit of e to buf if it is printed at
+// placement t.  Must be called with mu held.
+func (d *DbgLogger) appendBitTag(e *Entry, t BitTag) {
+	if d.bitTag != t || e.Bit == 0 {
+		return
+	}
+	if t == BitTagEnd {
+		d.buf = append(d.buf, ' ')
+	}
+	d.buf = append(d.buf, '[')
+	d.buf = append(d.buf, d.bitLabel(e.Bit)...)
+	d.buf = append(d.buf, ']')
+	if t != BitTagEnd {
+		d.buf = append(d.buf, ' ')
+	}
+}
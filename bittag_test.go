@@ -0,0 +1,139 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetBitTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  BitTag
+		want []string
+	}{
+		{
+			name: "none",
+			tag:  BitTagNone,
+			want: []string{
+				"p named",
+				"p unnamed",
+				"p multi",
+				"p fields k=1",
+				"p no bit",
+			},
+		},
+		{
+			name: "message",
+			tag:  BitTagMessage,
+			want: []string{
+				"p [net] named",
+				"p [0x4] unnamed",
+				"p [0x5] multi",
+				"p [net] fields k=1",
+				"p no bit",
+			},
+		},
+		{
+			name: "line",
+			tag:  BitTagLine,
+			want: []string{
+				"[net] p named",
+				"[0x4] p unnamed",
+				"[0x5] p multi",
+				"[net] p fields k=1",
+				"p no bit",
+			},
+		},
+		{
+			name: "end",
+			tag:  BitTagEnd,
+			want: []string{
+				"p named [net]",
+				"p unnamed [0x4]",
+				"p multi [0x5]",
+				"p fields k=1 [net]",
+				"p no bit",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("p ", 0, 0)
+			net := d.RegisterBit("net")
+			d.SetMask(net | 4)
+			d.SetBitTag(tt.tag)
+			d.DebugfM(net, "named")
+			d.DebugfM(4, "unnamed")
+			d.DebugfM(net|4, "multi")
+			d.DebugwM(net, "fields", "k", 1)
+			d.Debugf("no bit")
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetBitTagLogfmt(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  BitTag
+		bit  uint64
+		want string
+	}{
+		{
+			name: "none",
+			tag:  BitTagNone,
+			bit:  1,
+			want: "level=debug bit=0x1 msg=m",
+		},
+		{
+			name: "named",
+			tag:  BitTagLine,
+			bit:  1,
+			want: "level=debug bit=0x1 bit_name=net msg=m",
+		},
+		{
+			name: "unnamed",
+			tag:  BitTagLine,
+			bit:  4,
+			want: "level=debug bit=0x4 bit_name=0x4 msg=m",
+		},
+		{
+			name: "multi",
+			tag:  BitTagEnd,
+			bit:  5,
+			want: "level=debug bit=0x5 bit_name=0x5 msg=m",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := formatLogger(FormatLogfmt, "", 0)
+			d.SetMask(d.RegisterBit("net") | 4)
+			d.SetBitTag(tt.tag)
+			d.DebugfM(tt.bit, "m")
+			want := "ts=2024-02-29T13:04:05Z " + tt.want + "\n"
+			if got := b.String(); got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+// DebugCompiled is false when the package is built with the dbglog_disabled
+// build tag.  The Debug functions then never print and reduce to empty
+// functions the compiler inlines away, which keeps debug code and, usually,
+// its strings out of release binaries:
+//
+//	go build -tags dbglog_disabled
+//
+// The leveled functions, i.e. Errorf, and the log.Logger functions are not
+// affected.  Guard expensive debug only code with DebugCompiled to have it
+// removed as well.
+const DebugCompiled = true
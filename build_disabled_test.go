@@ -0,0 +1,54 @@
+//go:build dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"io"
+	"testing"
+)
+
+// Arguments that must be boxed to be passed as an interface{}.
+var (
+	allocInt    = 123456
+	allocString = "hello"
+)
+
+func TestCompiledOutAllocs(t *testing.T) {
+	d := New(io.Discard, "", 0)
+	d.Enable()
+	d.SetMask(^uint64(0))
+	d.SetVerbosity(10)
+	n, s := allocInt, allocString
+	calls := []struct {
+		name string
+		f    func()
+	}{
+		{"Debugf", func() { d.Debugf("%v %v", n, s) }},
+		{"Debug", func() { d.Debug(n, s) }},
+		{"Debugln", func() { d.Debugln(n, s) }},
+		{"DebugfM", func() { d.DebugfM(2, "%v %v", n, s) }},
+		{"On", func() { d.On(2).Debugf("%v %v", n, s) }},
+		{"V", func() { d.V(3).Debugf("%v %v", n, s) }},
+	}
+	for _, c := range calls {
+		if a := testing.AllocsPerRun(100, c.f); a != 0 {
+			t.Errorf("%v: %v allocs per call", c.name, a)
+		}
+	}
+}
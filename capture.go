@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"strings"
+	"sync"
+)
+
+var _ Backend = (*CaptureLogger)(nil)
+
+// CaptureLogger is a Backend that keeps every message in memory so that tests
+// can assert on what was printed, i.e.
+//
+//	c := dbglog.NewCaptureLogger()
+//	d.SetOutput(c)
+//	...
+//	if c.Count(bitNet) != 2 { t.Fatal(c.Lines()) }
+type CaptureLogger struct {
+	mu      sync.Mutex // protects entries and lines
+	entries []Entry
+	lines   []string
+}
+
+// NewCaptureLogger returns an empty CaptureLogger.
+func NewCaptureLogger() *CaptureLogger {
+	return &CaptureLogger{}
+}
+
+// Write records output that does not come from the Debug functions, such as
+// that of d.Printf, as a LevelInfo entry with the line as its message.
+func (c *CaptureLogger) Write(p []byte) (int, error) {
+	line := strings.TrimSuffix(string(p), "\n")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, Entry{Level: LevelInfo, Message: line})
+	c.lines = append(c.lines, line)
+	return len(p), nil
+}
+
+// WriteEntry records e and its rendered line.
+func (c *CaptureLogger) WriteEntry(e *Entry, line []byte) error {
+	entry := *e
+	entry.Fields = append([]Field(nil), e.Fields...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, entry)
+	c.lines = append(c.lines, strings.TrimSuffix(string(line), "\n"))
+	return nil
+}
+
+// Entries returns the recorded entries, oldest first.
+func (c *CaptureLogger) Entries() []Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Entry(nil), c.entries...)
+}
+
+// Lines returns the recorded lines as rendered, without trailing newline.
+func (c *CaptureLogger) Lines() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.lines...)
+}
+
+// Contains reports whether the message of any recorded entry contains s.
+func (c *CaptureLogger) Contains(s string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.entries {
+		if strings.Contains(e.Message, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of recorded entries that were printed for bit, 0
+// counts the entries printed without a bit.
+func (c *CaptureLogger) Count(bit uint64) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var n int
+	for _, e := range c.entries {
+		if e.Bit == bit {
+			n++
+		}
+	}
+	return n
+}
+
+// Reset discards the recorded entries.
+func (c *CaptureLogger) Reset() {
+	c.mu.Lock()
+	c.entries = nil
+	c.lines = nil
+	c.mu.Unlock()
+}
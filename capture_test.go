@@ -0,0 +1,115 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestCaptureLogger(t *testing.T) {
+	c := NewCaptureLogger()
+	d := New(c, "p ", 0)
+	d.Enable()
+	d.SetMask(1 | 2)
+	d.DebugfM(1, "one")
+	d.DebugwM(2, "two", "k", "v")
+	d.DebugfM(1, "three")
+	d.Debugf("no bit")
+	d.Printf("printed")
+
+	want := []string{
+		"p one",
+		"p two k=v",
+		"p three",
+		"p no bit",
+		"p printed",
+	}
+	if got := c.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	es := c.Entries()
+	if len(es) != 5 {
+		t.Fatalf("got %v entries", len(es))
+	}
+	if e := es[1]; e.Bit != 2 || e.Message != "two" ||
+		!reflect.DeepEqual(e.Fields, []Field{{Key: "k", Value: "v"}}) {
+		t.Fatalf("got entry %+v", e)
+	}
+	if e := es[4]; e.Level != LevelInfo || e.Message != "p printed" {
+		t.Fatalf("got entry %+v", e)
+	}
+	for _, s := range []string{"one", "thr", "printed"} {
+		if !c.Contains(s) {
+			t.Fatalf("does not contain %q", s)
+		}
+	}
+	if c.Contains("k=v") {
+		t.Fatal("fields are not part of the message")
+	}
+	for bit, want := range map[uint64]int{0: 2, 1: 2, 2: 1, 4: 0} {
+		if got := c.Count(bit); got != want {
+			t.Fatalf("bit %v: got count %v, want %v", bit, got,
+				want)
+		}
+	}
+
+	c.Reset()
+	if len(c.Entries()) != 0 || len(c.Lines()) != 0 || c.Contains("one") ||
+		c.Count(1) != 0 {
+		t.Fatal("not reset")
+	}
+	d.DebugfM(1, "after reset")
+	want = []string{"p after reset"}
+	if got := c.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestCaptureLoggerConcurrent(t *testing.T) {
+	c := NewCaptureLogger()
+	d := New(c, "", 0)
+	d.Enable()
+	d.SetMask(1)
+	const n = 100
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				d.DebugfM(1, "message %v", j)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				c.Lines()
+				c.Entries()
+				c.Contains("message")
+				c.Count(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := c.Count(1); got != 4*n {
+		t.Fatalf("got %v entries, want %v", got, 4*n)
+	}
+}
@@ -0,0 +1,218 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Category is a debug category for code bases that need more than the 64
+// categories of the mask, see RegisterCategory.  Categories work like mask
+// bits, a category must be enabled for its messages to print, but they have
+// their own, unlimited, set.  The mask and its bits are not affected by
+// categories.
+type Category int
+
+// CategoryKey is the field that carries the category name of a message.
+const CategoryKey = "category"
+
+// categories is the category registry a DbgLogger shares with the loggers
+// derived from it.
+type categories struct {
+	mu    sync.Mutex          // protects ids, names and set updates
+	ids   map[string]Category // registered categories by name
+	names []string            // registered names by category
+	set   atomic.Pointer[[]uint64]
+}
+
+// RegisterCategory returns the category for name, allocating a new one the
+// first time a name is seen.  Registering the same name again returns the same
+// category.  Categories start out disabled.
+func (d *DbgLogger) RegisterCategory(name string) Category {
+	c := &d.cats
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if id, ok := c.ids[name]; ok {
+		return id
+	}
+	if c.ids == nil {
+		c.ids = make(map[string]Category)
+	}
+	id := Category(len(c.names))
+	c.ids[name] = id
+	c.names = append(c.names, name)
+	return id
+}
+
+// CategoryByName returns the category registered for name.
+func (d *DbgLogger) CategoryByName(name string) (Category, bool) {
+	d.cats.mu.Lock()
+	defer d.cats.mu.Unlock()
+	id, ok := d.cats.ids[name]
+	return id, ok
+}
+
+// CategoryName returns the name of c or "" if it is not registered.
+func (d *DbgLogger) CategoryName(c Category) string {
+	d.cats.mu.Lock()
+	defer d.cats.mu.Unlock()
+	if c < 0 || int(c) >= len(d.cats.names) {
+		return ""
+	}
+	return d.cats.names[c]
+}
+
+// setCategories enables or disables cats.  If exact is set all other
+// categories are disabled.  The set is copied on write so that readers need
+// no lock and never see a partial update.
+func (d *DbgLogger) setCategories(on, exact bool, cats []Category) {
+	c := &d.cats
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var set []uint64
+	if p := c.set.Load(); p != nil && !exact {
+		set = append(set, *p...)
+	}
+	for _, id := range cats {
+		if id < 0 {
+			continue
+		}
+		w := int(id) / 64
+		for len(set) <= w {
+			set = append(set, 0)
+		}
+		if on {
+			set[w] |= 1 << (uint(id) % 64)
+		} else {
+			set[w] &^= 1 << (uint(id) % 64)
+		}
+	}
+	c.set.Store(&set)
+}
+
+// EnableCategories enables the messages of cats.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) EnableCategories(cats ...Category) {
+	d.setCategories(true, false, cats)
+}
+
+// DisableCategories disables the messages of cats.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) DisableCategories(cats ...Category) {
+	d.setCategories(false, false, cats)
+}
+
+// SetCategoriesByNames enables exactly the categories of a comma separated
+// list of registered names, i.e. "net.dial,db.pool".  Nothing is changed if
+// any of the names is not registered.
+func (d *DbgLogger) SetCategoriesByNames(names string) error {
+	var cats []Category
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, ok := d.CategoryByName(name)
+		if !ok {
+			return fmt.Errorf("dbglog: unknown category %q", name)
+		}
+		cats = append(cats, id)
+	}
+
+	d.setCategories(true, true, cats)
+	return nil
+}
+
+// EnabledCategories returns the names of the enabled categories.
+func (d *DbgLogger) EnabledCategories() []string {
+	c := &d.cats
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var names []string
+	for i, name := range c.names {
+		if d.categorySet(Category(i)) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// categorySet reports whether c is enabled in the set.
+func (d *DbgLogger) categorySet(c Category) bool {
+	p := d.cats.set.Load()
+	if p == nil || c < 0 || int(c)/64 >= len(*p) {
+		return false
+	}
+	return (*p)[int(c)/64]&(1<<(uint(c)%64)) != 0
+}
+
+// wantedC returns true if the messages of category c are printed or captured
+// in the ring buffer.
+func (d *DbgLogger) wantedC(c Category) bool {
+	if !DebugCompiled {
+		return false
+	}
+	if (d.Enabled() && d.categorySet(c)) || d.ringOn.Load() {
+		return true
+	}
+	d.count(LevelDebug, 0, false)
+	return false
+}
+
+// DebugEnabledC returns true if the messages of category c are used, that is
+// printed or captured by the ring buffer, see DebugEnabledM.
+func (d *DbgLogger) DebugEnabledC(c Category) bool {
+	return d.wantedC(c)
+}
+
+// emitC prints msg and fields for category c.
+func (d *DbgLogger) emitC(c Category, msg string, fields []Field) {
+	e := &Entry{Level: LevelDebug, Message: msg}
+	e.Fields = append(make([]Field, 0, 1+len(fields)),
+		Field{Key: CategoryKey, Value: d.CategoryName(c)})
+	e.Fields = append(e.Fields, fields...)
+	e.ringOnly = !d.Enabled() || !d.categorySet(c)
+	d.emit(3, e)
+}
+
+// log.Printf equivalent but only prints when debug is enabled and category c
+// is enabled.  The category name is added in the CategoryKey field.
+func (d *DbgLogger) DebugfC(c Category, format string, v ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wantedC(c) {
+		d.emitC(c, fmt.Sprintf(format, v...), nil)
+	}
+}
+
+// Debugw equivalent but only prints when debug is enabled and category c is
+// enabled.
+func (d *DbgLogger) DebugwC(c Category, msg string,
+	keysAndValues ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wantedC(c) {
+		d.emitC(c, msg, fieldsOf(keysAndValues))
+	}
+}
@@ -0,0 +1,76 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetCategoriesByNames(t *testing.T) {
+	d := NewNop()
+	dial := d.RegisterCategory("net.dial")
+	d.RegisterCategory("db.pool")
+	d.RegisterCategory("rpc")
+	d.EnableCategories(dial)
+
+	if err := d.SetCategoriesByNames("db.pool, rpc"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"db.pool", "rpc"}
+	if got := d.EnabledCategories(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if err := d.SetCategoriesByNames("rpc,nope"); err == nil {
+		t.Fatal("unknown name accepted")
+	}
+	if got := d.EnabledCategories(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("failed call changed the set: %v", got)
+	}
+}
+
+func TestSetCategoriesByNamesAtomic(t *testing.T) {
+	d := NewNop()
+	dial := d.RegisterCategory("net.dial")
+	d.RegisterCategory("db.pool")
+	d.EnableCategories(dial)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20000; i++ {
+			names := "net.dial"
+			if i%2 == 0 {
+				names = "net.dial,db.pool"
+			}
+			d.SetCategoriesByNames(names)
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if !d.categorySet(dial) {
+			<-done
+			t.Fatal("net.dial briefly disabled")
+		}
+	}
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// lastValue is the value last printed by DebugfIfChanged for a key.
+type lastValue struct {
+	value interface{}
+	t     time.Time
+}
+
+// SetIfChangedTTL makes DebugfIfChanged forget the value of a key that has not
+// been printed for ttl, the next value for that key is then always printed.  A
+// ttl of 0, the default, keeps values forever.
+func (d *DbgLogger) SetIfChangedTTL(ttl time.Duration) {
+	d.smu.Lock()
+	d.lastTTL = ttl
+	d.smu.Unlock()
+}
+
+// changed records value for key and reports whether it differs from the
+// previous value.
+func (d *DbgLogger) changed(key string, value interface{}) bool {
+	now := d.clock()
+
+	d.smu.Lock()
+	defer d.smu.Unlock()
+
+	if d.lastTTL > 0 && now.Sub(d.lastSweep) >= d.lastTTL {
+		for k, l := range d.last {
+			if now.Sub(l.t) >= d.lastTTL {
+				delete(d.last, k)
+			}
+		}
+		d.lastSweep = now
+	}
+
+	l, ok := d.last[key]
+	if ok && (d.lastTTL == 0 || now.Sub(l.t) < d.lastTTL) &&
+		reflect.DeepEqual(l.value, value) {
+		d.suppress(key)
+		return false
+	}
+	if d.last == nil {
+		d.last = make(map[string]lastValue)
+	}
+	d.last[key] = lastValue{value: snapshot(value), t: now}
+	return true
+}
+
+// copyKey identifies a pointer or map that has been copied by snapshot.
+type copyKey struct {
+	p uintptr
+	t reflect.Type
+}
+
+// snapshot returns a deep copy of v so that a slice, map or pointed to value
+// that is changed in place no longer compares equal to the copy.  Unexported
+// struct fields cannot be set through reflect and are copied shallowly.
+func snapshot(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return deepCopy(reflect.ValueOf(v), make(map[copyKey]reflect.Value)).
+		Interface()
+}
+
+// deepCopy returns a copy of v.  seen holds the copies of the pointers and
+// maps copied so far so that shared and cyclic values are copied once.
+func deepCopy(v reflect.Value, seen map[copyKey]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		k := copyKey{v.Pointer(), v.Type()}
+		if c, ok := seen[k]; ok {
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		seen[k] = c
+		c.Elem().Set(deepCopy(v.Elem(), seen))
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		k := copyKey{v.Pointer(), v.Type()}
+		if c, ok := seen[k]; ok {
+			return c
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		seen[k] = c
+		for it := v.MapRange(); it.Next(); {
+			c.SetMapIndex(deepCopy(it.Key(), seen),
+				deepCopy(it.Value(), seen))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), seen))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), seen))
+		}
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem(), seen))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i), seen))
+			}
+		}
+		return c
+	}
+	return v
+}
+
+// DebugfIfChanged is log.Printf equivalent but only prints when debug is
+// enabled and value differs, according to reflect.DeepEqual, from the value
+// that was last printed for key.  This is useful in polling loops.  A copy of
+// value is kept, so a slice or map that is changed in place and passed again
+// is printed.
+func (d *DbgLogger) DebugfIfChanged(key string, value interface{},
+	format string, v ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wanted() && d.changed(key, value) {
+		d.output(2, LevelDebug, 0, fmt.Sprintf(format, v...))
+	}
+}
@@ -0,0 +1,142 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDebugfIfChanged(t *testing.T) {
+	type step struct {
+		key   string
+		value interface{}
+		after time.Duration // clock advance before the call
+	}
+	tests := []struct {
+		name  string
+		ttl   time.Duration
+		steps []step
+		want  []string
+	}{
+		{
+			name: "repeated values",
+			steps: []step{
+				{key: "k", value: 1},
+				{key: "k", value: 1},
+				{key: "k", value: 2},
+				{key: "k", value: 2},
+				{key: "k", value: 1},
+			},
+			want: []string{"k=1", "k=2", "k=1"},
+		},
+		{
+			name: "deep equal",
+			steps: []step{
+				{key: "k", value: []int{1, 2}},
+				{key: "k", value: []int{1, 2}},
+				{key: "k", value: []int{1, 3}},
+			},
+			want: []string{"k=[1 2]", "k=[1 3]"},
+		},
+		{
+			name: "keys",
+			steps: []step{
+				{key: "a", value: 1},
+				{key: "b", value: 1},
+				{key: "a", value: 1},
+				{key: "b", value: 2},
+			},
+			want: []string{"a=1", "b=1", "b=2"},
+		},
+		{
+			name: "ttl",
+			ttl:  time.Minute,
+			steps: []step{
+				{key: "k", value: 1},
+				{key: "k", value: 1, after: 30 * time.Second},
+				{key: "k", value: 1, after: 30 * time.Second},
+				{key: "k", value: 1, after: 30 * time.Second},
+			},
+			want: []string{"k=1", "k=1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 0)
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			d.SetTimeFunc(func() time.Time { return now })
+			d.SetIfChangedTTL(tt.ttl)
+			for _, s := range tt.steps {
+				now = now.Add(s.after)
+				d.DebugfIfChanged(s.key, s.value, "%v=%v",
+					s.key, s.value)
+			}
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDebugfIfChangedInPlace(t *testing.T) {
+	type inner struct{ N int }
+	type outer struct {
+		P *inner
+		M map[string][]int
+	}
+	buf := []int{1, 2}
+	m := map[string]int{"a": 1}
+	o := outer{P: &inner{1}, M: map[string][]int{"a": {1}}}
+	steps := []func(){
+		func() { buf[0] = 9 },
+		func() { m["a"] = 2 },
+		func() { o.P.N = 2 },
+		func() { o.M["a"][0] = 2 },
+	}
+	values := []interface{}{buf, m, o, o}
+
+	d, b := newTestLogger("", 0, 0)
+	for i, change := range steps {
+		key := string(rune('a' + i))
+		d.DebugfIfChanged(key, values[i], "first")
+		change()
+		d.DebugfIfChanged(key, values[i], "changed")
+		d.DebugfIfChanged(key, values[i], "same")
+	}
+	want := []string{"first", "changed", "first", "changed",
+		"first", "changed", "first", "changed"}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestSnapshotCycle(t *testing.T) {
+	type node struct {
+		Next *node
+		V    int
+	}
+	n := &node{V: 1}
+	n.Next = n
+	c := snapshot(n).(*node)
+	if c == n || c.Next != c || c.V != 1 {
+		t.Fatalf("bad copy %p %p %v", c, c.Next, c.V)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bytes"
+	"regexp"
+	"sync"
+)
+
+// classifyRule assigns bit to the lines that match prefix or re.
+type classifyRule struct {
+	prefix []byte
+	re     *regexp.Regexp
+	bit    uint64
+}
+
+// ClassifyWriter is an io.Writer that assigns every line written to it a bit
+// by the first matching rule, see DbgLogger.ClassifyWriter.
+type ClassifyWriter struct {
+	*lineWriter
+
+	rmu   sync.RWMutex // protects rules
+	rules []classifyRule
+}
+
+// ClassifyWriter returns a Writer that prints every line with the bit of the
+// first rule that matches it, or with bit when no rule matches.  A bit of 0
+// drops unmatched lines.  Rules are checked in the order they were added, i.e.
+//
+//	w := d.ClassifyWriter(DbgOutput)
+//	w.AddPrefix("ERROR", DbgError)
+//	w.AddRegexp(regexp.MustCompile(`^\d+ bytes`), DbgIO)
+//	cmd.Stdout = w
+func (d *DbgLogger) ClassifyWriter(bit uint64) *ClassifyWriter {
+	w := &ClassifyWriter{lineWriter: &lineWriter{d: d, bit: bit}}
+	w.lineWriter.classify = w.classify
+	return w
+}
+
+// AddPrefix assigns bit to the lines that start with prefix.
+func (w *ClassifyWriter) AddPrefix(prefix string, bit uint64) {
+	w.add(classifyRule{prefix: []byte(prefix), bit: bit})
+}
+
+// AddRegexp assigns bit to the lines that match re.
+func (w *ClassifyWriter) AddRegexp(re *regexp.Regexp, bit uint64) {
+	w.add(classifyRule{re: re, bit: bit})
+}
+
+func (w *ClassifyWriter) add(r classifyRule) {
+	w.rmu.Lock()
+	w.rules = append(w.rules, r)
+	w.rmu.Unlock()
+}
+
+// classify returns the bit of the first rule that matches l.
+func (w *ClassifyWriter) classify(l []byte) uint64 {
+	w.rmu.RLock()
+	defer w.rmu.RUnlock()
+
+	for _, r := range w.rules {
+		if r.re != nil {
+			if r.re.Match(l) {
+				return r.bit
+			}
+		} else if bytes.HasPrefix(l, r.prefix) {
+			return r.bit
+		}
+	}
+	return w.bit
+}
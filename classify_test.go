@@ -0,0 +1,108 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"io"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestClassifyWriter(t *testing.T) {
+	type rule struct {
+		prefix string
+		re     string
+		bit    uint64
+	}
+	tests := []struct {
+		name     string
+		fallback uint64
+		rules    []rule
+		writes   []string
+		want     []string
+	}{
+		{
+			name:     "fallback",
+			fallback: 2,
+			rules:    []rule{{prefix: "ERROR", bit: 4}},
+			writes:   []string{"hello\n"},
+			want:     []string{"[0x2] hello"},
+		},
+		{
+			name:   "fallback drops",
+			rules:  []rule{{prefix: "ERROR", bit: 4}},
+			writes: []string{"hello\nERROR x\n"},
+			want:   []string{"[0x4] ERROR x"},
+		},
+		{
+			name:     "prefix before regexp",
+			fallback: 2,
+			rules: []rule{
+				{prefix: "ERROR", bit: 4},
+				{re: `\d+ bytes`, bit: 8},
+			},
+			writes: []string{"ERROR 10 bytes\n10 bytes\n"},
+			want: []string{
+				"[0x4] ERROR 10 bytes",
+				"[0x8] 10 bytes",
+			},
+		},
+		{
+			name:     "regexp before prefix",
+			fallback: 2,
+			rules: []rule{
+				{re: `\d+ bytes`, bit: 8},
+				{prefix: "ERROR", bit: 4},
+			},
+			writes: []string{"ERROR 10 bytes\nERROR\n"},
+			want:   []string{"[0x8] ERROR 10 bytes", "[0x4] ERROR"},
+		},
+		{
+			name:     "partial lines",
+			fallback: 2,
+			rules:    []rule{{prefix: "ERROR", bit: 4}},
+			writes:   []string{"ER", "ROR x\nhel", "lo", "\n"},
+			want:     []string{"[0x4] ERROR x", "[0x2] hello"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 2|4|8)
+			d.SetBitTag(BitTagLine)
+			w := d.ClassifyWriter(tt.fallback)
+			for _, r := range tt.rules {
+				if r.re != "" {
+					re := regexp.MustCompile(r.re)
+					w.AddRegexp(re, r.bit)
+				} else {
+					w.AddPrefix(r.prefix, r.bit)
+				}
+			}
+			for _, s := range tt.writes {
+				if _, err := io.WriteString(w, s); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
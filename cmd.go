@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import "os/exec"
+
+// CaptureCmd sets the standard output and error of cmd to Writers that print
+// every line of output with stdoutBit and stderrBit respectively.  It must be
+// called before cmd is started.  The returned function prints an unterminated
+// last line of either stream and must be called after cmd.Wait returns, i.e.
+//
+//	done := d.CaptureCmd(cmd, DbgStdout, DbgStderr)
+//	err := cmd.Run()
+//	done()
+//
+// A bit of 0 discards that stream.
+func (d *DbgLogger) CaptureCmd(cmd *exec.Cmd, stdoutBit,
+	stderrBit uint64) (done func()) {
+	stdout := &lineWriter{d: d, bit: stdoutBit}
+	stderr := &lineWriter{d: d, bit: stderrBit}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return func() {
+		stdout.Close()
+		stderr.Close()
+	}
+}
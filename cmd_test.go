@@ -0,0 +1,93 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestCaptureCmdHelper is the process run by TestCaptureCmd.
+func TestCaptureCmdHelper(t *testing.T) {
+	if os.Getenv("DBGLOG_HELPER_PROCESS") != "1" {
+		t.Skip("helper process")
+	}
+	fmt.Fprint(os.Stdout, "out 1\nout 2")
+	fmt.Fprint(os.Stderr, "err 1\n")
+	os.Exit(0)
+}
+
+func TestCaptureCmd(t *testing.T) {
+	tests := []struct {
+		name   string
+		stdout uint64
+		stderr uint64
+		want   []string
+	}{
+		{
+			name:   "both",
+			stdout: 2,
+			stderr: 4,
+			want: []string{
+				"[0x2] out 1",
+				"[0x2] out 2 (no newline)",
+				"[0x4] err 1",
+			},
+		},
+		{
+			name:   "discard stdout",
+			stderr: 4,
+			want:   []string{"[0x4] err 1"},
+		},
+		{
+			name:   "discard stderr",
+			stdout: 2,
+			want: []string{
+				"[0x2] out 1",
+				"[0x2] out 2 (no newline)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 2|4)
+			d.SetBitTag(BitTagLine)
+			cmd := exec.Command(os.Args[0],
+				"-test.run=^TestCaptureCmdHelper$")
+			cmd.Env = append(os.Environ(),
+				"DBGLOG_HELPER_PROCESS=1")
+			done := d.CaptureCmd(cmd, tt.stdout, tt.stderr)
+			if err := cmd.Run(); err != nil {
+				t.Fatal(err)
+			}
+			done()
+
+			// The streams are copied concurrently.
+			got := lines(b)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
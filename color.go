@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"io"
+	"os"
+	"strconv"
+)
+
+// Color is an ANSI terminal foreground color.
+type Color int
+
+// Colors that can be used with SetTagColor, SetLevelColor and SetBitColor.
+const (
+	ColorNone    Color = 0
+	ColorRed     Color = 31
+	ColorGreen   Color = 32
+	ColorYellow  Color = 33
+	ColorBlue    Color = 34
+	ColorMagenta Color = 35
+	ColorCyan    Color = 36
+	ColorWhite   Color = 37
+)
+
+// colorize wraps s in the escape sequences for c.
+func (c Color) colorize(s string) string {
+	if c == ColorNone {
+		return s
+	}
+	return "\x1b[" + strconv.Itoa(int(c)) + "m" + s + "\x1b[0m"
+}
+
+// defaultLevelColors are the colors of the levels that have not been set with
+// SetLevelColor.
+var defaultLevelColors = map[Level]Color{
+	LevelWarn:  ColorYellow,
+	LevelError: ColorRed,
+	LevelFatal: ColorRed,
+}
+
+// isTerminal reports whether w is a terminal that understands colors.
+// Colors are never used when the NO_COLOR environment variable is set or
+// TERM is dumb.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// SetColor enables or disables colorized output.  New enables it when the
+// output is a terminal.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) SetColor(on bool) {
+	d.mu.Lock()
+	d.color = on
+	d.mu.Unlock()
+}
+
+// SetLevelColor sets the color of the lines of level l when color is
+// enabled.  Warnings are yellow and errors red unless changed.  ColorNone
+// prints the level uncolored.
+func (d *DbgLogger) SetLevelColor(l Level, c Color) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.levelColors == nil {
+		d.levelColors = make(map[Level]Color)
+	}
+	d.levelColors[l] = c
+}
+
+// SetBitColor sets the color of the debug lines printed for bit when color is
+// enabled, i.e. the lines printed by DebugfM(bit, ...).  ColorNone removes the
+// color.
+func (d *DbgLogger) SetBitColor(bit uint64, c Color) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if c == ColorNone {
+		delete(d.bitColors, bit)
+		return
+	}
+	if d.bitColors == nil {
+		d.bitColors = make(map[uint64]Color)
+	}
+	d.bitColors[bit] = c
+}
+
+// lineColor returns the color of the line for e.  Must be called with mu
+// held.
+func (d *DbgLogger) lineColor(e *Entry) Color {
+	if e.Level == LevelDebug {
+		return d.bitColors[e.Bit]
+	}
+	if c, ok := d.levelColors[e.Level]; ok {
+		return c
+	}
+	return defaultLevelColors[e.Level]
+}
@@ -0,0 +1,133 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestColor(t *testing.T) {
+	tests := []struct {
+		name  string
+		color bool
+		set   func(d *DbgLogger)
+		want  []string
+	}{
+		{
+			name: "off",
+			set: func(d *DbgLogger) {
+				d.SetBitColor(2, ColorCyan)
+			},
+			want: []string{
+				"debug", "bit", "[INFO] info", "[WARN] warn",
+				"[ERROR] error",
+			},
+		},
+		{
+			name:  "default",
+			color: true,
+			want: []string{
+				"debug", "bit", "[INFO] info",
+				"\x1b[33m[WARN] warn\x1b[0m",
+				"\x1b[31m[ERROR] error\x1b[0m",
+			},
+		},
+		{
+			name:  "levels",
+			color: true,
+			set: func(d *DbgLogger) {
+				d.SetLevelColor(LevelInfo, ColorGreen)
+				d.SetLevelColor(LevelWarn, ColorNone)
+				d.SetLevelColor(LevelError, ColorMagenta)
+			},
+			want: []string{
+				"debug", "bit",
+				"\x1b[32m[INFO] info\x1b[0m",
+				"[WARN] warn",
+				"\x1b[35m[ERROR] error\x1b[0m",
+			},
+		},
+		{
+			name:  "bit",
+			color: true,
+			set: func(d *DbgLogger) {
+				d.SetBitColor(2, ColorCyan)
+				d.SetBitColor(4, ColorBlue)
+				d.SetBitColor(4, ColorNone)
+			},
+			want: []string{
+				"debug", "\x1b[36mbit\x1b[0m", "[INFO] info",
+				"\x1b[33m[WARN] warn\x1b[0m",
+				"\x1b[31m[ERROR] error\x1b[0m",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 2|4)
+			d.SetColor(tt.color)
+			if tt.set != nil {
+				tt.set(d)
+			}
+			d.Debugf("debug")
+			d.DebugfM(2, "bit")
+			d.Infof("info")
+			d.Warnf("warn")
+			d.Errorf("error")
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColorNotTerminal(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	for name, out := range map[string]io.Writer{
+		"file": f,
+		"pipe": w,
+	} {
+		if isTerminal(out) {
+			t.Fatalf("%v is a terminal", name)
+		}
+	}
+
+	var b bytes.Buffer
+	d := New(&b, "", 0)
+	d.Warnf("warn")
+	if got := b.String(); got != "[WARN] warn\n" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,306 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// fileConfig is the configuration file read by LoadConfig.
+type fileConfig struct {
+	Enabled *bool          `json:"enabled"`
+	Mask    *maskSpec      `json:"mask"`
+	Exclude *maskSpec      `json:"exclude"`
+	Level   *string        `json:"level"`
+	Format  *string        `json:"format"`
+	Color   *bool          `json:"color"`
+	Outputs []outputConfig `json:"outputs"`
+}
+
+// outputConfig is an output of a configuration file, see AddOutput.
+type outputConfig struct {
+	Path   string    `json:"path"`
+	Level  string    `json:"level"`
+	Mask   *maskSpec `json:"mask"`
+	Format string    `json:"format"`
+}
+
+// maskSpec is a mask in a configuration file, either a number or a ParseMask
+// string such as "net,db".
+type maskSpec struct {
+	number uint64
+	spec   string
+	isSpec bool
+}
+
+func (m *maskSpec) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		m.isSpec = true
+		return json.Unmarshal(b, &m.spec)
+	}
+	n, err := strconv.ParseUint(string(b), 10, 64)
+	if err != nil {
+		return fmt.Errorf("dbglog: invalid mask %v", string(b))
+	}
+	m.number = n
+	return nil
+}
+
+// mask returns the mask described by m.
+func (m *maskSpec) mask(d *DbgLogger) (uint64, error) {
+	if !m.isSpec {
+		return m.number, nil
+	}
+	return d.ParseMask(m.spec)
+}
+
+// LoadConfig applies the JSON configuration file at path, i.e.
+//
+//	{
+//		"enabled": true,
+//		"mask": "net,db",
+//		"exclude": "0x10",
+//		"level": "info",
+//		"format": "text",
+//		"color": false,
+//		"outputs": [
+//			{"path": "/var/log/app.log", "format": "json"},
+//			{"path": "stderr", "level": "warn"}
+//		]
+//	}
+//
+// Settings that are left out are not changed.  Masks are numbers or ParseMask
+// strings.  Outputs are added as with AddOutput and replace the outputs of an
+// earlier LoadConfig, their path is stdout, stderr or a file opened with
+// NewFileWriter.  Nothing is changed if the file is invalid.
+func (d *DbgLogger) LoadConfig(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var c fileConfig
+	if err := json.Unmarshal(b, &c); err != nil {
+		return fmt.Errorf("dbglog: %v: %v", path, err)
+	}
+
+	var apply []func()
+	if c.Enabled != nil {
+		if *c.Enabled {
+			apply = append(apply, d.Enable)
+		} else {
+			apply = append(apply, d.Disable)
+		}
+	}
+	if c.Mask != nil {
+		mask, err := c.Mask.mask(d)
+		if err != nil {
+			return err
+		}
+		apply = append(apply, func() { d.SetMask(mask) })
+	}
+	if c.Exclude != nil {
+		mask, err := c.Exclude.mask(d)
+		if err != nil {
+			return err
+		}
+		apply = append(apply, func() { d.SetExcludeMask(mask) })
+	}
+	if c.Level != nil {
+		l, err := ParseLevel(*c.Level)
+		if err != nil {
+			return err
+		}
+		apply = append(apply, func() { d.SetLevel(l) })
+	}
+	if c.Format != nil {
+		f, err := ParseFormat(*c.Format)
+		if err != nil {
+			return err
+		}
+		apply = append(apply, func() { d.SetFormat(f) })
+	}
+	if c.Color != nil {
+		color := *c.Color
+		apply = append(apply, func() { d.SetColor(color) })
+	}
+	if c.Outputs != nil {
+		outputs, err := d.openOutputs(c.Outputs)
+		if err != nil {
+			return err
+		}
+		apply = append(apply, func() { d.replaceOutputs(outputs) })
+	}
+	for _, f := range apply {
+		f()
+	}
+	return nil
+}
+
+// openOutputs returns the outputs of a configuration file.  Files that were
+// opened are closed again on error.
+func (d *DbgLogger) openOutputs(oc []outputConfig) ([]tee, error) {
+	var outputs []tee
+	fail := func(err error) ([]tee, error) {
+		for _, t := range outputs {
+			if t.closer != nil {
+				t.closer.Close()
+			}
+		}
+		return nil, err
+	}
+	for _, o := range oc {
+		t := tee{level: LevelTrace}
+		if o.Level != "" {
+			l, err := ParseLevel(o.Level)
+			if err != nil {
+				return fail(err)
+			}
+			t.level = l
+		}
+		if o.Mask != nil {
+			mask, err := o.Mask.mask(d)
+			if err != nil {
+				return fail(err)
+			}
+			t.mask = mask
+		}
+		if o.Format != "" {
+			f, err := ParseFormat(o.Format)
+			if err != nil {
+				return fail(err)
+			}
+			t.format = f
+			t.ownFormat = true
+		}
+		switch o.Path {
+		case "":
+			return fail(fmt.Errorf("dbglog: output without path"))
+		case "stdout":
+			t.w = os.Stdout
+			t.closer = nopCloser{}
+		case "stderr":
+			t.w = os.Stderr
+			t.closer = nopCloser{}
+		default:
+			f, err := NewFileWriter(o.Path)
+			if err != nil {
+				return fail(err)
+			}
+			t.w = f
+			t.closer = f
+		}
+		outputs = append(outputs, t)
+	}
+	return outputs, nil
+}
+
+// replaceOutputs replaces the outputs of an earlier configuration file with
+// outputs and closes the files of the old ones.
+func (d *DbgLogger) replaceOutputs(outputs []tee) {
+	var old []io.Closer
+
+	d.mu.Lock()
+	kept := d.outputs[:0:0]
+	for _, t := range d.outputs {
+		if t.closer != nil {
+			old = append(old, t.closer)
+			continue
+		}
+		kept = append(kept, t)
+	}
+	d.outputs = append(kept, outputs...)
+	d.mu.Unlock()
+
+	for _, c := range old {
+		c.Close()
+	}
+}
+
+// nopCloser marks the standard streams as outputs of a configuration file
+// without closing them.
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// WatchConfig applies the configuration file at path with LoadConfig and
+// applies it again whenever the process receives SIGHUP and, if interval is
+// positive, whenever its modification time changed when checked every
+// interval.  Errors while reloading are printed at LevelError.  The returned
+// function stops watching, it may be called more than once.
+func (d *DbgLogger) WatchConfig(path string,
+	interval time.Duration) (stop func(), err error) {
+	if err := d.LoadConfig(path); err != nil {
+		return nil, err
+	}
+	var mtime time.Time
+	if fi, err := os.Stat(path); err == nil {
+		mtime = fi.ModTime()
+	}
+
+	hup := make(chan os.Signal, 1)
+	if sigs := hangup(); len(sigs) != 0 {
+		signal.Notify(hup, sigs...)
+	}
+	var tick <-chan time.Time
+	var ticker *time.Ticker
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+	done := make(chan struct{})
+
+	reload := func() {
+		if err := d.LoadConfig(path); err != nil {
+			d.output(1, LevelError, 0,
+				fmt.Sprintf("dbglog: reload %v: %v", path, err))
+		}
+	}
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-hup:
+				reload()
+			case <-tick:
+				fi, err := os.Stat(path)
+				if err != nil || fi.ModTime().Equal(mtime) {
+					continue
+				}
+				mtime = fi.ModTime()
+				reload()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(hup)
+			if ticker != nil {
+				ticker.Stop()
+			}
+			close(done)
+		})
+	}, nil
+}
@@ -0,0 +1,121 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes a configuration file with text to dir and returns its
+// path.
+func writeConfig(t *testing.T, dir, text string) string {
+	t.Helper()
+	path := filepath.Join(dir, "dbglog.json")
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.log")
+	path := writeConfig(t, dir, `{
+		"enabled": true,
+		"mask": "net",
+		"exclude": 4,
+		"level": "warn",
+		"outputs": [{"path": "`+out+`", "format": "json"}]
+	}`)
+	d := NewNop()
+	net := d.RegisterBit("net")
+	if err := d.LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Enabled() || d.GetMask() != net || d.GetExcludeMask() != 4 ||
+		d.GetLevel() != LevelWarn {
+		t.Fatalf("not applied: enabled %v mask %#x exclude %#x "+
+			"level %v", d.Enabled(), d.GetMask(),
+			d.GetExcludeMask(), d.GetLevel())
+	}
+
+	d.Infof("below")
+	d.Warnf("warned")
+	// Replacing the outputs closes the file.
+	err := d.LoadConfig(writeConfig(t, dir, `{"outputs": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); strings.Contains(s, "below") ||
+		!strings.HasPrefix(s, "{") || !strings.Contains(s, "warned") {
+		t.Fatalf("got %q", s)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"syntax", `{"enabled": true,`},
+		{"level", `{"enabled": true, "level": "loud"}`},
+		{"mask", `{"enabled": true, "mask": "nosuchbit"}`},
+		{"output", `{"enabled": true, "outputs": [{"level": "warn"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewNop()
+			path := writeConfig(t, t.TempDir(), tt.text)
+			if err := d.LoadConfig(path); err == nil {
+				t.Fatal("no error")
+			}
+			if d.Enabled() {
+				t.Fatal("invalid file was partially applied")
+			}
+		})
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, `{"level": "info"}`)
+	d := NewNop()
+	stop, err := d.WatchConfig(path, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.GetLevel() != LevelInfo {
+		t.Fatalf("level %v", d.GetLevel())
+	}
+
+	writeConfig(t, dir, `{"level": "error"}`)
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(path, later, later)
+	waitFor(t, "reload", func() bool { return d.GetLevel() == LevelError })
+	stop()
+	stop()
+}
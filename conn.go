@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import "net"
+
+// tracedConn is a net.Conn that dumps its traffic, see WrapConn.
+type tracedConn struct {
+	net.Conn
+	d   *DbgLogger
+	bit uint64
+}
+
+// WrapConn returns a net.Conn that hex dumps everything read from and written
+// to c, like DebugDumpM, when debug is enabled and bit is enabled in the mask:
+//
+//	< read from 10.0.0.2:4242 (5 bytes):
+//	00000000  68 65 6c 6c 6f                                    |hello|
+//
+// Reads are marked with <, writes with >, along with errors and Close.  The
+// mask is checked on every call so tracing can be switched on an open
+// connection.  The returned net.Conn has the CloseRead and CloseWrite methods
+// of c, i.e. of a *net.TCPConn, exactly when c has them, and c is available
+// through its NetConn method like that of a *tls.Conn.
+func (d *DbgLogger) WrapConn(c net.Conn, bit uint64) net.Conn {
+	t := &tracedConn{Conn: c, d: d, bit: bit}
+	_, r := c.(interface{ CloseRead() error })
+	_, w := c.(interface{ CloseWrite() error })
+	switch {
+	case r && w:
+		return struct {
+			*tracedConn
+			closeReader
+			closeWriter
+		}{t, closeReader{t}, closeWriter{t}}
+	case r:
+		return struct {
+			*tracedConn
+			closeReader
+		}{t, closeReader{t}}
+	case w:
+		return struct {
+			*tracedConn
+			closeWriter
+		}{t, closeWriter{t}}
+	}
+	return t
+}
+
+// NetConn returns the wrapped connection.
+func (c *tracedConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// peer returns the remote address of the connection.
+func (c *tracedConn) peer() string {
+	if a := c.RemoteAddr(); a != nil {
+		return a.String()
+	}
+	return "?"
+}
+
+func (c *tracedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if c.d.wantedM(c.bit) {
+		if n > 0 {
+			c.d.output(2, LevelDebug, c.bit,
+				"< read from "+c.peer()+" "+dump(p[:n]))
+		}
+		if err != nil {
+			c.d.output(2, LevelDebug, c.bit,
+				"< read from "+c.peer()+": "+err.Error())
+		}
+	}
+	return n, err
+}
+
+func (c *tracedConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if c.d.wantedM(c.bit) {
+		if n > 0 {
+			c.d.output(2, LevelDebug, c.bit, "> write to "+c.peer()+
+				" "+dump(p[:n]))
+		}
+		if err != nil {
+			c.d.output(2, LevelDebug, c.bit, "> write to "+c.peer()+
+				": "+err.Error())
+		}
+	}
+	return n, err
+}
+
+func (c *tracedConn) Close() error {
+	err := c.Conn.Close()
+	c.closed("close ", err)
+	return err
+}
+
+// closed prints what, the peer and err, if any, for a Close method.
+func (c *tracedConn) closed(what string, err error) {
+	if !c.d.wantedM(c.bit) {
+		return
+	}
+	s := what + c.peer()
+	if err != nil {
+		s += ": " + err.Error()
+	}
+	c.d.output(3, LevelDebug, c.bit, s)
+}
+
+// closeReader and closeWriter forward the half-close methods of the wrapped
+// connection, see WrapConn.
+type (
+	closeReader struct{ c *tracedConn }
+	closeWriter struct{ c *tracedConn }
+)
+
+func (r closeReader) CloseRead() error {
+	err := r.c.Conn.(interface{ CloseRead() error }).CloseRead()
+	r.c.closed("close read ", err)
+	return err
+}
+
+func (w closeWriter) CloseWrite() error {
+	err := w.c.Conn.(interface{ CloseWrite() error }).CloseWrite()
+	w.c.closed("close write ", err)
+	return err
+}
@@ -0,0 +1,177 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWrapConn(t *testing.T) {
+	for _, mask := range []uint64{0, 1} {
+		d, b := newTestLogger("", 0, mask)
+		a, p := net.Pipe()
+		c := d.WrapConn(a, 1)
+		go func() {
+			p.Write([]byte("hello"))
+			io.ReadFull(p, make([]byte, 2))
+			p.Close()
+		}()
+		buf := make([]byte, 8)
+		if n, err := c.Read(buf); n != 5 || err != nil {
+			t.Fatalf("read %v %v", n, err)
+		}
+		if n, err := c.Write([]byte("hi")); n != 2 || err != nil {
+			t.Fatalf("write %v %v", n, err)
+		}
+		if _, err := c.Read(buf); err != io.EOF {
+			t.Fatalf("read %v, want EOF", err)
+		}
+		c.Close()
+
+		var want []string
+		if mask != 0 {
+			want = []string{
+				"< read from pipe (5 bytes):",
+				"00000000  68 65 6c 6c 6f" +
+					strings.Repeat(" ", 36) + "|hello|",
+				"> write to pipe (2 bytes):",
+				"00000000  68 69" +
+					strings.Repeat(" ", 45) + "|hi|",
+				"< read from pipe: EOF",
+				"close pipe",
+			}
+		}
+		if got := lines(b); !reflect.DeepEqual(got, want) {
+			t.Fatalf("mask %v: got %q, want %q", mask, got, want)
+		}
+		nc := c.(interface{ NetConn() net.Conn }).NetConn()
+		if nc != a {
+			t.Fatalf("NetConn %v, want %v", nc, a)
+		}
+	}
+}
+
+// closeReadConn and closeWriteConn are connections that can only close one
+// direction.
+type (
+	closeReadConn  struct{ net.Conn }
+	closeWriteConn struct{ net.Conn }
+)
+
+func (closeReadConn) CloseRead() error { return nil }
+
+func (closeWriteConn) CloseWrite() error {
+	return errors.New("not connected")
+}
+
+func TestWrapConnHalfClose(t *testing.T) {
+	a, p := net.Pipe()
+	defer a.Close()
+	defer p.Close()
+	tests := []struct {
+		name  string
+		c     net.Conn
+		read  bool
+		write bool
+		want  []string
+	}{
+		{name: "pipe", c: a},
+		{
+			name: "read",
+			c:    closeReadConn{a},
+			read: true,
+			want: []string{"close read pipe"},
+		},
+		{
+			name:  "write",
+			c:     closeWriteConn{a},
+			write: true,
+			want:  []string{"close write pipe: not connected"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 1)
+			c := d.WrapConn(tt.c, 1)
+			r, read := c.(interface{ CloseRead() error })
+			w, write := c.(interface{ CloseWrite() error })
+			if read != tt.read || write != tt.write {
+				t.Fatalf("got CloseRead %v CloseWrite %v", read,
+					write)
+			}
+			if read {
+				r.CloseRead()
+			}
+			if write {
+				w.CloseWrite()
+			}
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapConnTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	done := make(chan []byte)
+	go func() {
+		s, err := l.Accept()
+		if err != nil {
+			done <- nil
+			return
+		}
+		defer s.Close()
+		b, _ := io.ReadAll(s)
+		s.Write([]byte("bye"))
+		done <- b
+	}()
+
+	d, b := newTestLogger("", 0, 1)
+	tc, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := d.WrapConn(tc, 1)
+	defer c.Close()
+	c.Write([]byte("hi"))
+	// The server only answers once it has read everything.
+	if err := c.(interface{ CloseWrite() error }).CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-done; string(got) != "hi" {
+		t.Fatalf("server got %q", got)
+	}
+	if got, _ := io.ReadAll(c); string(got) != "bye" {
+		t.Fatalf("got %q", got)
+	}
+	want := "close write " + tc.RemoteAddr().String()
+	if !strings.Contains(b.String(), want+"\n") {
+		t.Fatalf("got %q, want %q", b.String(), want)
+	}
+}
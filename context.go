@@ -0,0 +1,183 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"context"
+	"fmt"
+)
+
+// Span is the part of a tracing span that dbglog uses.  Tracing libraries can
+// be hooked up by storing an adapter in a context with ContextWithSpan.
+type Span interface {
+	RecordError(err error)
+}
+
+// spanKey is the context key of the Span.
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx that carries s.
+func ContextWithSpan(ctx context.Context, s Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// SpanFromContext returns the Span carried by ctx or nil.
+func SpanFromContext(ctx context.Context) Span {
+	s, _ := ctx.Value(spanKey{}).(Span)
+	return s
+}
+
+// DebugfOnErrorCtx does nothing if err is nil.  Otherwise it records err on the
+// Span carried by ctx, if any, and prints the message followed by ": " and err
+// when debug is enabled and bit is enabled in the mask.  err is returned
+// unchanged so the call can be used in a return statement.
+func (d *DbgLogger) DebugfOnErrorCtx(ctx context.Context, bit uint64,
+	err error, format string, v ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	if s := SpanFromContext(ctx); s != nil {
+		s.RecordError(err)
+	}
+	if d.wantedM(bit) {
+		d.output(2, LevelDebug, bit,
+			fmt.Sprintf(format, v...)+": "+err.Error())
+	}
+	return err
+}
+
+// loggerKey is the context key of the DbgLogger.
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx that carries d, i.e. a logger with request
+// scoped fields, see WithFields.
+func NewContext(ctx context.Context, d *DbgLogger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, d)
+}
+
+// FromContext returns the DbgLogger carried by ctx or the Default logger.
+func FromContext(ctx context.Context) *DbgLogger {
+	if d, ok := ctx.Value(loggerKey{}).(*DbgLogger); ok {
+		return d
+	}
+	return Default()
+}
+
+// SpanIDs is implemented by Spans that know the ids of their trace and span.
+// The ids are added to the messages of the Ctx functions, i.e. DebugfCtx.
+type SpanIDs interface {
+	TraceID() string
+	SpanID() string
+}
+
+// SpanLogger is implemented by Spans that record log messages, i.e. as
+// OpenTelemetry log records or span events.  The Ctx functions pass every
+// printed message to it.
+type SpanLogger interface {
+	Log(e Entry)
+}
+
+// Keys of the fields that carry the trace and span ids.
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+// traceIDFunc returns the trace and span ids of a context.
+type traceIDFunc func(ctx context.Context) (traceID, spanID string)
+
+// SetTraceIDFunc sets the function that extracts the trace and span ids from
+// the context of the Ctx functions, i.e. for OpenTelemetry:
+//
+//	d.SetTraceIDFunc(func(ctx context.Context) (string, string) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		if !sc.IsValid() {
+//			return "", ""
+//		}
+//		return sc.TraceID().String(), sc.SpanID().String()
+//	})
+//
+// Without it, or when it returns empty ids, the ids are taken from the Span
+// carried by the context if it implements SpanIDs.
+func (d *DbgLogger) SetTraceIDFunc(f func(ctx context.Context) (traceID,
+	spanID string)) {
+	d.mu.Lock()
+	d.traceIDs = f
+	d.mu.Unlock()
+}
+
+// traceFields returns fields with the trace and span ids of ctx appended and
+// the Span carried by ctx.
+func (d *DbgLogger) traceFields(ctx context.Context,
+	fields []Field) ([]Field, Span) {
+	d.mu.Lock()
+	f := d.traceIDs
+	d.mu.Unlock()
+
+	var traceID, spanID string
+	if f != nil {
+		traceID, spanID = f(ctx)
+	}
+	s := SpanFromContext(ctx)
+	if ids, ok := s.(SpanIDs); ok && traceID == "" {
+		traceID, spanID = ids.TraceID(), ids.SpanID()
+	}
+	if traceID != "" {
+		fields = append(fields, Field{Key: TraceIDKey, Value: traceID})
+	}
+	if spanID != "" {
+		fields = append(fields, Field{Key: SpanIDKey, Value: spanID})
+	}
+	return fields, s
+}
+
+// ctxEntry emits an entry for msg and fields with the trace and span ids of
+// ctx added and hands it to the SpanLogger of ctx, if any.
+func (d *DbgLogger) ctxEntry(ctx context.Context, bit uint64, msg string,
+	fields []Field) {
+	fields, s := d.traceFields(ctx, fields)
+	e := &Entry{Level: LevelDebug, Bit: bit, Message: msg, Fields: fields}
+	d.emit(3, e)
+	if l, ok := s.(SpanLogger); ok && d.printed(bit) {
+		l.Log(*e)
+	}
+}
+
+// DebugfCtx is log.Printf equivalent that adds the trace and span ids of ctx
+// to the message, see SetTraceIDFunc.  It only prints when debug is enabled
+// and bit is enabled in the mask, a bit of 0 prints whenever debug is enabled
+// like Debugf.
+func (d *DbgLogger) DebugfCtx(ctx context.Context, bit uint64, format string,
+	v ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if (bit == 0 && d.wanted()) || (bit != 0 && d.wantedM(bit)) {
+		d.ctxEntry(ctx, bit, fmt.Sprintf(format, v...), nil)
+	}
+}
+
+// DebugwCtx is Debugw with the trace and span ids of ctx added, see DebugfCtx.
+func (d *DbgLogger) DebugwCtx(ctx context.Context, bit uint64, msg string,
+	keysAndValues ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if (bit == 0 && d.wanted()) || (bit != 0 && d.wantedM(bit)) {
+		d.ctxEntry(ctx, bit, msg, fieldsOf(keysAndValues))
+	}
+}
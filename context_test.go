@@ -0,0 +1,201 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// recordingSpan is a Span that records the errors it receives.
+type recordingSpan struct {
+	errs []error
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.errs = append(s.errs, err)
+}
+
+func TestDebugfOnErrorCtx(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		name string
+		err  error
+		span bool
+		mask uint64
+		want []string
+	}{
+		{name: "nil", mask: 1, span: true},
+		{
+			name: "error",
+			err:  boom,
+			mask: 1,
+			want: []string{"op 1: boom"},
+		},
+		{
+			name: "error with span",
+			err:  boom,
+			span: true,
+			mask: 1,
+			want: []string{"op 1: boom"},
+		},
+		{name: "masked with span", err: boom, span: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, tt.mask)
+			ctx := context.Background()
+			s := &recordingSpan{}
+			if tt.span {
+				ctx = ContextWithSpan(ctx, s)
+			}
+			err := d.DebugfOnErrorCtx(ctx, 1, tt.err, "op %v", 1)
+			if err != tt.err {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+			var want []error
+			if tt.span && tt.err != nil {
+				want = []error{tt.err}
+			}
+			if !reflect.DeepEqual(s.errs, want) {
+				t.Fatalf("span got %v, want %v", s.errs, want)
+			}
+		})
+	}
+}
+
+// idSpan is a Span with ids that records the entries it is handed.
+type idSpan struct {
+	recordingSpan
+	entries []Entry
+}
+
+func (s *idSpan) TraceID() string { return "span-trace" }
+func (s *idSpan) SpanID() string  { return "span-span" }
+func (s *idSpan) Log(e Entry)     { s.entries = append(s.entries, e) }
+
+func TestDebugCtx(t *testing.T) {
+	ids := func(traceID, spanID string) func(context.Context) (string,
+		string) {
+		return func(context.Context) (string, string) {
+			return traceID, spanID
+		}
+	}
+	tests := []struct {
+		name  string
+		ids   func(context.Context) (string, string)
+		span  bool
+		bit   uint64
+		want  []string
+		spans bool // entries handed to the span
+	}{
+		{
+			name: "no ids",
+			bit:  1,
+			want: []string{"f 1", "w k=v"},
+		},
+		{
+			name: "trace id func",
+			ids:  ids("t", "s"),
+			bit:  1,
+			want: []string{
+				"f 1 trace_id=t span_id=s",
+				"w k=v trace_id=t span_id=s",
+			},
+		},
+		{
+			name:  "trace id func before span",
+			ids:   ids("t", ""),
+			span:  true,
+			bit:   1,
+			want:  []string{"f 1 trace_id=t", "w k=v trace_id=t"},
+			spans: true,
+		},
+		{
+			name:  "span ids",
+			span:  true,
+			bit:   1,
+			spans: true,
+			want: []string{
+				"f 1 trace_id=span-trace span_id=span-span",
+				"w k=v trace_id=span-trace span_id=span-span",
+			},
+		},
+		{
+			name:  "span ids when func returns none",
+			ids:   ids("", ""),
+			span:  true,
+			bit:   1,
+			spans: true,
+			want: []string{
+				"f 1 trace_id=span-trace span_id=span-span",
+				"w k=v trace_id=span-trace span_id=span-span",
+			},
+		},
+		{
+			name: "bit 0",
+			ids:  ids("t", "s"),
+			want: []string{
+				"f 1 trace_id=t span_id=s",
+				"w k=v trace_id=t span_id=s",
+			},
+		},
+		{
+			name: "masked",
+			ids:  ids("t", "s"),
+			span: true,
+			bit:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 1)
+			d.SetTraceIDFunc(tt.ids)
+			ctx := context.Background()
+			s := &idSpan{}
+			if tt.span {
+				ctx = ContextWithSpan(ctx, s)
+			}
+			d.DebugfCtx(ctx, tt.bit, "f %v", 1)
+			d.DebugwCtx(ctx, tt.bit, "w", "k", "v")
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+			var msgs, want []string
+			for _, e := range s.entries {
+				if e.Bit != tt.bit {
+					t.Fatalf("span got bit %v, want %v",
+						e.Bit, tt.bit)
+				}
+				msgs = append(msgs, e.Message)
+			}
+			if tt.spans {
+				want = []string{"f 1", "w"}
+			}
+			if !reflect.DeepEqual(msgs, want) {
+				t.Fatalf("span got %q, want %q", msgs, want)
+			}
+		})
+	}
+}
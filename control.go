@@ -0,0 +1,192 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// controlState is the document served by ControlHandler.
+type controlState struct {
+	Enabled bool     `json:"enabled"`
+	Mask    string   `json:"mask"`
+	Exclude string   `json:"exclude"`
+	Names   []string `json:"names"`
+	Level   string   `json:"level"`
+
+	Filter     string `json:"filter,omitempty"`
+	DropFilter string `json:"drop_filter,omitempty"`
+}
+
+// ControlHandler returns an http.Handler that exposes the enabled state, mask
+// and level of d, i.e. for mounting under /debug/dbglog.  GET returns the
+// state as a JSON object.  POST changes it using the form values enabled
+// (a boolean), mask and exclude (ParseMask lists), level (a ParseLevel
+// name) and filter and drop_filter (regular expressions, empty to remove
+// them, see SetFilter) and then returns the new state.  With the form value
+// for, a duration such as 10m, enabling and the mask are reverted after that
+// time, see EnableFor and SetMaskFor.  Nothing is changed if any value is
+// invalid.  Only values in the request body are used and cross origin POST
+// requests, i.e. forms submitted from another site, are rejected.
+func (d *DbgLogger) ControlHandler() http.Handler {
+	return http.HandlerFunc(d.serveControl)
+}
+
+func (d *DbgLogger) serveControl(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		if crossOrigin(r) {
+			http.Error(w, "dbglog: cross origin request rejected",
+				http.StatusForbidden)
+			return
+		}
+		if err := d.control(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed),
+			http.StatusMethodNotAllowed)
+		return
+	}
+
+	names := d.MaskNames()
+	if names == nil {
+		names = []string{}
+	}
+	state := controlState{
+		Enabled: d.Enabled(),
+		Mask:    maskString(d.GetMask()),
+		Exclude: maskString(d.GetExcludeMask()),
+		Names:   names,
+		Level:   d.GetLevel().String(),
+	}
+	d.mu.Lock()
+	if d.filter != nil {
+		state.Filter = d.filter.String()
+	}
+	if d.dropFilter != nil {
+		state.DropFilter = d.dropFilter.String()
+	}
+	d.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// crossOrigin reports whether r was sent by a page of another origin, using
+// the Sec-Fetch-Site header of browsers or, when it is absent, Origin.
+// Requests without either header are not from a browser and are allowed.
+func crossOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "":
+	case "same-origin", "none":
+		return false
+	default:
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	return err != nil || u.Host != r.Host
+}
+
+// control applies the form values in the body of r.
+func (d *DbgLogger) control(r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	var dur time.Duration
+	if v, ok := r.PostForm["for"]; ok {
+		var err error
+		if dur, err = time.ParseDuration(v[0]); err != nil {
+			return err
+		}
+	}
+
+	var apply []func()
+	if v, ok := r.PostForm["enabled"]; ok {
+		enabled, err := strconv.ParseBool(v[0])
+		if err != nil {
+			return err
+		}
+		if enabled && dur > 0 {
+			apply = append(apply, func() { d.EnableFor(dur) })
+		} else if enabled {
+			apply = append(apply, d.Enable)
+		} else {
+			apply = append(apply, d.Disable)
+		}
+	}
+	if v, ok := r.PostForm["mask"]; ok {
+		mask, err := d.ParseMask(v[0])
+		if err != nil {
+			return err
+		}
+		if dur > 0 {
+			apply = append(apply, func() {
+				d.SetMaskFor(mask, dur)
+			})
+		} else {
+			apply = append(apply, func() { d.SetMask(mask) })
+		}
+	}
+	if v, ok := r.PostForm["exclude"]; ok {
+		mask, err := d.ParseMask(v[0])
+		if err != nil {
+			return err
+		}
+		apply = append(apply, func() { d.SetExcludeMask(mask) })
+	}
+	if v, ok := r.PostForm["level"]; ok {
+		l, err := ParseLevel(v[0])
+		if err != nil {
+			return err
+		}
+		apply = append(apply, func() { d.SetLevel(l) })
+	}
+	for key, set := range map[string]func(*regexp.Regexp){
+		"filter":      d.SetFilter,
+		"drop_filter": d.SetDropFilter,
+	} {
+		v, ok := r.PostForm[key]
+		if !ok {
+			continue
+		}
+		var re *regexp.Regexp
+		if v[0] != "" {
+			var err error
+			if re, err = regexp.Compile(v[0]); err != nil {
+				return err
+			}
+		}
+		apply = append(apply, func() { set(re) })
+	}
+	for _, f := range apply {
+		f()
+	}
+	return nil
+}
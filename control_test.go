@@ -0,0 +1,225 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package dbglog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// control sends a request with the form values in body to the ControlHandler
+// of d and returns the recorded response.
+func control(d *DbgLogger, method, target, body string,
+	header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type",
+			"application/x-www-form-urlencoded")
+	}
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	d.ControlHandler().ServeHTTP(w, r)
+	return w
+}
+
+func TestControlHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		header map[string]string
+		code   int
+		want   controlState // state after the request
+	}{
+		{
+			name:   "get",
+			method: http.MethodGet,
+			target: "/",
+			code:   http.StatusOK,
+			want:   controlState{Enabled: true, Mask: "0x1"},
+		},
+		{
+			name:   "get does not change",
+			method: http.MethodGet,
+			target: "/?enabled=false&mask=0x4",
+			code:   http.StatusOK,
+			want:   controlState{Enabled: true, Mask: "0x1"},
+		},
+		{
+			name:   "disable",
+			method: http.MethodPost,
+			target: "/",
+			body:   "enabled=false",
+			code:   http.StatusOK,
+			want:   controlState{Enabled: false, Mask: "0x1"},
+		},
+		{
+			name:   "mask and level",
+			method: http.MethodPost,
+			target: "/",
+			body:   "mask=0x6&exclude=0x2&level=warn",
+			code:   http.StatusOK,
+			want: controlState{Enabled: true, Mask: "0x6",
+				Exclude: "0x2", Level: "warn"},
+		},
+		{
+			name:   "filters",
+			method: http.MethodPost,
+			target: "/",
+			body:   "filter=^a&drop_filter=b$",
+			code:   http.StatusOK,
+			want: controlState{Enabled: true, Mask: "0x1",
+				Filter: "^a", DropFilter: "b$"},
+		},
+		{
+			name:   "query ignored",
+			method: http.MethodPost,
+			target: "/?enabled=false",
+			code:   http.StatusOK,
+			want:   controlState{Enabled: true, Mask: "0x1"},
+		},
+		{
+			name:   "same origin",
+			method: http.MethodPost,
+			target: "http://example.com/",
+			body:   "mask=0x4",
+			header: map[string]string{
+				"Sec-Fetch-Site": "same-origin",
+			},
+			code: http.StatusOK,
+			want: controlState{Enabled: true, Mask: "0x4"},
+		},
+		{
+			name:   "cross site",
+			method: http.MethodPost,
+			target: "/",
+			body:   "enabled=false",
+			header: map[string]string{
+				"Sec-Fetch-Site": "cross-site",
+			},
+			code: http.StatusForbidden,
+			want: controlState{Enabled: true, Mask: "0x1"},
+		},
+		{
+			name:   "cross origin",
+			method: http.MethodPost,
+			target: "http://example.com/",
+			body:   "enabled=false",
+			header: map[string]string{
+				"Origin": "http://evil.example",
+			},
+			code: http.StatusForbidden,
+			want: controlState{Enabled: true, Mask: "0x1"},
+		},
+		{
+			name:   "bad value changes nothing",
+			method: http.MethodPost,
+			target: "/",
+			body:   "enabled=false&mask=0xzz",
+			code:   http.StatusBadRequest,
+			want:   controlState{Enabled: true, Mask: "0x1"},
+		},
+		{
+			name:   "bad level",
+			method: http.MethodPost,
+			target: "/",
+			body:   "level=loud",
+			code:   http.StatusBadRequest,
+			want:   controlState{Enabled: true, Mask: "0x1"},
+		},
+		{
+			name:   "bad filter",
+			method: http.MethodPost,
+			target: "/",
+			body:   "filter=(",
+			code:   http.StatusBadRequest,
+			want:   controlState{Enabled: true, Mask: "0x1"},
+		},
+		{
+			name:   "bad duration",
+			method: http.MethodPost,
+			target: "/",
+			body:   "enabled=false&for=soon",
+			code:   http.StatusBadRequest,
+			want:   controlState{Enabled: true, Mask: "0x1"},
+		},
+		{
+			name:   "method",
+			method: http.MethodPut,
+			target: "/",
+			body:   "enabled=false",
+			code:   http.StatusMethodNotAllowed,
+			want:   controlState{Enabled: true, Mask: "0x1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := newTestLogger("", 0, 1)
+			level := d.GetLevel().String()
+			w := control(d, tt.method, tt.target, tt.body,
+				tt.header)
+			if w.Code != tt.code {
+				t.Fatalf("code %v, want %v: %v", w.Code,
+					tt.code, w.Body)
+			}
+
+			w = control(d, http.MethodGet, "/", "", nil)
+			var got controlState
+			if err := json.NewDecoder(w.Body).Decode(&got); err !=
+				nil {
+				t.Fatal(err)
+			}
+			want := tt.want
+			if want.Exclude == "" {
+				want.Exclude = "0x0"
+			}
+			if want.Level == "" {
+				want.Level = level
+			}
+			if got.Enabled != want.Enabled ||
+				got.Mask != want.Mask ||
+				got.Exclude != want.Exclude ||
+				got.Level != want.Level ||
+				got.Filter != want.Filter ||
+				got.DropFilter != want.DropFilter {
+				t.Fatalf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestControlHandlerFor(t *testing.T) {
+	d, _ := newTestLogger("", 0, 1)
+	d.Disable()
+	body := url.Values{"enabled": {"true"}, "mask": {"0x4"},
+		"for": {"1h"}}.Encode()
+	if w := control(d, http.MethodPost, "/", body, nil); w.Code !=
+		http.StatusOK {
+		t.Fatalf("code %v: %v", w.Code, w.Body)
+	}
+	if !d.Enabled() || d.GetMask() != 4 {
+		t.Fatalf("enabled %v mask %#x", d.Enabled(), d.GetMask())
+	}
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DebugfCount increments the counter for key and returns the new count.  When
+// debug is enabled the message is printed with " (#count)" appended.  The
+// counter is incremented regardless of the enabled state.
+func (d *DbgLogger) DebugfCount(key string, format string,
+	v ...interface{}) uint64 {
+	d.cmu.Lock()
+	if d.counts == nil {
+		d.counts = make(map[string]uint64)
+	}
+	d.counts[key]++
+	n := d.counts[key]
+	d.cmu.Unlock()
+
+	if d.wanted() {
+		s := strings.TrimSuffix(fmt.Sprintf(format, v...), "\n")
+		d.output(2, LevelDebug, 0, fmt.Sprintf("%v (#%v)", s, n))
+	}
+	return n
+}
+
+// ResetCount resets the DebugfCount counter for key.
+func (d *DbgLogger) ResetCount(key string) {
+	d.cmu.Lock()
+	delete(d.counts, key)
+	d.cmu.Unlock()
+}
@@ -0,0 +1,80 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDebugfCount(t *testing.T) {
+	d, b := newTestLogger("", 0, 0)
+	tests := []struct {
+		key  string
+		want uint64
+	}{
+		{"a", 1},
+		{"a", 2},
+		{"b", 1},
+		{"a", 3},
+		{"b", 2},
+	}
+	for _, tt := range tests {
+		if n := d.DebugfCount(tt.key, "item %v", tt.key); n != tt.want {
+			t.Fatalf("%v: got %v, want %v", tt.key, n, tt.want)
+		}
+	}
+	if !strings.HasSuffix(b.String(), "item b (#2)\n") {
+		t.Fatalf("no count in %q", b.String())
+	}
+
+	d.ResetCount("a")
+	if n := d.DebugfCount("a", "x"); n != 1 {
+		t.Fatalf("after reset got %v, want 1", n)
+	}
+	if n := d.DebugfCount("b", "x"); n != 3 {
+		t.Fatalf("reset changed other key, got %v, want 3", n)
+	}
+}
+
+func TestDebugfCountConcurrent(t *testing.T) {
+	d, _ := newTestLogger("", 0, 0)
+	const workers, per = 8, 100
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var last uint64
+			for j := 0; j < per; j++ {
+				n := d.DebugfCount("k", "x")
+				if n <= last {
+					t.Errorf("not monotonic: %v after %v",
+						n, last)
+				}
+				last = n
+			}
+		}()
+	}
+	wg.Wait()
+	if n := d.DebugfCount("k", "x"); n != workers*per+1 {
+		t.Fatalf("got %v, want %v", n, workers*per+1)
+	}
+}
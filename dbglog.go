@@ -23,80 +23,418 @@
 // log.Logger functions as well.  I.e. d.Printf("hello\n").
 // Do read the log pkg documentation as well and note that in order to use the
 // log flags one must import pkg log.
+//
+// The Debug functions render their own lines using the prefix, flags and
+// output of the embedded log.Logger.  The layout is the same as the one used
+// by log.Logger except that the separator between the header and the message
+// can be changed with SetPrefixSeparator.
+//
+// A Debug call that prints nothing does not format its message but, as for
+// any function taking ...interface{}, the caller allocates the arguments that
+// have to be boxed.  Guard such calls in hot loops with On, V or
+// DebugEnabledM, or build with the dbglog_disabled tag, to keep the disabled
+// path free of allocations.
 package dbglog
 
 import (
+	"fmt"
 	"io"
 	"log"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// Masks that select no bits and all bits.
+const (
+	DebugNone uint64 = 0
+	DebugAll  uint64 = ^DebugNone
 )
 
 // Opaque receiver type used by the dbglog package.
 type DbgLogger struct {
 	*log.Logger
-	enabled	bool
-	mask	uint64
+	*shared
+	parent  *DbgLogger   // logger d was derived from with Sub
+	depth   atomic.Int32 // extra frames to skip for the caller
+	ringOn  atomic.Bool  // ring holds entries
+	statsOn atomic.Bool  // count messages in stats
+	stats   stats        // message counters, see SetStats
+
+	mu sync.Mutex // protects config, buf and ring and serializes writes
+	config
+	buf      []byte   // line being assembled, from bufPool
+	ring     [][]byte // last lines, see SetRingSize
+	ringNext int      // next slot in ring
+	dupKey   string   // last printed message, see SetDedup
+	dupEntry Entry    // last printed entry
+	repeats  int      // times dupKey was repeated since it was printed
+
+	dropped atomic.Uint64 // lines that could not be written
+
+	cmu    sync.Mutex        // protects counts
+	counts map[string]uint64 // DebugfCount counters
+
+	smu        sync.Mutex           // protects this group
+	emitted    map[string]int       // DebugfN emissions per key
+	suppressed map[string]uint64    // suppressed messages per key
+	last       map[string]lastValue // DebugfIfChanged values per key
+	lastTTL    time.Duration        // DebugfIfChanged eviction
+	lastSweep  time.Time            // last DebugfIfChanged eviction run
+	every      map[string]time.Time // DebugEvery emissions per call site
+}
+
+// shared is the state a DbgLogger shares with the loggers derived from it.
+type shared struct {
+	enabled atomic.Bool
+	mask    atomic.Uint64
+	exclude atomic.Uint64 // bits that are never printed
+	level   atomic.Int32  // Level
+	verbose atomic.Int32  // verbosity, see V
+
+	bitLevels [64]atomic.Int32 // Level+1 per bit or 0, see SetBitLevel
+
+	nmu            sync.Mutex        // protects this group
+	bits           map[string]uint64 // registered bits by name
+	bitNames       []string          // registered names by bit number
+	groups         map[string]uint64 // bit groups by name, see DefineGroup
+	pending        []string          // unregistered names, see SetMaskSpec
+	pendingExclude []string          // see SetExcludeMaskSpec
+
+	tmu   sync.Mutex // protects scope
+	scope scoped     // temporary changes, see WithMask and EnableFor
+
+	omu      sync.Mutex           // protects onChange
+	onChange []func(bool, uint64) // see OnConfigChange
+
+	cats categories // see RegisterCategory
+}
+
+// config is the configuration of a DbgLogger.  Derived loggers start out
+// with a copy of the configuration of their parent.
+type config struct {
+	sep          string              // separator between header and message
+	sepSet       bool                // SetPrefixSeparator was called
+	timeFormat   string              // time layout, empty for log flags
+	timeFunc     func() time.Time    // clock, nil for time.Now
+	color        bool                // colorize output
+	tagColors    map[string]Color    // DebugfTagged colors
+	levelColors  map[Level]Color     // line colors per level
+	bitColors    map[uint64]Color    // debug line colors per bit
+	routes       []route             // routing rules in registration order
+	outputs      []tee               // additional outputs, see AddOutput
+	attempts     int                 // write retries
+	backoff      time.Duration       // initial delay between write retries
+	errorHandler func(error)         // called when a line is not written
+	maxPartial   int                 // Writer partial line limit
+	lineBuffered bool                // one Write per line, not per message
+	skipRuntime  bool                // drop runtime frames from stacks
+	dropFrames   []string            // stack frame prefixes to drop
+	stackAll     bool                // DebugStack prints all goroutines
+	spewDepth    int                 // DebugSpew nesting limit
+	syncWrites   bool                // Sync the output after every message
+	format       Format              // output encoding
+	template     *template.Template  // text layout, see SetTemplate
+	bitTag       BitTag              // placement of bit names
+	multiline    MultilineMode       // continuation lines of messages
+	escape       bool                // escape non-printable characters
+	maxMessage   int                 // message length limit, 0 for none
+	traceIDs     traceIDFunc         // trace ids, see SetTraceIDFunc
+	fields       []Field             // added to messages, see WithFields
+	redactors    []Redactor          // scrub messages, see AddRedactor
+	filter       *regexp.Regexp      // lines to print, see SetFilter
+	dropFilter   *regexp.Regexp      // lines to drop, see SetDropFilter
+	hooks        []func(Entry)       // called for every printed message
+	dedup        bool                // collapse repeated messages
+	samples      map[uint64]*sampler // sampling per bit, see SetSample
+}
+
+// clone returns a copy of c that shares no maps or slices with it.
+func (c *config) clone() config {
+	n := *c
+	if c.tagColors != nil {
+		n.tagColors = make(map[string]Color, len(c.tagColors))
+		for k, v := range c.tagColors {
+			n.tagColors[k] = v
+		}
+	}
+	if c.levelColors != nil {
+		n.levelColors = make(map[Level]Color, len(c.levelColors))
+		for k, v := range c.levelColors {
+			n.levelColors[k] = v
+		}
+	}
+	if c.bitColors != nil {
+		n.bitColors = make(map[uint64]Color, len(c.bitColors))
+		for k, v := range c.bitColors {
+			n.bitColors[k] = v
+		}
+	}
+	n.routes = append([]route(nil), c.routes...)
+	n.outputs = append([]tee(nil), c.outputs...)
+	n.hooks = append(([]func(Entry))(nil), c.hooks...)
+	n.dropFrames = append([]string(nil), c.dropFrames...)
+	n.redactors = append([]Redactor(nil), c.redactors...)
+	n.fields = append([]Field(nil), c.fields...)
+	if c.samples != nil {
+		// The samplers are shared so that the counts are.
+		n.samples = make(map[uint64]*sampler, len(c.samples))
+		for k, v := range c.samples {
+			n.samples[k] = v
+		}
+	}
+	return n
 }
 
 // log.Printf equivalent but only prints when debug is enabled.
 func (d *DbgLogger) Debugf(format string, v ...interface{}) {
-	if d.enabled {
-		d.Printf(format, v...)
+	if !DebugCompiled {
+		return
+	}
+	if d.wanted() {
+		d.output(2, LevelDebug, 0, fmt.Sprintf(format, v...))
 	}
 }
 
 // log.Print equivalent but only prints when debug is enabled.
 func (d *DbgLogger) Debug(v ...interface{}) {
-	if d.enabled {
+	if !DebugCompiled {
+		return
+	}
+	if d.wanted() {
+		d.output(2, LevelDebug, 0, fmt.Sprint(v...))
 	}
-		d.Print(v...)
 }
 
 // log.Println equivalent but only prints when debug is enabled.
 func (d *DbgLogger) Debugln(v ...interface{}) {
-	if d.enabled {
-		d.Println(v...)
+	if !DebugCompiled {
+		return
+	}
+	if d.wanted() {
+		d.output(2, LevelDebug, 0, fmt.Sprintln(v...))
 	}
 }
 
 // In order for the Debug functions to print the Enable function must be called.
 // This is a runtime function that can be called at any time.
 func (d *DbgLogger) Enable() {
-	d.enabled = true
+	if !d.enabled.Swap(true) {
+		d.notify()
+	}
 }
 
 // In order to disable Debug functions call Disable.
 // This is a runtime function that can be called at any time.
 func (d *DbgLogger) Disable() {
-	d.enabled = false
+	if d.enabled.Swap(false) {
+		d.notify()
+	}
+}
+
+// Enabled returns true if debug is enabled.
+func (d *DbgLogger) Enabled() bool {
+	return d.enabled.Load()
 }
 
 // SetMask sets the mask for the Debug*M functions.
 // This mask is considered a bitfield.
+// This is a runtime function that can be called at any time.
 func (d *DbgLogger) SetMask(mask uint64) {
-	d.mask = mask
+	if d.mask.Swap(mask) != mask {
+		d.notify()
+	}
+}
+
+// GetMask returns the mask set with SetMask.
+func (d *DbgLogger) GetMask() uint64 {
+	return d.mask.Load()
+}
+
+// EnableBits sets bits in the mask, leaving the other bits alone.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) EnableBits(bits uint64) {
+	if old := d.mask.Or(bits); old|bits != old {
+		d.notify()
+	}
+}
+
+// DisableBits clears bits in the mask, leaving the other bits alone.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) DisableBits(bits uint64) {
+	if old := d.mask.And(^bits); old&^bits != old {
+		d.notify()
+	}
+}
+
+// ToggleBits flips bits in the mask, leaving the other bits alone.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) ToggleBits(bits uint64) {
+	if bits == 0 {
+		return
+	}
+	for {
+		old := d.mask.Load()
+		if d.mask.CompareAndSwap(old, old^bits) {
+			break
+		}
+	}
+	d.notify()
+}
+
+// OnConfigChange adds a function that is called with the enabled state and
+// the mask after either of them changed, i.e. to persist the configuration or
+// to pass it on to other processes.  It is called by the goroutine that made
+// the change, also for changes made through a logger derived with Sub, and
+// must not change the configuration itself.
+func (d *DbgLogger) OnConfigChange(f func(enabled bool, mask uint64)) {
+	d.omu.Lock()
+	d.onChange = append(d.onChange, f)
+	d.omu.Unlock()
+}
+
+// notify calls the OnConfigChange functions.
+func (d *DbgLogger) notify() {
+	d.omu.Lock()
+	fs := d.onChange
+	d.omu.Unlock()
+
+	if len(fs) == 0 {
+		return
+	}
+	enabled, mask := d.Enabled(), d.GetMask()
+	for _, f := range fs {
+		f(enabled, mask)
+	}
+}
+
+// SetExcludeMask sets bits that are not printed even when they are set in the
+// mask, i.e. to express every bit except the packet tracer:
+//
+//	d.SetMask(dbglog.DebugAll)
+//	d.SetExcludeMask(bitPacket)
+//
+// A message for several bits is not printed if any of them is excluded.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) SetExcludeMask(mask uint64) {
+	d.exclude.Store(mask)
+}
+
+// GetExcludeMask returns the mask set with SetExcludeMask.
+func (d *DbgLogger) GetExcludeMask() uint64 {
+	return d.exclude.Load()
+}
+
+// isSet returns true if debug is enabled, all of bit is set in the mask, none
+// of it in the exclude mask and its level, if set, is at most LevelDebug.
+func (d *DbgLogger) isSet(bit uint64) bool {
+	if !DebugCompiled {
+		return false
+	}
+	return d.Enabled() && bit != 0 && bit&d.GetMask() == bit &&
+		bit&d.GetExcludeMask() == 0 && d.debugOK(bit)
+}
+
+// printed returns true if a debug message for bit is printed.  A bit of 0 is
+// used for the messages of the functions that do not take a mask bit.
+func (d *DbgLogger) printed(bit uint64) bool {
+	if !DebugCompiled {
+		return false
+	}
+	if bit == 0 {
+		return d.Enabled()
+	}
+	return d.isSet(bit)
+}
+
+// wanted returns true if the messages of the Debug functions are printed or
+// captured in the ring buffer.
+func (d *DbgLogger) wanted() bool {
+	if !DebugCompiled {
+		return false
+	}
+	if d.Enabled() || d.ringOn.Load() {
+		return true
+	}
+	d.count(LevelDebug, 0, false)
+	return false
+}
+
+// wantedM returns true if the messages of the Debug*M functions for bit are
+// printed or captured in the ring buffer.
+func (d *DbgLogger) wantedM(bit uint64) bool {
+	if !DebugCompiled {
+		return false
+	}
+	if d.isSet(bit) || (bit != 0 && d.ringOn.Load()) {
+		return true
+	}
+	d.count(LevelDebug, bit, false)
+	return false
+}
+
+// DebugEnabled returns true if the messages of the Debug functions are used,
+// that is printed or captured by the ring buffer.  Use it to avoid building
+// expensive arguments when debug is disabled.
+func (d *DbgLogger) DebugEnabled() bool {
+	return d.wanted()
+}
+
+// DebugEnabledM returns true if the messages of the Debug*M functions for bit
+// are used, that is printed or captured by the ring buffer.  Use it to avoid
+// building expensive arguments, i.e. hex dumps:
+//
+//	if d.DebugEnabledM(myDebugNet) {
+//		d.DebugfM(myDebugNet, "%v", hex.Dump(packet))
+//	}
+func (d *DbgLogger) DebugEnabledM(bit uint64) bool {
+	return d.wantedM(bit)
 }
 
 // log.Printf equivalent but only prints when debug is enabled and bit is
 // enabled in the mask.
 func (d *DbgLogger) DebugfM(bit uint64, format string, v ...interface{}) {
-	if d.enabled == true && bit != 0 && bit & d.mask == bit {
-		d.Printf(format, v...)
+	if !DebugCompiled {
+		return
+	}
+	if d.wantedM(bit) {
+		d.output(2, LevelDebug, bit, fmt.Sprintf(format, v...))
 	}
 }
 
 // log.Print equivalent but only prints when debug is enabled and bit is
 // enabled in the mask.
 func (d *DbgLogger) DebugM(bit uint64, format string, v ...interface{}) {
-	if d.enabled == true && bit != 0 && bit & d.mask == bit {
-		d.Print(v...)
+	if !DebugCompiled {
+		return
+	}
+	if d.wantedM(bit) {
+		d.output(2, LevelDebug, bit, fmt.Sprint(v...))
 	}
 }
 
 // log.Println equivalent but only prints when debug is enabled and bit is
 // enabled in the mask.
 func (d *DbgLogger) DebuglnM(bit uint64, format string, v ...interface{}) {
-	if d.enabled == true && bit != 0 && bit & d.mask == bit {
-		d.Println(v...)
+	if !DebugCompiled {
+		return
+	}
+	if d.wantedM(bit) {
+		d.output(2, LevelDebug, bit, fmt.Sprintln(v...))
+	}
+}
+
+// DebugLazy prints the string returned by fn when debug is enabled and bit is
+// enabled in the mask.  fn is not called otherwise, which keeps the cost of
+// expensive messages out of hot paths.
+func (d *DbgLogger) DebugLazy(bit uint64, fn func() string) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wantedM(bit) {
+		d.output(2, LevelDebug, bit, fn())
 	}
 }
 
@@ -124,10 +462,24 @@ func (d *DbgLogger) DebuglnM(bit uint64, format string, v ...interface{}) {
 	}
 */
 func New(out io.Writer, prefix string, flag int) *DbgLogger {
-	d := &DbgLogger{}
+	d := &DbgLogger{
+		shared: &shared{},
+		config: config{
+			maxPartial: defaultMaxPartial,
+			color:      isTerminal(out),
+		},
+	}
 	d.Logger = log.New(out, prefix, flag)
+	d.level.Store(int32(LevelInfo))
 	return d
 }
+
+// NewNop returns a DbgLogger that discards all output.  It is useful as a
+// placeholder where a Logger is required but no output is desired.
+func NewNop() *DbgLogger {
+	return New(io.Discard, "", 0)
+}
+
 /*
 const	(
 	myDebugOne = 1<<0
@@ -0,0 +1,365 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestLogger returns an enabled logger with mask that writes to the
+// returned buffer.
+func newTestLogger(prefix string, flag int, mask uint64) (*DbgLogger,
+	*bytes.Buffer) {
+	var b bytes.Buffer
+	d := New(&b, prefix, flag)
+	d.Enable()
+	d.SetMask(mask)
+	return d, &b
+}
+
+// lines returns the lines written to b.
+func lines(b *bytes.Buffer) []string {
+	s := strings.TrimSuffix(b.String(), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+func TestPrefixSeparator(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		sep    *string
+		want   string
+	}{
+		{name: "default", prefix: "app ", want: "app msg\n"},
+		{name: "empty", prefix: "app ", sep: ptr(""), want: "appmsg\n"},
+		{
+			name:   "tab",
+			prefix: "app ",
+			sep:    ptr("\t"),
+			want:   "app\tmsg\n",
+		},
+		{
+			name:   "custom",
+			prefix: "app",
+			sep:    ptr(" | "),
+			want:   "app | msg\n",
+		},
+		{name: "no header", sep: ptr(" | "), want: "msg\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger(tt.prefix, 0, 0)
+			if tt.sep != nil {
+				d.SetPrefixSeparator(*tt.sep)
+			}
+			d.Debugf("msg")
+			if got := b.String(); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixSeparatorLogger(t *testing.T) {
+	for _, prefix := range []string{"foo:", "foo: ", ""} {
+		for _, flag := range []int{0, log.Lmsgprefix} {
+			d, b := newTestLogger(prefix, flag, 0)
+			d.Debugf("hello")
+			got := b.String()
+			b.Reset()
+			d.Printf("hello")
+			if want := b.String(); got != want {
+				t.Errorf("%q %v: got %q, want %q", prefix, flag,
+					got, want)
+			}
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// lockedBuffer is a bytes.Buffer that can be written by a background
+// goroutine while the test reads it.
+type lockedBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (l *lockedBuffer) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.b.Write(p)
+}
+
+func (l *lockedBuffer) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.b.String()
+}
+
+// waitFor polls cond until it returns true and fails the test after a few
+// seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %v", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// server embeds a Logger like code that only needs debug output does.
+type server struct {
+	Logger
+	name string
+}
+
+func TestLogger(t *testing.T) {
+	s := server{Logger: NewNop(), name: "nop"}
+	s.Enable()
+	s.SetMask(1)
+	s.Debugf("%v", s.name)
+	s.DebugfM(1, "%v", s.name)
+	s.Disable()
+
+	d, b := newTestLogger("", 0, 1)
+	s = server{Logger: d, name: "real"}
+	s.DebugfM(1, "%v", s.name)
+	if got := b.String(); got != "real\n" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestOnConfigChange(t *testing.T) {
+	type config struct {
+		enabled bool
+		mask    uint64
+	}
+	d, _ := newTestLogger("", 0, 1)
+	d.Disable()
+	var got []config
+	d.OnConfigChange(func(enabled bool, mask uint64) {
+		got = append(got, config{enabled, mask})
+	})
+	steps := []struct {
+		name string
+		f    func()
+		want []config // notifications
+	}{
+		{"Enable", d.Enable, []config{{true, 1}}},
+		{"Enable again", d.Enable, nil},
+		{"SetMask", func() { d.SetMask(3) }, []config{{true, 3}}},
+		{"SetMask same", func() { d.SetMask(3) }, nil},
+		{"EnableBits", func() { d.EnableBits(4) }, []config{{true, 7}}},
+		{"EnableBits set", func() { d.EnableBits(4) }, nil},
+		{
+			"DisableBits",
+			func() { d.DisableBits(4) },
+			[]config{{true, 3}},
+		},
+		{"DisableBits clear", func() { d.DisableBits(4) }, nil},
+		{"ToggleBits", func() { d.ToggleBits(2) }, []config{{true, 1}}},
+		{"Sub", func() { d.Sub("s ").SetMask(2) }, []config{{true, 2}}},
+		{
+			"SetMaskSpec pending",
+			func() { d.SetMaskSpec("net") },
+			[]config{{true, 0}},
+		},
+		{
+			"RegisterBit pending",
+			func() { d.RegisterBit("net") },
+			[]config{{true, 1}},
+		},
+		{"RegisterBit again", func() { d.RegisterBit("net") }, nil},
+		{"SetMaskSpec same", func() { d.SetMaskSpec("net,io") }, nil},
+		{"DefineGroup set", func() { d.DefineGroup("io", 1) }, nil},
+		{
+			"SetMaskByNames",
+			func() { d.SetMaskByNames("") },
+			[]config{{true, 0}},
+		},
+		{"Disable", d.Disable, []config{{false, 0}}},
+		{"Disable again", d.Disable, nil},
+	}
+	for _, s := range steps {
+		got = nil
+		s.f()
+		if !reflect.DeepEqual(got, s.want) {
+			t.Fatalf("%v: got %v, want %v", s.name, got, s.want)
+		}
+	}
+}
+
+func TestMaskBits(t *testing.T) {
+	tests := []struct {
+		name     string
+		mask     uint64
+		f        func(d *DbgLogger)
+		want     uint64
+		notified bool
+	}{
+		{
+			name:     "EnableBits",
+			mask:     1,
+			f:        func(d *DbgLogger) { d.EnableBits(2 | 4) },
+			want:     1 | 2 | 4,
+			notified: true,
+		},
+		{
+			name: "EnableBits none",
+			mask: 1,
+			f:    func(d *DbgLogger) { d.EnableBits(0) },
+			want: 1,
+		},
+		{
+			name:     "DisableBits",
+			mask:     1 | 2 | 4,
+			f:        func(d *DbgLogger) { d.DisableBits(2 | 8) },
+			want:     1 | 4,
+			notified: true,
+		},
+		{
+			name: "DisableBits none",
+			mask: 1,
+			f:    func(d *DbgLogger) { d.DisableBits(0) },
+			want: 1,
+		},
+		{
+			name:     "ToggleBits",
+			mask:     1 | 2,
+			f:        func(d *DbgLogger) { d.ToggleBits(2 | 4) },
+			want:     1 | 4,
+			notified: true,
+		},
+		{
+			name: "ToggleBits none",
+			mask: 1,
+			f:    func(d *DbgLogger) { d.ToggleBits(0) },
+			want: 1,
+		},
+		{
+			name:     "DebugAll",
+			mask:     1,
+			f:        func(d *DbgLogger) { d.SetMask(DebugAll) },
+			want:     ^uint64(0),
+			notified: true,
+		},
+		{
+			name:     "DebugNone",
+			mask:     1,
+			f:        func(d *DbgLogger) { d.SetMask(DebugNone) },
+			want:     0,
+			notified: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := newTestLogger("", 0, tt.mask)
+			notified := false
+			d.OnConfigChange(func(bool, uint64) { notified = true })
+			tt.f(d)
+			if got := d.GetMask(); got != tt.want {
+				t.Fatalf("got mask %#x, want %#x", got, tt.want)
+			}
+			if notified != tt.notified {
+				t.Fatalf("notified %v, want %v", notified,
+					tt.notified)
+			}
+		})
+	}
+}
+
+func TestDebugAll(t *testing.T) {
+	d, b := newTestLogger("", 0, DebugAll)
+	d.DebugfM(1, "first")
+	d.DebugfM(1<<63, "last")
+	d.DebugfM(DebugAll, "all")
+	d.SetMask(DebugNone)
+	d.DebugfM(1, "none")
+	want := []string{"first", "last", "all"}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestSetExcludeMask(t *testing.T) {
+	d, b := newTestLogger("", 0, DebugAll)
+	d.SetExcludeMask(2)
+	d.DebugfM(1, "included")
+	d.DebugfM(2, "excluded")
+	d.DebugfM(1|2, "one bit excluded")
+	d.DebugfM(1|4, "none excluded")
+	if d.DebugEnabledM(2) || d.On(2).Enabled() {
+		t.Fatal("excluded bit enabled")
+	}
+	d.SetExcludeMask(0)
+	d.DebugfM(2, "cleared")
+	want := []string{"included", "none excluded", "cleared"}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestDebugLazy(t *testing.T) {
+	tests := []struct {
+		name     string
+		disabled bool
+		mask     uint64
+		exclude  uint64
+		want     []string
+	}{
+		{name: "bit on", mask: 1, want: []string{"lazy"}},
+		{name: "bit off", mask: 2},
+		{name: "disabled", disabled: true, mask: 1},
+		{name: "excluded", mask: 1, exclude: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, tt.mask)
+			if tt.disabled {
+				d.Disable()
+			}
+			d.SetExcludeMask(tt.exclude)
+			calls := 0
+			d.DebugLazy(1, func() string {
+				calls++
+				return "lazy"
+			})
+			if want := len(tt.want); calls != want {
+				t.Fatalf("fn called %v times, want %v", calls,
+					want)
+			}
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+// Package dbglogsql traces database/sql drivers with a dbglog.DbgLogger, see
+// dbglog.DbgLogger.WrapDriver.
+package dbglogsql
+
+import (
+	"database/sql/driver"
+
+	"github.com/marcopeereboom/dbglog"
+)
+
+// Wrap returns a driver that prints the queries and statements run through
+// drv, with their arguments, the number of affected rows, errors and timings,
+// with d when bit is enabled in the mask, i.e.
+//
+//	sql.Register("pq-traced", dbglogsql.Wrap(&pq.Driver{}, d, debugSQL))
+func Wrap(drv driver.Driver, d *dbglog.DbgLogger, bit uint64) driver.Driver {
+	return d.WrapDriver(drv, bit)
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglogsql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/marcopeereboom/dbglog"
+)
+
+// execDriver is a driver whose connections only run Exec.
+type execDriver struct{}
+
+func (execDriver) Open(string) (driver.Conn, error) { return execConn{}, nil }
+
+type execConn struct{}
+
+func (execConn) Prepare(string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+func (execConn) Close() error              { return nil }
+func (execConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+func (execConn) ExecContext(context.Context, string,
+	[]driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(2), nil
+}
+
+func TestWrap(t *testing.T) {
+	if !dbglog.DebugCompiled {
+		t.Skip("debug output is compiled out")
+	}
+	var b bytes.Buffer
+	d := dbglog.New(&b, "", 0)
+	d.Enable()
+	d.SetMask(1)
+	c, err := Wrap(execDriver{}, d, 1).(driver.DriverContext).
+		OpenConnector("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(c)
+	defer db.Close()
+	if _, err := db.Exec("delete", 7); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); !strings.Contains(got, "exec delete") ||
+		!strings.Contains(got, "rows=2") {
+		t.Fatalf("not traced: %q", got)
+	}
+}
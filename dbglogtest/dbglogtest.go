@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+// Package dbglogtest provides dbglog loggers for tests.  It registers the
+// -dbglog flag and must only be imported by tests.
+package dbglogtest
+
+import (
+	"flag"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/marcopeereboom/dbglog"
+)
+
+// testFlag is the -dbglog flag of test binaries.  -dbglog alone enables debug
+// with all bits, -dbglog=0x3 enables debug with a mask.
+type testFlag struct {
+	spec string
+}
+
+func (f *testFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.spec
+}
+
+func (f *testFlag) Set(s string) error {
+	if b, err := strconv.ParseBool(s); err == nil {
+		if b {
+			s = "all"
+		} else {
+			s = ""
+		}
+	}
+	f.spec = s
+	return nil
+}
+
+func (f *testFlag) IsBoolFlag() bool { return true }
+
+// testDebug holds the -dbglog flag.
+var testDebug testFlag
+
+func init() {
+	if flag.Lookup("dbglog") == nil {
+		flag.Var(&testDebug, "dbglog",
+			"enable dbglog debug output of dbglogtest loggers, "+
+				"optionally with a mask, i.e. -dbglog=net,0x4")
+	}
+}
+
+// testWriter writes lines with the Log method of a test.
+type testWriter struct {
+	t testing.TB
+
+	mu   sync.Mutex // protects done
+	done bool       // the test completed
+}
+
+func (w *testWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.done {
+		w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+	return len(p), nil
+}
+
+// New returns a DbgLogger that prints with t.Log so that its output is
+// interleaved with the output of the test and only shown for failing tests or
+// with go test -v.  Lines carry the file and line of the caller since t.Log
+// cannot report them.  Debug is enabled when the test binary runs with the
+// -dbglog flag, i.e.
+//
+//	go test -run TestRPC -args -dbglog=net,rpc
+//
+// The mask is set with SetMaskSpec so the flag may name bits that the test
+// registers after New returns.  Output after the test completed is discarded.
+func New(t testing.TB, prefix string) *dbglog.DbgLogger {
+	w := &testWriter{t: t}
+	t.Cleanup(func() {
+		w.mu.Lock()
+		w.done = true
+		w.mu.Unlock()
+	})
+
+	d := dbglog.New(w, prefix, log.Lshortfile)
+	if testDebug.spec != "" {
+		if err := d.SetMaskSpec(testDebug.spec); err != nil {
+			t.Logf("-dbglog: %v", err)
+		}
+		d.Enable()
+	}
+	return d
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglogtest
+
+import (
+	"testing"
+
+	"github.com/marcopeereboom/dbglog"
+)
+
+func TestNewLazyNames(t *testing.T) {
+	if !dbglog.DebugCompiled {
+		t.Skip("debug output is compiled out")
+	}
+	old := testDebug.spec
+	defer func() { testDebug.spec = old }()
+	testDebug.Set("net,0x8")
+
+	d := New(t, "")
+	if !d.Enabled() || d.GetMask() != 0x8 {
+		t.Fatalf("enabled %v mask %#x", d.Enabled(), d.GetMask())
+	}
+	net := d.RegisterBit("net")
+	if d.GetMask() != net|0x8 {
+		t.Fatalf("net not resolved, mask %#x", d.GetMask())
+	}
+	if !d.DebugEnabledM(net) {
+		t.Fatal("net not enabled")
+	}
+}
+
+func TestNewDisabled(t *testing.T) {
+	old := testDebug.spec
+	defer func() { testDebug.spec = old }()
+	testDebug.Set("false")
+
+	if d := New(t, ""); d.Enabled() {
+		t.Fatal("enabled without -dbglog")
+	}
+}
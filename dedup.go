@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+// SetDedup makes the logger collapse consecutive identical messages.  A
+// message is identical to the last one if its level, bit, text and fields are
+// the same.  Repeats are not printed, instead the next different message is
+// preceded by a "last message repeated N times" line.  Turning deduplication
+// off prints the pending summary.
+func (d *DbgLogger) SetDedup(on bool) {
+	d.mu.Lock()
+	d.dedup = on
+	d.mu.Unlock()
+	d.flushDedup()
+}
+
+// flushDedup prints the summary of the pending repeats, if any, and forgets
+// the last message so that the next one is printed.
+func (d *DbgLogger) flushDedup() {
+	d.mu.Lock()
+	var err error
+	if d.repeats != 0 {
+		err = d.flushRepeats(d.Flags())
+	}
+	d.dupKey = ""
+	d.dupEntry = Entry{}
+	handler := d.errorHandler
+	d.mu.Unlock()
+
+	if err != nil {
+		d.dropped.Add(1)
+		if handler != nil {
+			handler(err)
+		}
+	}
+}
+
+// dedupe reports whether e is identical to the last printed message and
+// counts it if so.  Otherwise the summary of the repeats of the last message
+// is printed and e becomes the last message.  Must be called with mu held.
+func (d *DbgLogger) dedupe(e *Entry, flag int) (bool, error) {
+	b := append([]byte(nil), byte(e.Level))
+	b = append(b, maskString(e.Bit)...)
+	b = append(b, ' ')
+	b = append(b, e.Message...)
+	for _, f := range e.Fields {
+		b = append(b, ' ')
+		b = appendField(b, f)
+	}
+	key := string(b)
+	if key == d.dupKey {
+		d.repeats++
+		return true, nil
+	}
+
+	var err error
+	if d.repeats != 0 {
+		err = d.flushRepeats(flag)
+	}
+	d.dupKey = key
+	d.dupEntry = Entry{Prefix: e.Prefix, Level: e.Level, Bit: e.Bit,
+		File: e.File, Line: e.Line}
+	return false, err
+}
+
+// flushRepeats prints the summary of the repeats of the last message.  buf is
+// preserved.  Must be called with mu held.
+func (d *DbgLogger) flushRepeats(flag int) error {
+	e := d.dupEntry
+	e.Time = d.now()
+	e.Message = "last message repeated " + itoa10(d.repeats) + " times"
+	d.repeats = 0
+
+	line, bp := d.buf, getBuf()
+	d.buf = *bp
+	d.render(&e, flag, d.format)
+	err, failed, _ := d.deliver(&e, flag)
+	if err == nil && len(failed) != 0 {
+		err = failed[0]
+	}
+	putBuf(bp, d.buf)
+	d.buf = line
+	return err
+}
@@ -0,0 +1,112 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetDedup(t *testing.T) {
+	tests := []struct {
+		name  string
+		print func(d *DbgLogger)
+		want  []string
+	}{
+		{
+			name: "repeats",
+			print: func(d *DbgLogger) {
+				d.Debugf("a")
+				d.Debugf("a")
+				d.Debugf("a")
+				d.Debugf("b")
+			},
+			want: []string{"a", "last message repeated 2 times",
+				"b"},
+		},
+		{
+			name: "single repeat",
+			print: func(d *DbgLogger) {
+				d.Debugf("a")
+				d.Debugf("a")
+				d.Debugf("b")
+			},
+			want: []string{"a", "last message repeated 1 times",
+				"b"},
+		},
+		{
+			name: "level differs",
+			print: func(d *DbgLogger) {
+				d.Infof("a")
+				d.Warnf("a")
+			},
+			want: []string{"[INFO] a", "[WARN] a"},
+		},
+		{
+			name: "bit differs",
+			print: func(d *DbgLogger) {
+				d.DebugfM(1, "a")
+				d.DebugfM(2, "a")
+			},
+			want: []string{"a", "a"},
+		},
+		{
+			name: "fields differ",
+			print: func(d *DbgLogger) {
+				d.Debugw("a", "k", 1)
+				d.Debugw("a", "k", 2)
+			},
+			want: []string{"a k=1", "a k=2"},
+		},
+		{
+			name: "turned off",
+			print: func(d *DbgLogger) {
+				d.Debugf("a")
+				d.Debugf("a")
+				d.SetDedup(false)
+				d.Debugf("a")
+			},
+			want: []string{"a", "last message repeated 1 times",
+				"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 1|2)
+			d.SetDedup(true)
+			tt.print(d)
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetDedupHooks(t *testing.T) {
+	d, _ := newTestLogger("", 0, 0)
+	d.SetDedup(true)
+	var got []string
+	d.AddHook(func(e Entry) { got = append(got, e.Message) })
+	d.Debugf("a")
+	d.Debugf("a")
+	d.Debugf("b")
+	if want := []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+)
+
+// std is the default logger used by the package level functions.
+var std atomic.Pointer[DbgLogger]
+
+func init() {
+	std.Store(New(os.Stderr, "", log.LstdFlags))
+}
+
+// Default returns the default logger used by the package level functions.  It
+// writes to os.Stderr with log.LstdFlags and debug disabled.
+func Default() *DbgLogger {
+	return std.Load()
+}
+
+// SetDefault makes d the default logger used by the package level functions.
+func SetDefault(d *DbgLogger) {
+	std.Store(d)
+}
+
+// Debugf calls Debugf on the default logger.
+func Debugf(format string, v ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d := Default(); d.wanted() {
+		d.output(2, LevelDebug, 0, fmt.Sprintf(format, v...))
+	}
+}
+
+// Debug calls Debug on the default logger.
+func Debug(v ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d := Default(); d.wanted() {
+		d.output(2, LevelDebug, 0, fmt.Sprint(v...))
+	}
+}
+
+// Debugln calls Debugln on the default logger.
+func Debugln(v ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d := Default(); d.wanted() {
+		d.output(2, LevelDebug, 0, fmt.Sprintln(v...))
+	}
+}
+
+// DebugfM calls DebugfM on the default logger.
+func DebugfM(bit uint64, format string, v ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d := Default(); d.wantedM(bit) {
+		d.output(2, LevelDebug, bit, fmt.Sprintf(format, v...))
+	}
+}
+
+// DebugM calls DebugM on the default logger.
+func DebugM(bit uint64, format string, v ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d := Default(); d.wantedM(bit) {
+		d.output(2, LevelDebug, bit, fmt.Sprint(v...))
+	}
+}
+
+// DebuglnM calls DebuglnM on the default logger.
+func DebuglnM(bit uint64, format string, v ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d := Default(); d.wantedM(bit) {
+		d.output(2, LevelDebug, bit, fmt.Sprintln(v...))
+	}
+}
+
+// Enable calls Enable on the default logger.
+func Enable() {
+	Default().Enable()
+}
+
+// Disable calls Disable on the default logger.
+func Disable() {
+	Default().Disable()
+}
+
+// SetMask calls SetMask on the default logger.
+func SetMask(mask uint64) {
+	Default().SetMask(mask)
+}
@@ -0,0 +1,223 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvVar is the environment variable read by NewFromEnv.
+const EnvVar = "DBGLOG"
+
+// directive parses the value of a configuration directive and returns the
+// function that applies it.
+type directive func(d *DbgLogger, value string) (func(), error)
+
+// timeLayouts are the names of the layouts accepted by the time directive.
+var timeLayouts = map[string]string{
+	"rfc3339":      time.RFC3339,
+	"rfc3339micro": RFC3339Micro,
+	"rfc3339nano":  time.RFC3339Nano,
+}
+
+// switches are the configuration directives that take no value.
+var switches = map[string]func(d *DbgLogger){
+	"enabled":  (*DbgLogger).Enable,
+	"disabled": (*DbgLogger).Disable,
+	"enable":   (*DbgLogger).Enable,
+	"disable":  (*DbgLogger).Disable,
+	"text":     func(d *DbgLogger) { d.SetFormat(FormatText) },
+	"json":     func(d *DbgLogger) { d.SetFormat(FormatJSON) },
+	"logfmt":   func(d *DbgLogger) { d.SetFormat(FormatLogfmt) },
+	"color":    func(d *DbgLogger) { d.SetColor(true) },
+	"nocolor":  func(d *DbgLogger) { d.SetColor(false) },
+	"escape":   func(d *DbgLogger) { d.SetEscape(true) },
+}
+
+// maskSpec returns the ParseMask list of the + separated list value after
+// checking that it is valid.  Names that are not registered yet are allowed,
+// they are resolved once registered, see SetMaskSpec.
+func (d *DbgLogger) maskSpec(value string) (string, error) {
+	spec := strings.ReplaceAll(value, "+", ",")
+	d.nmu.Lock()
+	_, _, err := d.parseMask(spec, true)
+	d.nmu.Unlock()
+	return spec, err
+}
+
+// directives are the configuration directives that take a value.
+var directives = map[string]directive{
+	"mask": func(d *DbgLogger, value string) (func(), error) {
+		spec, err := d.maskSpec(value)
+		if err != nil {
+			return nil, err
+		}
+		return func() { d.SetMaskSpec(spec) }, nil
+	},
+	"exclude": func(d *DbgLogger, value string) (func(), error) {
+		spec, err := d.maskSpec(value)
+		if err != nil {
+			return nil, err
+		}
+		return func() { d.SetExcludeMaskSpec(spec) }, nil
+	},
+	"categories": func(d *DbgLogger, value string) (func(), error) {
+		names := strings.Split(value, "+")
+		for _, name := range names {
+			if _, ok := d.CategoryByName(name); !ok && name != "" {
+				return nil, fmt.Errorf(
+					"dbglog: unknown category %q", name)
+			}
+		}
+		return func() {
+			d.SetCategoriesByNames(strings.Join(names, ","))
+		}, nil
+	},
+	"level": func(d *DbgLogger, value string) (func(), error) {
+		l, err := ParseLevel(value)
+		if err != nil {
+			return nil, err
+		}
+		return func() { d.SetLevel(l) }, nil
+	},
+	"v": func(d *DbgLogger, value string) (func(), error) {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("dbglog: invalid verbosity %q",
+				value)
+		}
+		return func() { d.SetVerbosity(n) }, nil
+	},
+	"time": func(d *DbgLogger, value string) (func(), error) {
+		if l, ok := timeLayouts[strings.ToLower(value)]; ok {
+			value = l
+		}
+		return func() { d.SetTimeFormat(value) }, nil
+	},
+}
+
+// Configure applies a comma separated list of directives.  The known
+// directives are:
+//
+//	enabled		enable debug, enable is accepted as well
+//	disabled	disable debug, disable is accepted as well
+//	mask=m		set the mask, m is a + separated ParseMask list, i.e.
+//			mask=net+0x4, names that are not registered yet are
+//			added once they are, see SetMaskSpec
+//	exclude=m	set the exclude mask, m is a mask list like for mask
+//	categories=c	enable exactly the categories of the + separated list
+//			c, see RegisterCategory
+//	level=l		set the level, i.e. level=warn
+//	v=n		set the verbosity used by V, i.e. v=2
+//	time=t		set the time layout, t is a time.Time.Format layout or
+//			one of rfc3339, rfc3339micro and rfc3339nano
+//	text		print lines in the log.Logger layout
+//	json		print lines as JSON objects
+//	logfmt		print lines as logfmt
+//	color		enable colorized output
+//	nocolor		disable colorized output
+//	escape		escape non-printable characters in messages
+//
+// A comma in a value is written as \, i.e. time=Jan 2\, 15:04:05.  Nothing is
+// changed if spec contains an unknown or invalid directive, a value for a
+// directive that takes none or a directive without its value.
+func (d *DbgLogger) Configure(spec string) error {
+	var (
+		apply   []func()
+		unknown []string
+	)
+	for _, s := range splitSpec(spec) {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		name, value, hasValue := strings.Cut(s, "=")
+		if sw, ok := switches[name]; ok {
+			if hasValue {
+				return fmt.Errorf("dbglog: directive %v takes "+
+					"no value", name)
+			}
+			apply = append(apply, func() { sw(d) })
+			continue
+		}
+		dir, ok := directives[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		if value == "" {
+			return fmt.Errorf("dbglog: directive %v needs a value",
+				name)
+		}
+		f, err := dir(d, value)
+		if err != nil {
+			return err
+		}
+		apply = append(apply, f)
+	}
+	if len(unknown) != 0 {
+		return fmt.Errorf("dbglog: unknown directive: %v",
+			strings.Join(unknown, ", "))
+	}
+	for _, f := range apply {
+		f()
+	}
+	return nil
+}
+
+// splitSpec splits spec at the commas that are not escaped with a backslash
+// and unescapes the escaped ones.
+func splitSpec(spec string) []string {
+	var (
+		list []string
+		b    strings.Builder
+	)
+	for i := 0; i < len(spec); i++ {
+		switch {
+		case spec[i] == '\\' && i+1 < len(spec) && spec[i+1] == ',':
+			b.WriteByte(',')
+			i++
+		case spec[i] == ',':
+			list = append(list, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(spec[i])
+		}
+	}
+	return append(list, b.String())
+}
+
+// ConfigureFromEnv applies the directives in environment variable varName,
+// i.e. DBGLOG=enabled,mask=0x3,color.  See Configure for the directives.  An
+// unset or empty variable changes nothing.
+func (d *DbgLogger) ConfigureFromEnv(varName string) error {
+	return d.Configure(os.Getenv(varName))
+}
+
+// NewFromEnv returns a new DbgLogger configured from the directives in
+// environment variable EnvVar, i.e. DBGLOG=enable,mask=net+db.  The logger is
+// returned even if the variable is invalid, the error reports why it was left
+// unconfigured.
+func NewFromEnv(out io.Writer, prefix string, flag int) (*DbgLogger, error) {
+	d := New(out, prefix, flag)
+	return d, d.ConfigureFromEnv(EnvVar)
+}
@@ -0,0 +1,210 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigure(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  string
+		check func(d *DbgLogger) bool
+	}{
+		{"empty", "", func(d *DbgLogger) bool { return !d.Enabled() }},
+		{"enabled", "enabled", (*DbgLogger).Enabled},
+		{"enable", " enable ", (*DbgLogger).Enabled},
+		{
+			"disabled",
+			"enabled,disabled",
+			func(d *DbgLogger) bool { return !d.Enabled() },
+		},
+		{
+			"mask",
+			"mask=net+0x8",
+			func(d *DbgLogger) bool { return d.GetMask() == 1|8 },
+		},
+		{
+			"exclude",
+			"exclude=db",
+			func(d *DbgLogger) bool {
+				return d.GetExcludeMask() == 2
+			},
+		},
+		{
+			"level",
+			"level=warn",
+			func(d *DbgLogger) bool {
+				return d.GetLevel() == LevelWarn
+			},
+		},
+		{
+			"verbosity",
+			"v=3",
+			func(d *DbgLogger) bool {
+				return d.GetVerbosity() == 3
+			},
+		},
+		{
+			"escaped comma",
+			`enabled,time=Jan 2\, 15:04,v=1`,
+			func(d *DbgLogger) bool {
+				return d.timeFormat == "Jan 2, 15:04" &&
+					d.GetVerbosity() == 1
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewNop()
+			d.RegisterBit("net")
+			d.RegisterBit("db")
+			if err := d.Configure(tt.spec); err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(d) {
+				t.Fatalf("%q not applied", tt.spec)
+			}
+		})
+	}
+}
+
+func TestConfigureFullSpec(t *testing.T) {
+	d, b := newTestLogger("", 0, 0)
+	d.Disable()
+	net := d.RegisterBit("net")
+	db := d.RegisterBit("db")
+	err := d.Configure("enabled,level=debug,mask=net+db,v=2,json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Enabled() || d.GetMask() != net|db ||
+		d.GetLevel() != LevelDebug || d.GetVerbosity() != 2 {
+		t.Fatalf("not applied: enabled %v mask %#x level %v v %v",
+			d.Enabled(), d.GetMask(), d.GetLevel(),
+			d.GetVerbosity())
+	}
+	d.DebugfM(db, "msg")
+	if !strings.HasPrefix(b.String(), "{") {
+		t.Fatalf("not JSON: %q", b.String())
+	}
+}
+
+func TestConfigureTime(t *testing.T) {
+	pinned := time.Date(2024, 2, 29, 13, 4, 5, 123456789,
+		time.FixedZone("CET", 3600))
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"time=rfc3339", "2024-02-29T13:04:05+01:00 msg\n"},
+		{"time=rfc3339micro", "2024-02-29T13:04:05.123456+01:00 msg\n"},
+		{"time=RFC3339Micro", "2024-02-29T13:04:05.123456+01:00 msg\n"},
+		{
+			"time=rfc3339nano",
+			"2024-02-29T13:04:05.123456789+01:00 msg\n",
+		},
+		{"time=15:04:05.000", "13:04:05.123 msg\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 0)
+			d.SetTimeFunc(func() time.Time { return pinned })
+			if err := d.Configure(tt.spec); err != nil {
+				t.Fatal(err)
+			}
+			d.Debugf("msg")
+			if got := b.String(); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigureInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want []string // substrings of the error
+	}{
+		{"unknown", "enabled,bogus,nope=1", []string{"bogus", "nope"}},
+		{"bad mask", "enabled,mask=0xzz", []string{"0xzz"}},
+		{"bad exclude", "enabled,exclude=9x", []string{"9x"}},
+		{"switch value", "enabled=false", []string{"enabled"}},
+		{"empty switch value", "nocolor=,enabled", []string{"nocolor"}},
+		{"bad level", "enabled,level=loud", []string{"loud"}},
+		{"bad verbosity", "enabled,v=x", []string{`"x"`}},
+		{"bare mask", "enabled,mask", []string{"mask"}},
+		{"empty mask", "enabled,mask=", []string{"mask"}},
+		{
+			"unescaped comma",
+			"enabled,time=Jan 2, 15:04",
+			[]string{"15"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewNop()
+			err := d.Configure(tt.spec)
+			if err == nil {
+				t.Fatal("no error")
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(err.Error(), s) {
+					t.Fatalf("%q not in %v", s, err)
+				}
+			}
+			if d.Enabled() {
+				t.Fatal("invalid spec was partially applied")
+			}
+		})
+	}
+}
+
+func TestConfigureFromEnv(t *testing.T) {
+	t.Setenv("DBGLOG_TEST", "enabled,mask=0x6")
+	d := NewNop()
+	if err := d.ConfigureFromEnv("DBGLOG_TEST"); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Enabled() || d.GetMask() != 6 {
+		t.Fatalf("enabled %v mask %#x", d.Enabled(), d.GetMask())
+	}
+}
+
+func TestNewFromEnvPendingNames(t *testing.T) {
+	t.Setenv(EnvVar, "enable,mask=net+0x8,exclude=db")
+	d, err := NewFromEnv(io.Discard, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Enabled() || d.GetMask() != 8 {
+		t.Fatalf("enabled %v mask %#x", d.Enabled(), d.GetMask())
+	}
+	net := d.RegisterBit("net")
+	db := d.RegisterBit("db")
+	if d.GetMask() != net|8 || d.GetExcludeMask() != db {
+		t.Fatalf("mask %#x exclude %#x", d.GetMask(),
+			d.GetExcludeMask())
+	}
+}
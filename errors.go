@@ -0,0 +1,38 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import "fmt"
+
+// DebugErr does nothing if err is nil.  Otherwise it prints msg followed by
+// ": " and err when debug is enabled and bit is enabled in the mask.  err is
+// formatted with %+v so errors that carry a stack trace, i.e. those of
+// github.com/pkg/errors, print it.  err is returned unchanged which makes
+// logging and propagating a one liner:
+//
+//	if err != nil {
+//		return d.DebugErr(myDebugNet, err, "dial")
+//	}
+func (d *DbgLogger) DebugErr(bit uint64, err error, msg string) error {
+	if !DebugCompiled {
+		return err
+	}
+	if err != nil && d.wantedM(bit) {
+		d.output(2, LevelDebug, bit, fmt.Sprintf("%v: %+v", msg, err))
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// stackError prints a fake stack trace when formatted with %+v.
+type stackError struct{}
+
+func (stackError) Error() string { return "stack error" }
+
+func (e stackError) Format(s fmt.State, verb rune) {
+	fmt.Fprint(s, e.Error())
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprint(s, "\n\tmain.go:1")
+	}
+}
+
+func TestDebugErr(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		name string
+		err  error
+		mask uint64
+		want []string
+	}{
+		{name: "nil", mask: 1},
+		{
+			name: "error",
+			err:  boom,
+			mask: 1,
+			want: []string{"dial: boom"},
+		},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("read: %w", boom),
+			mask: 1,
+			want: []string{"dial: read: boom"},
+		},
+		{
+			name: "stack",
+			err:  stackError{},
+			mask: 1,
+			want: []string{"dial: stack error", "\tmain.go:1"},
+		},
+		{name: "masked", err: boom, mask: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, tt.mask)
+			if err := d.DebugErr(1, tt.err, "dial"); err != tt.err {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
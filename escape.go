@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// SetEscape makes the text format escape the non-printable characters of
+// messages the way strconv.Quote does, i.e. a NUL becomes \x00 and an ANSI
+// escape \x1b, so that logged data cannot corrupt a terminal or log file.
+// Newlines and tabs are kept, as are backslashes.  Invalid UTF-8 is escaped
+// byte by byte.  Fields are always quoted when needed and the JSON format
+// escapes everything on its own.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) SetEscape(on bool) {
+	d.mu.Lock()
+	d.escape = on
+	d.mu.Unlock()
+}
+
+// escaped returns s with its non-printable characters escaped, see
+// SetEscape.
+func escaped(s string) string {
+	i := 0
+	for i < len(s) {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		if (r == utf8.RuneError && n == 1) ||
+			(r != '\n' && r != '\t' && !strconv.IsPrint(r)) {
+			break
+		}
+		i += n
+	}
+	if i == len(s) {
+		return s
+	}
+
+	b := make([]byte, 0, len(s)+8)
+	b = append(b, s[:i]...)
+	for i < len(s) {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && n == 1:
+			b = append(b, `\x`...)
+			b = append(b, "0123456789abcdef"[s[i]>>4],
+				"0123456789abcdef"[s[i]&0xf])
+		case r == '\n' || r == '\t' || strconv.IsPrint(r):
+			b = append(b, s[i:i+n]...)
+		default:
+			q := strconv.QuoteRune(r)
+			b = append(b, q[1:len(q)-1]...)
+		}
+		i += n
+	}
+	return string(b)
+}
@@ -0,0 +1,91 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEscaped(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{name: "printable", s: `a "b" \ ü`, want: `a "b" \ ü`},
+		{name: "newline and tab", s: "a\n\tb", want: "a\n\tb"},
+		{name: "nul", s: "a\x00b", want: `a\x00b`},
+		{name: "escape", s: "\x1b[31mred", want: `\x1b[31mred`},
+		{name: "carriage return", s: "a\rb", want: `a\rb`},
+		{name: "del", s: "a\x7f", want: `a\x7f`},
+		{name: "line separator", s: "a\u2028b", want: `a\u2028b`},
+		{name: "invalid utf-8", s: "a\xff\xfeb", want: `a\xff\xfeb`},
+		{name: "truncated rune", s: "a\xe2\x82", want: `a\xe2\x82`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escaped(tt.s); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetEscape(t *testing.T) {
+	tests := []struct {
+		name   string
+		escape bool
+		mode   MultilineMode
+		want   []string
+	}{
+		{
+			name: "off",
+			want: []string{"p a\x00", "b\x1b"},
+		},
+		{
+			name:   "on",
+			escape: true,
+			want:   []string{`p a\x00`, `b\x1b`},
+		},
+		{
+			name:   "prefix",
+			escape: true,
+			mode:   MultilinePrefix,
+			want:   []string{`p a\x00`, `p b\x1b`},
+		},
+		{
+			name:   "indent",
+			escape: true,
+			mode:   MultilineIndent,
+			want:   []string{`p a\x00`, `  b\x1b`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("p ", 0, 0)
+			d.SetEscape(tt.escape)
+			d.SetMultilineMode(tt.mode)
+			d.Debugf("a\x00\nb\x1b")
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
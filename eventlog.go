@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+// Event types of ReportEvent.
+const (
+	eventError       = 0x0001
+	eventWarning     = 0x0002
+	eventInformation = 0x0004
+)
+
+// eventType returns the event type messages of level l are reported as.
+func eventType(l Level) uint16 {
+	switch {
+	case l >= LevelError:
+		return eventError
+	case l == LevelWarn:
+		return eventWarning
+	}
+	return eventInformation
+}
+
+// EventLogOption configures an EventLog backend.
+type EventLogOption func(*eventLogConfig)
+
+// eventLogConfig selects the messages an EventLog reports.
+type eventLogConfig struct {
+	level Level  // minimum level
+	mask  uint64 // bits of the debug messages that are reported as well
+	id    uint32 // event identifier
+}
+
+// EventLogLevel sets the minimum level of the reported messages, the default
+// is LevelWarn.
+func EventLogLevel(l Level) EventLogOption {
+	return func(c *eventLogConfig) {
+		c.level = l
+	}
+}
+
+// EventLogMask makes an EventLog also report the debug messages with any of
+// the bits in mask set, as informational events.
+func EventLogMask(mask uint64) EventLogOption {
+	return func(c *eventLogConfig) {
+		c.mask = mask
+	}
+}
+
+// EventLogID sets the event identifier of the reported events, the default
+// is 1.
+func EventLogID(id uint32) EventLogOption {
+	return func(c *eventLogConfig) {
+		c.id = id
+	}
+}
+
+// reported reports whether an event is reported for e.
+func (c *eventLogConfig) reported(e *Entry) bool {
+	if e.Level == LevelDebug && e.Bit&c.mask != 0 {
+		return true
+	}
+	return e.Level >= c.level
+}
+
+// newEventLogConfig returns the configuration for opts.
+func newEventLogConfig(opts []EventLogOption) eventLogConfig {
+	c := eventLogConfig{level: LevelWarn, id: 1}
+	for _, o := range opts {
+		o(&c)
+	}
+	return c
+}
@@ -0,0 +1,51 @@
+//go:build !windows
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import "errors"
+
+// errNoEventLog is returned by NewEventLog on systems other than Windows.
+var errNoEventLog = errors.New("dbglog: the event log is only available " +
+	"on Windows")
+
+// EventLog reports messages to the Windows Event Log.  It is only available
+// on Windows, elsewhere NewEventLog fails.
+type EventLog struct{}
+
+var _ Backend = (*EventLog)(nil)
+
+// NewEventLog fails on systems other than Windows.
+func NewEventLog(source string, opts ...EventLogOption) (*EventLog, error) {
+	return nil, errNoEventLog
+}
+
+// Write fails on systems other than Windows.
+func (l *EventLog) Write(p []byte) (int, error) {
+	return 0, errNoEventLog
+}
+
+// WriteEntry fails on systems other than Windows.
+func (l *EventLog) WriteEntry(e *Entry, line []byte) error {
+	return errNoEventLog
+}
+
+// Close does nothing on systems other than Windows.
+func (l *EventLog) Close() error {
+	return nil
+}
@@ -0,0 +1,87 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import "testing"
+
+func TestEventType(t *testing.T) {
+	tests := []struct {
+		level Level
+		want  uint16
+	}{
+		{LevelTrace, eventInformation},
+		{LevelDebug, eventInformation},
+		{LevelInfo, eventInformation},
+		{LevelWarn, eventWarning},
+		{LevelError, eventError},
+		{LevelFatal, eventError},
+	}
+	for _, tt := range tests {
+		if got := eventType(tt.level); got != tt.want {
+			t.Errorf("%v: got %#x, want %#x", tt.level, got,
+				tt.want)
+		}
+	}
+}
+
+func TestEventLogReported(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []EventLogOption
+		e    Entry
+		want bool
+	}{
+		{name: "default info", e: Entry{Level: LevelInfo}},
+		{
+			name: "default warn",
+			e:    Entry{Level: LevelWarn},
+			want: true,
+		},
+		{
+			name: "level",
+			opts: []EventLogOption{EventLogLevel(LevelInfo)},
+			e:    Entry{Level: LevelInfo},
+			want: true,
+		},
+		{
+			name: "level above",
+			opts: []EventLogOption{EventLogLevel(LevelError)},
+			e:    Entry{Level: LevelWarn},
+		},
+		{
+			name: "mask",
+			opts: []EventLogOption{EventLogMask(2 | 4)},
+			e:    Entry{Level: LevelDebug, Bit: 4},
+			want: true,
+		},
+		{
+			name: "mask other bit",
+			opts: []EventLogOption{EventLogMask(2 | 4)},
+			e:    Entry{Level: LevelDebug, Bit: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newEventLogConfig(tt.opts)
+			if got := c.reported(&tt.e); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+//go:build windows
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32                  = syscall.NewLazyDLL("advapi32.dll")
+	procRegisterEventSource   = advapi32.NewProc("RegisterEventSourceW")
+	procDeregisterEventSource = advapi32.NewProc("DeregisterEventSource")
+	procReportEvent           = advapi32.NewProc("ReportEventW")
+)
+
+// EventLog is a Backend that reports messages to the Windows Event Log.  By
+// default only warnings and errors are reported, see EventLogLevel and
+// EventLogMask.  Errors and fatal messages are reported as error events,
+// warnings as warning events and everything else as informational events.
+type EventLog struct {
+	config eventLogConfig
+
+	mu     sync.Mutex // protects handle
+	handle uintptr
+}
+
+var _ Backend = (*EventLog)(nil)
+
+// NewEventLog registers source as an event source on the local computer.  The
+// source should be registered in the registry, i.e. by the installer, for
+// the Event Viewer to show the messages without complaint.
+func NewEventLog(source string, opts ...EventLogOption) (*EventLog, error) {
+	s, err := syscall.UTF16PtrFromString(source)
+	if err != nil {
+		return nil, err
+	}
+	h, _, err := procRegisterEventSource.Call(0,
+		uintptr(unsafe.Pointer(s)))
+	if h == 0 {
+		return nil, err
+	}
+	return &EventLog{config: newEventLogConfig(opts), handle: h}, nil
+}
+
+// report reports msg as an event of type.
+func (l *EventLog) report(typ uint16, msg string) error {
+	s, err := syscall.UTF16PtrFromString(strings.ReplaceAll(
+		strings.TrimRight(msg, "\n"), "\x00", ""))
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.handle == 0 {
+		return syscall.EINVAL
+	}
+	r, _, err := procReportEvent.Call(l.handle, uintptr(typ), 0,
+		uintptr(l.config.id), 0, 1, 0, uintptr(unsafe.Pointer(&s)), 0)
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+// Write reports p as an informational event if informational events are
+// reported.
+func (l *EventLog) Write(p []byte) (int, error) {
+	if l.config.level > LevelInfo {
+		return len(p), nil
+	}
+	if err := l.report(eventInformation, string(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteEntry reports line if e is selected by the options.
+func (l *EventLog) WriteEntry(e *Entry, line []byte) error {
+	if !l.config.reported(e) {
+		return nil
+	}
+	return l.report(eventType(e.Level), string(line))
+}
+
+// Close deregisters the event source.
+func (l *EventLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.handle == 0 {
+		return nil
+	}
+	r, _, err := procDeregisterEventSource.Call(l.handle)
+	l.handle = 0
+	if r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+// Package expvarstats publishes the message counters of a dbglog.DbgLogger
+// with expvar.  It is a separate package because importing expvar registers
+// the /debug/vars handler, which exposes the command line and memory
+// statistics, on http.DefaultServeMux.
+package expvarstats
+
+import (
+	"expvar"
+
+	"github.com/marcopeereboom/dbglog"
+)
+
+// Publish enables the stats of d and publishes them with expvar as name, i.e.
+// in /debug/vars.  Like expvar.Publish it panics if name is already in use.
+func Publish(name string, d *dbglog.DbgLogger) {
+	d.SetStats(true)
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return d.Stats()
+	}))
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package expvarstats
+
+import (
+	"expvar"
+	"io"
+	"testing"
+
+	"github.com/marcopeereboom/dbglog"
+)
+
+func TestPublish(t *testing.T) {
+	if !dbglog.DebugCompiled {
+		t.Skip("debug output is compiled out")
+	}
+	d := dbglog.New(io.Discard, "", 0)
+	d.Enable()
+	d.SetMask(1)
+	Publish("dbglog_test", d)
+	d.DebugfM(1, "printed")
+	d.DebugfM(2, "suppressed")
+
+	v := expvar.Get("dbglog_test")
+	if v == nil {
+		t.Fatal("not published")
+	}
+	want := `{"levels":{"debug":{"emitted":1,"suppressed":1}},` +
+		`"bits":{"0x1":{"emitted":1,"suppressed":0},` +
+		`"0x2":{"emitted":0,"suppressed":1}}}`
+	if got := v.String(); got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// osExit is os.Exit, replaced by tests.
+var osExit = os.Exit
+
+// bufferedOutput is implemented by outputs that hold on to messages, such as
+// AsyncWriter.
+type bufferedOutput interface {
+	Flush() error
+}
+
+// exit flushes the buffered outputs of d and of the loggers it was derived
+// from with Sub, so that the message that made the program exit is not lost,
+// and calls os.Exit(1).
+func (d *DbgLogger) exit() {
+	var ws []io.Writer
+	for l := d; l != nil; l = l.parent {
+		l.mu.Lock()
+		ws = append(ws, l.Logger.Writer())
+		for _, r := range l.routes {
+			ws = append(ws, r.w)
+		}
+		for _, t := range l.outputs {
+			ws = append(ws, t.w)
+		}
+		l.mu.Unlock()
+	}
+
+	for _, w := range ws {
+		if b, ok := w.(bufferedOutput); ok {
+			b.Flush()
+		}
+	}
+	osExit(1)
+}
+
+// fatal prints msg at LevelFatal regardless of the enabled state and level.
+// When bit is enabled in the mask the stack trace of the calling goroutine is
+// appended.  calldepth is that of the exported function.
+func (d *DbgLogger) fatal(calldepth int, bit uint64, msg string) {
+	if bit != 0 && bit&d.GetMask() == bit && bit&d.GetExcludeMask() == 0 {
+		// Skip stack, fatal and the exported function.
+		msg = strings.TrimSuffix(msg, "\n") + "\n" +
+			string(d.filterStack(skipFrames(stack(false), 3)))
+	}
+	d.output(calldepth+1, LevelFatal, bit, msg)
+}
+
+// DebugFatalfM is log.Fatalf equivalent that prints through d at LevelFatal
+// and then calls os.Exit(1) after flushing buffered outputs such as an
+// AsyncWriter.  The message is always printed, regardless of
+// Enable and SetLevel, the mask only decides whether the stack trace is
+// appended.
+func (d *DbgLogger) DebugFatalfM(bit uint64, format string, v ...interface{}) {
+	d.fatal(2, bit, fmt.Sprintf(format, v...))
+	d.exit()
+}
+
+// DebugPanicfM is log.Panicf equivalent that prints like DebugFatalfM and then
+// panics with the message.
+func (d *DbgLogger) DebugPanicfM(bit uint64, format string, v ...interface{}) {
+	s := fmt.Sprintf(format, v...)
+	d.fatal(2, bit, s)
+	panic(s)
+}
+
+// FatalIf does nothing if err is nil.  Otherwise it prints err at LevelFatal,
+// regardless of Enable and SetLevel, and calls os.Exit(1).
+func (d *DbgLogger) FatalIf(err error) {
+	if err != nil {
+		d.fatal(2, 0, err.Error())
+		d.exit()
+	}
+}
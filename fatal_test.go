@@ -0,0 +1,104 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeExit replaces osExit and returns the exit codes it receives.
+func fakeExit(t *testing.T) *[]int {
+	var codes []int
+	osExit = func(c int) { codes = append(codes, c) }
+	t.Cleanup(func() { osExit = os.Exit })
+	return &codes
+}
+
+func TestDebugFatalfM(t *testing.T) {
+	tests := []struct {
+		name  string
+		mask  uint64
+		stack bool
+	}{
+		{name: "masked", mask: 2},
+		{name: "stack", mask: 1, stack: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codes := fakeExit(t)
+			d, b := newTestLogger("", 0, tt.mask)
+			d.Disable()
+			d.DebugFatalfM(1, "fatal %v", 1)
+			if !reflect.DeepEqual(*codes, []int{1}) {
+				t.Fatalf("exit codes %v", *codes)
+			}
+			got := lines(b)
+			if len(got) == 0 || got[0] != "[FATAL] fatal 1" {
+				t.Fatalf("got %q", got)
+			}
+			stack := strings.Contains(b.String(), "fatal_test.go")
+			if len(got) > 1 != tt.stack || stack != tt.stack {
+				t.Fatalf("got %q, want stack %v", got, tt.stack)
+			}
+		})
+	}
+}
+
+func TestDebugPanicfM(t *testing.T) {
+	codes := fakeExit(t)
+	d, b := newTestLogger("", 0, 2)
+	d.SetLevel(LevelFatal)
+	var r interface{}
+	func() {
+		defer func() { r = recover() }()
+		d.DebugPanicfM(1, "panic %v", 1)
+	}()
+	if r != "panic 1" {
+		t.Fatalf("recovered %v", r)
+	}
+	if len(*codes) != 0 {
+		t.Fatalf("exit codes %v", *codes)
+	}
+	want := []string{"[FATAL] panic 1"}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestFatalIf(t *testing.T) {
+	codes := fakeExit(t)
+	d, b := newTestLogger("", 0, 0)
+	d.Disable()
+	d.FatalIf(nil)
+	if len(*codes) != 0 || b.Len() != 0 {
+		t.Fatalf("nil: exit codes %v, got %q", *codes, b.String())
+	}
+	d.FatalIf(errors.New("boom"))
+	if !reflect.DeepEqual(*codes, []int{1}) {
+		t.Fatalf("exit codes %v", *codes)
+	}
+	want := []string{"[FATAL] boom"}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
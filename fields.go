@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// badKey is used as the key of a value without a valid key.
+const badKey = "!BADKEY"
+
+// Field is a key/value pair attached to a message.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// fieldsOf pairs up alternating keys and values.  A value without a string key
+// gets the key !BADKEY.
+func fieldsOf(kv []interface{}) []Field {
+	fields := make([]Field, 0, (len(kv)+1)/2)
+	for len(kv) > 0 {
+		key, ok := kv[0].(string)
+		if !ok || len(kv) == 1 {
+			fields = append(fields,
+				Field{Key: badKey, Value: kv[0]})
+			kv = kv[1:]
+			continue
+		}
+		fields = append(fields, Field{Key: key, Value: kv[1]})
+		kv = kv[2:]
+	}
+	return fields
+}
+
+// quoteValue returns s quoted if it is empty or contains a blank, a quote, an
+// equal sign or a non-printable character.
+func quoteValue(s string) string {
+	if s == "" || strings.IndexFunc(s, func(r rune) bool {
+		return r <= ' ' || r == '"' || r == '=' || !strconv.IsPrint(r)
+	}) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// appendField appends f to b as key=value.
+func appendField(b []byte, f Field) []byte {
+	b = append(b, quoteValue(f.Key)...)
+	b = append(b, '=')
+	return append(b, quoteValue(fmtValue(f.Value))...)
+}
+
+// fmtValue returns the fmt representation of v.
+func fmtValue(v interface{}) string {
+	return fmt.Sprint(v)
+}
+
+// Debugw prints msg followed by keysAndValues as key=value pairs when debug is
+// enabled.  keysAndValues alternate between string keys and arbitrary values,
+// i.e. d.Debugw("connected", "addr", addr, "tries", n).  Values are quoted
+// when needed so lines remain easy to parse.
+func (d *DbgLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wanted() {
+		d.emit(2, &Entry{Level: LevelDebug, Message: msg,
+			Fields: fieldsOf(keysAndValues)})
+	}
+}
+
+// DebugwM is Debugw but only prints when debug is enabled and bit is enabled in
+// the mask.
+func (d *DbgLogger) DebugwM(bit uint64, msg string,
+	keysAndValues ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wantedM(bit) {
+		d.emit(2, &Entry{Level: LevelDebug, Bit: bit, Message: msg,
+			Fields: fieldsOf(keysAndValues)})
+	}
+}
+
+// WithFields returns a logger derived from d, see Sub, that adds fields to
+// every message it prints, i.e. a request id.  The fields follow those of d,
+// if any, in key order and precede the fields of the message itself.
+func (d *DbgLogger) WithFields(fields map[string]interface{}) *DbgLogger {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	s := d.Sub("")
+	s.mu.Lock()
+	for _, k := range keys {
+		s.fields = append(s.fields, Field{Key: k, Value: fields[k]})
+	}
+	s.mu.Unlock()
+	return s
+}
@@ -0,0 +1,70 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestWithFields(t *testing.T) {
+	d, b := newTestLogger("p ", 0, 1)
+	a := d.WithFields(map[string]interface{}{"b": 2, "a": 1})
+	c := a.WithFields(map[string]interface{}{"c": 3, "a": 0})
+	c.Debugw("w", "k", "v")
+	c.Debugf("f")
+	a.DebugfM(1, "a")
+	d.Debugf("d")
+	want := []string{
+		"p w a=1 b=2 a=0 c=3 k=v",
+		"p f a=1 b=2 a=0 c=3",
+		"p a a=1 b=2",
+		"p d",
+	}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestNewContext(t *testing.T) {
+	ctx := context.Background()
+	if got := FromContext(ctx); got != Default() {
+		t.Fatalf("got %p, want Default %p", got, Default())
+	}
+
+	d, b := newTestLogger("", 0, 1)
+	r := d.WithFields(map[string]interface{}{"request": 7})
+	ctx = NewContext(ctx, r)
+	if got := FromContext(ctx); got != r {
+		t.Fatalf("got %p, want %p", got, r)
+	}
+	FromContext(ctx).Debugf("handled")
+	want := []string{"handled request=7"}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// A context derived from ctx carries the same logger.
+	child, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if got := FromContext(child); got != r {
+		t.Fatalf("child got %p, want %p", got, r)
+	}
+}
@@ -0,0 +1,513 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Period is the interval of time based rotation of a FileWriter.
+type Period int
+
+// Rotation periods.
+const (
+	PeriodNone   Period = iota // no time based rotation
+	PeriodHourly               // a new file every hour
+	PeriodDaily                // a new file every day, at local midnight
+)
+
+// FileOption configures a FileWriter.
+type FileOption func(*FileWriter)
+
+// MaxSize makes a FileWriter rotate the file before it grows beyond bytes.
+func MaxSize(bytes int64) FileOption {
+	return func(f *FileWriter) {
+		f.maxSize = bytes
+	}
+}
+
+// MaxBackups sets the number of rotated files that are kept, older ones are
+// removed.  The default of 0 keeps all of them.
+func MaxBackups(n int) FileOption {
+	return func(f *FileWriter) {
+		f.maxBackups = n
+	}
+}
+
+// Rotate makes a FileWriter start a new file every period.  The path of the
+// FileWriter should contain strftime style directives, i.e.
+// "myapp-%Y-%m-%d.log", so that every period gets its own file.
+func Rotate(period Period) FileOption {
+	return func(f *FileWriter) {
+		f.period = period
+	}
+}
+
+// Compress makes a FileWriter gzip rotated files in the background, appending
+// ".gz" to their names.
+func Compress() FileOption {
+	return func(f *FileWriter) {
+		f.compress = true
+	}
+}
+
+// FileClock sets the clock used for time based rotation and for expanding the
+// path, the default is time.Now.  This is mostly useful for tests.
+func FileClock(now func() time.Time) FileOption {
+	return func(f *FileWriter) {
+		f.now = now
+	}
+}
+
+// strftime expands the directives %Y, %y, %m, %d, %j, %H, %M, %S and %% in
+// tmpl with t.
+func strftime(tmpl string, t time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(tmpl); i++ {
+		c := tmpl[i]
+		if c != '%' || i+1 == len(tmpl) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch tmpl[i] {
+		case 'Y':
+			fmt.Fprintf(&b, "%04d", t.Year())
+		case 'y':
+			fmt.Fprintf(&b, "%02d", t.Year()%100)
+		case 'm':
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case 'd':
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case 'H':
+			fmt.Fprintf(&b, "%02d", t.Hour())
+		case 'M':
+			fmt.Fprintf(&b, "%02d", t.Minute())
+		case 'S':
+			fmt.Fprintf(&b, "%02d", t.Second())
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(tmpl[i])
+		}
+	}
+	return b.String()
+}
+
+// glob returns a filepath.Match pattern matching every expansion of tmpl.
+func glob(tmpl string) string {
+	var b strings.Builder
+	for i := 0; i < len(tmpl); i++ {
+		c := tmpl[i]
+		if c == '%' && i+1 < len(tmpl) &&
+			strings.IndexByte("YymdjHMS", tmpl[i+1]) >= 0 {
+			b.WriteByte('*')
+			i++
+			continue
+		}
+		if c == '%' && i+1 < len(tmpl) && tmpl[i+1] == '%' {
+			i++
+		}
+		if strings.IndexByte("*?[\\", c) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// FileWriter is an io.Writer that appends to a file and rotates it.  Files
+// rotated because of their size are renamed to name.1, name.2 and so on with
+// name.1 being the most recent.  With time based rotation every period is
+// written to the file named by expanding the path in local time.  Periods
+// start at the top of the hour or at midnight on the wall clock, so days that
+// are shorter or longer because of daylight saving time still get a single
+// file.  With Compress the backups are renamed and compressed in the
+// background, in the order they were rotated.
+type FileWriter struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	period     Period
+	compress   bool
+	now        func() time.Time
+
+	wg sync.WaitGroup // running worker, see retire
+
+	mu      sync.Mutex // protects everything below
+	f       *os.File
+	name    string // expanded path of f
+	key     string // period of f
+	size    int64
+	seq     int       // numbers the names of rotated files
+	jobs    []retired // rotated files waiting for the worker
+	working bool      // the worker is running
+	closed  bool
+}
+
+// tmpSuffix ends the names of the files that are still being rotated or
+// compressed.
+const tmpSuffix = ".tmp"
+
+// retired is a file that has been rotated.
+type retired struct {
+	name string // the rotated file, "" if there is none
+	base string // set if rotated by size, name becomes backup 1 of base
+}
+
+// NewFileWriter opens, or creates, the file at path for appending.  path may
+// contain the strftime style directives %Y, %y, %m, %d, %j, %H, %M, %S which
+// are expanded with the time the file is opened.
+func NewFileWriter(path string, opts ...FileOption) (*FileWriter, error) {
+	f := &FileWriter{path: path, now: time.Now}
+	for _, o := range opts {
+		o(f)
+	}
+	if err := f.open(f.now()); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// periodKey returns the period t falls in.
+func (f *FileWriter) periodKey(t time.Time) string {
+	switch f.period {
+	case PeriodHourly:
+		return t.Format("2006010215")
+	case PeriodDaily:
+		return t.Format("20060102")
+	}
+	return ""
+}
+
+// open opens the file for t.  Must be called with mu held or before f is
+// shared.
+func (f *FileWriter) open(t time.Time) error {
+	name := strftime(f.path, t)
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE,
+		0644)
+	if err != nil {
+		return err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.f = file
+	f.name = name
+	f.key = f.periodKey(t)
+	f.size = fi.Size()
+	return nil
+}
+
+// backup returns the name of backup n of base.
+func backup(base string, n int) string {
+	return fmt.Sprintf("%v.%v", base, n)
+}
+
+// existing returns the name backup n of base exists under, compressed or not.
+func existing(base string, n int) (string, bool) {
+	b := backup(base, n)
+	for _, name := range []string{b, b + ".gz"} {
+		if _, err := os.Stat(name); err == nil {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// gzip compresses the file name to name.gz and removes it.  name.gz keeps the
+// modification time of name for prune.  On failure the file is left alone.
+func gzipFile(name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := name + ".gz" + tmpSuffix
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		if fi, serr := in.Stat(); serr == nil {
+			os.Chtimes(tmp, fi.ModTime(), fi.ModTime())
+		}
+		err = os.Rename(tmp, name+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(name)
+}
+
+// retire finishes the rotation of r.  With compression this is left to a
+// background worker so that writers do not wait for it.  The worker retires
+// the files in the order they were rotated.  Must be called with mu held.
+func (f *FileWriter) retire(r retired) error {
+	if !f.compress {
+		err := f.finish(r)
+		if r.base == "" {
+			f.prune(f.name)
+		}
+		return err
+	}
+	f.jobs = append(f.jobs, r)
+	if !f.working {
+		f.working = true
+		f.wg.Add(1)
+		go f.work()
+	}
+	return nil
+}
+
+// work retires the queued files until there are none left.
+func (f *FileWriter) work() {
+	defer f.wg.Done()
+	for {
+		f.mu.Lock()
+		if len(f.jobs) == 0 {
+			f.working = false
+			f.mu.Unlock()
+			return
+		}
+		r := f.jobs[0]
+		f.jobs = f.jobs[1:]
+		f.mu.Unlock()
+
+		f.finish(r)
+		if r.base == "" {
+			// Another rotation may have happened in the
+			// meantime.
+			f.mu.Lock()
+			current := f.name
+			f.mu.Unlock()
+			f.prune(current)
+		}
+	}
+}
+
+// finish turns a file rotated by size into backup 1 of its base after
+// shifting the existing backups and compresses it if requested.  It only uses
+// the options of f.
+func (f *FileWriter) finish(r retired) error {
+	name := r.name
+	if r.base != "" {
+		f.shift(r.base)
+		err := os.Rename(name, backup(r.base, 1))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name = backup(r.base, 1)
+	}
+	if f.compress && name != "" {
+		gzipFile(name)
+	}
+	return nil
+}
+
+// shift renames the backups of base up by one, oldest first, and removes the
+// ones that would exceed the limit.
+func (f *FileWriter) shift(base string) {
+	n := 1
+	for {
+		if _, ok := existing(base, n); !ok {
+			break
+		}
+		n++
+	}
+	for i := n - 1; i >= 1; i-- {
+		name, _ := existing(base, i)
+		if f.maxBackups > 0 && i >= f.maxBackups {
+			os.Remove(name)
+			continue
+		}
+		from, to := backup(base, i), backup(base, i+1)
+		os.Rename(name, to+strings.TrimPrefix(name, from))
+	}
+}
+
+// rotate moves the current file aside and opens a new one.  The file becomes
+// backup 1 once the existing backups are shifted, see retire.  Must be called
+// with mu held.
+func (f *FileWriter) rotate() error {
+	if f.f != nil {
+		f.f.Close()
+		f.f = nil
+	}
+
+	r := retired{name: f.name, base: f.name}
+	if f.compress {
+		// Move the file out of the way so that the new file can be
+		// opened before the worker gets to it.
+		f.seq++
+		r.name = fmt.Sprintf("%v.rotated%v%v", f.name, f.seq, tmpSuffix)
+		err := os.Rename(f.name, r.name)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err != nil {
+			return f.open(f.now())
+		}
+	}
+	if err := f.retire(r); err != nil {
+		return err
+	}
+	return f.open(f.now())
+}
+
+// prune removes the files of old periods beyond the backup limit.  current
+// and the files that are still being rotated or compressed are skipped.
+func (f *FileWriter) prune(current string) {
+	if f.maxBackups <= 0 {
+		return
+	}
+	files, err := filepath.Glob(glob(f.path))
+	if err != nil {
+		return
+	}
+	gz, _ := filepath.Glob(glob(f.path) + ".gz")
+	files = append(files, gz...)
+	type old struct {
+		name string
+		t    time.Time
+	}
+	var olds []old
+	seen := make(map[string]bool)
+	for _, name := range files {
+		// Without a suffix in the path the first pattern matches the
+		// compressed and temporary files as well.
+		if seen[name] || name == current ||
+			strings.HasSuffix(name, tmpSuffix) {
+			continue
+		}
+		seen[name] = true
+		fi, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		olds = append(olds, old{name: name, t: fi.ModTime()})
+	}
+	sort.Slice(olds, func(i, j int) bool {
+		return olds[i].t.After(olds[j].t)
+	})
+	for i := f.maxBackups; i < len(olds); i++ {
+		os.Remove(olds[i].name)
+	}
+}
+
+// Rotate rotates the file now.
+func (f *FileWriter) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+// Write appends p to the file.  It first moves on to a new file when a new
+// period started or when p would make the file grow beyond the maximum size.
+// p is never split across files.  It fails with os.ErrClosed after Close.
+func (f *FileWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.period != PeriodNone {
+		if t := f.now(); f.periodKey(t) != f.key {
+			prev := f.name
+			if f.f != nil {
+				f.f.Close()
+				f.f = nil
+			}
+			if err := f.open(t); err != nil {
+				return 0, err
+			}
+			var r retired
+			if prev != f.name {
+				r.name = prev
+			}
+			f.retire(r)
+		}
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	if f.f == nil {
+		if err := f.open(f.now()); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.f.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Sync commits the file to stable storage.
+func (f *FileWriter) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.f == nil {
+		return nil
+	}
+	return f.f.Sync()
+}
+
+// Close closes the file and waits for the background rotations to finish.
+func (f *FileWriter) Close() error {
+	f.mu.Lock()
+	f.closed = true
+	var err error
+	if f.f != nil {
+		err = f.f.Close()
+		f.f = nil
+	}
+	f.mu.Unlock()
+
+	f.wg.Wait()
+	return err
+}
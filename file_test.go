@@ -0,0 +1,382 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dirFiles returns the names of the files in dir, sorted.
+func dirFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fileText returns the content of the file name in dir.
+func fileText(t *testing.T, dir, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestFileWriterMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFileWriter(filepath.Join(dir, "app.log"), MaxSize(10),
+		MaxBackups(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 8; i++ {
+		line := strings.Repeat(string(rune('0'+i)), 4) + "\n"
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"app.log":   "6666\n7777\n",
+		"app.log.1": "4444\n5555\n",
+		"app.log.2": "2222\n3333\n",
+	}
+	if got := dirFiles(t, dir); len(got) != len(want) {
+		t.Fatalf("got files %q", got)
+	}
+	for name, text := range want {
+		if got := fileText(t, dir, name); got != text {
+			t.Fatalf("%v: got %q, want %q", name, got, text)
+		}
+	}
+}
+
+func TestFileWriterNoSplit(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFileWriter(filepath.Join(dir, "app.log"), MaxSize(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, s := range []string{"long line\n", "x\n"} {
+		if _, err := f.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := fileText(t, dir, "app.log.1"); got != "long line\n" {
+		t.Fatalf("got %q", got)
+	}
+	if got := fileText(t, dir, "app.log"); got != "x\n" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestFileWriterAppends(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(name, []byte("12345678\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFileWriter(name, MaxSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.Write([]byte("ab\n"))
+	if got := fileText(t, dir, "app.log.1"); got != "12345678\n" {
+		t.Fatalf("existing size not counted: %q", got)
+	}
+}
+
+func TestFileWriterClosed(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	f, err := NewFileWriter(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("second close: %v", err)
+	}
+	os.Remove(name)
+
+	if _, err := f.Write([]byte("x\n")); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("write: %v", err)
+	}
+	if err := f.Rotate(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("rotate: %v", err)
+	}
+	if got := dirFiles(t, dir); !reflect.DeepEqual(got, []string(nil)) {
+		t.Fatalf("file reopened: %q", got)
+	}
+}
+
+func TestStrftime(t *testing.T) {
+	tm := time.Date(2024, 3, 9, 7, 5, 2, 0, time.UTC)
+	tests := []struct {
+		tmpl string
+		want string
+		glob string
+	}{
+		{"app.log", "app.log", "app.log"},
+		{"app-%Y%m%d.log", "app-20240309.log", "app-***.log"},
+		{"%y %j %H:%M:%S", "24 069 07:05:02", "* * *:*:*"},
+		{"100%% %q%", "100% %q%", "100% %q%"},
+		{"a*[%d]", "a*[09]", `a\*\[*]`},
+	}
+	for _, tt := range tests {
+		if got := strftime(tt.tmpl, tm); got != tt.want {
+			t.Errorf("strftime(%q) = %q, want %q", tt.tmpl, got,
+				tt.want)
+		}
+		if got := glob(tt.tmpl); got != tt.glob {
+			t.Errorf("glob(%q) = %q, want %q", tt.tmpl, got,
+				tt.glob)
+		}
+	}
+}
+
+// fileClock is a FileClock that is moved by the test.
+type fileClock struct {
+	t time.Time
+}
+
+func (c *fileClock) now() time.Time {
+	return c.t
+}
+
+func TestFileWriterHourly(t *testing.T) {
+	dir := t.TempDir()
+	c := &fileClock{time.Date(2024, 3, 9, 10, 0, 0, 0, time.UTC)}
+	f, err := NewFileWriter(filepath.Join(dir, "app-%Y%m%d%H.log"),
+		Rotate(PeriodHourly), FileClock(c.now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []time.Duration{0, 59 * time.Minute, time.Minute,
+		time.Hour} {
+		c.t = c.t.Add(step)
+		f.Write([]byte(c.t.Format("15:04") + "\n"))
+	}
+	f.Close()
+
+	want := map[string]string{
+		"app-2024030910.log": "10:00\n10:59\n",
+		"app-2024030911.log": "11:00\n",
+		"app-2024030912.log": "12:00\n",
+	}
+	if got := dirFiles(t, dir); len(got) != len(want) {
+		t.Fatalf("got files %q", got)
+	}
+	for name, text := range want {
+		if got := fileText(t, dir, name); got != text {
+			t.Fatalf("%v: got %q, want %q", name, got, text)
+		}
+	}
+}
+
+func TestFileWriterDailyDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	dir := t.TempDir()
+	// March 10 2024 only has 23 hours in New York.
+	c := &fileClock{time.Date(2024, 3, 10, 0, 30, 0, 0, loc)}
+	f, err := NewFileWriter(filepath.Join(dir, "app-%Y%m%d.log"),
+		Rotate(PeriodDaily), FileClock(c.now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tm := range []time.Time{
+		time.Date(2024, 3, 10, 0, 30, 0, 0, loc),
+		time.Date(2024, 3, 10, 23, 59, 0, 0, loc),
+		time.Date(2024, 3, 11, 0, 0, 0, 0, loc),
+	} {
+		c.t = tm
+		f.Write([]byte(tm.Format("15:04") + "\n"))
+	}
+	f.Close()
+
+	got := fileText(t, dir, "app-20240310.log")
+	if got != "00:30\n23:59\n" {
+		t.Fatalf("got %q", got)
+	}
+	if got := fileText(t, dir, "app-20240311.log"); got != "00:00\n" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+// writeDays writes a line on each of days with f, dating the previous file
+// back so that pruning sees the files in order.
+func writeDays(t *testing.T, dir string, c *fileClock, f *FileWriter,
+	days int) {
+	t.Helper()
+	for i := 0; i < days; i++ {
+		if i != 0 {
+			prev := filepath.Join(dir,
+				"app-"+c.t.Format("20060102"))
+			os.Chtimes(prev, c.t, c.t)
+			c.t = c.t.AddDate(0, 0, 1)
+		}
+		if _, err := f.Write([]byte("x\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFileWriterPrune(t *testing.T) {
+	dir := t.TempDir()
+	c := &fileClock{time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	f, err := NewFileWriter(filepath.Join(dir, "app-%Y%m%d"),
+		Rotate(PeriodDaily), MaxBackups(2), FileClock(c.now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeDays(t, dir, c, f, 5)
+	f.Close()
+
+	want := []string{"app-20240303", "app-20240304", "app-20240305"}
+	if got := dirFiles(t, dir); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestFileWriterPruneCompressed(t *testing.T) {
+	dir := t.TempDir()
+	// A compression that is still running must not be removed or take
+	// the place of a finished backup.
+	tmp := filepath.Join(dir, "app-20240101.gz.tmp")
+	if err := os.WriteFile(tmp, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(tmp, future, future)
+
+	c := &fileClock{time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	f, err := NewFileWriter(filepath.Join(dir, "app-%Y%m%d"),
+		Rotate(PeriodDaily), MaxBackups(1), Compress(),
+		FileClock(c.now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeDays(t, dir, c, f, 3)
+	f.Close()
+
+	want := []string{"app-20240101.gz.tmp", "app-20240302.gz",
+		"app-20240303"}
+	if got := dirFiles(t, dir); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+// gunzipText returns the decompressed content of the file name in dir.
+func gunzipText(t *testing.T, dir, name string) string {
+	t.Helper()
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestFileWriterCompress(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFileWriter(filepath.Join(dir, "app.log"), MaxSize(4),
+		Compress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"one\n", "two\n", "six\n"} {
+		if _, err := f.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	want := []string{"app.log", "app.log.1.gz", "app.log.2.gz"}
+	if got := dirFiles(t, dir); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got := gunzipText(t, dir, "app.log.1.gz"); got != "two\n" {
+		t.Fatalf("backup 1: %q", got)
+	}
+	if got := gunzipText(t, dir, "app.log.2.gz"); got != "one\n" {
+		t.Fatalf("backup 2: %q", got)
+	}
+}
+
+func TestFileWriterCompressFails(t *testing.T) {
+	dir := t.TempDir()
+	// A directory in the way of the temporary file makes gzip fail.
+	err := os.Mkdir(filepath.Join(dir, "app.log.1.gz"+tmpSuffix), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFileWriter(filepath.Join(dir, "app.log"), Compress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("kept\n"))
+	if err := f.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	want := []string{"app.log", "app.log.1", "app.log.1.gz" + tmpSuffix}
+	if got := dirFiles(t, dir); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got := fileText(t, dir, "app.log.1"); got != "kept\n" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import "regexp"
+
+// SetFilter makes only the debug and trace lines that match re print, grep
+// style.  Lines of the other levels always print.  A nil re removes the
+// filter.  Filters are matched against the rendered line and do not affect
+// the ring buffer.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) SetFilter(re *regexp.Regexp) {
+	d.mu.Lock()
+	d.filter = re
+	d.mu.Unlock()
+}
+
+// SetDropFilter drops the debug and trace lines that match re, like grep -v.
+// It is applied after the filter set with SetFilter.  A nil re removes the
+// drop filter.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) SetDropFilter(re *regexp.Regexp) {
+	d.mu.Lock()
+	d.dropFilter = re
+	d.mu.Unlock()
+}
+
+// filtered reports whether the line in buf for e is removed by the filters.
+// Must be called with mu held.
+func (d *DbgLogger) filtered(e *Entry) bool {
+	if e.Level > LevelDebug {
+		return false
+	}
+	if d.filter != nil && !d.filter.Match(d.buf) {
+		return true
+	}
+	return d.dropFilter != nil && d.dropFilter.Match(d.buf)
+}
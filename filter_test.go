@@ -0,0 +1,100 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestSetFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		drop   string
+		clear  bool
+		want   []string
+	}{
+		{
+			name: "none",
+			want: []string{
+				"net dial",
+				"net read",
+				"[TRACE] disk read",
+				"[INFO] disk read",
+			},
+		},
+		{
+			name:   "include",
+			filter: "net",
+			want: []string{
+				"net dial",
+				"net read",
+				"[INFO] disk read",
+			},
+		},
+		{
+			name: "drop",
+			drop: "read",
+			want: []string{"net dial", "[INFO] disk read"},
+		},
+		{
+			name:   "both",
+			filter: "net",
+			drop:   "read",
+			want:   []string{"net dial", "[INFO] disk read"},
+		},
+		{
+			name:   "cleared",
+			filter: "net",
+			drop:   "read",
+			clear:  true,
+			want: []string{
+				"net dial",
+				"net read",
+				"[TRACE] disk read",
+				"[INFO] disk read",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 1)
+			d.SetLevel(LevelTrace)
+			if tt.filter != "" {
+				d.SetFilter(regexp.MustCompile(tt.filter))
+			}
+			if tt.drop != "" {
+				d.SetDropFilter(regexp.MustCompile(tt.drop))
+			}
+			if tt.clear {
+				d.SetFilter(nil)
+				d.SetDropFilter(nil)
+			}
+			d.Debugf("net dial")
+			d.DebugfM(1, "net read")
+			d.Tracef("disk read")
+			d.Infof("disk read")
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
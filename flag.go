@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import "strings"
+
+// MaskFlag is a flag.Value that sets the mask of a DbgLogger, i.e.
+//
+//	flag.Var(d.MaskFlag(), "debug", d.MaskFlag().Usage())
+//
+// accepts -debug net,db and -debug 0xff.  See ParseMask for the syntax.  A
+// non-empty mask also enables debug, so the flag alone turns on the output.
+type MaskFlag struct {
+	d *DbgLogger
+}
+
+// MaskFlag returns a flag.Value that sets the mask of d.
+func (d *DbgLogger) MaskFlag() *MaskFlag {
+	return &MaskFlag{d: d}
+}
+
+// String returns the mask as a list of names or, when it contains unnamed
+// bits, as a number.
+func (f *MaskFlag) String() string {
+	if f == nil || f.d == nil {
+		return ""
+	}
+	mask := f.d.GetMask()
+	names := f.d.MaskNames()
+	var named uint64
+	for _, name := range names {
+		bit, _ := f.d.BitByName(name)
+		named |= bit
+	}
+	if named != mask {
+		return maskString(mask)
+	}
+	return strings.Join(names, ",")
+}
+
+// Set sets the mask and enables debug when the mask is not empty.  An empty
+// mask does not disable debug.
+func (f *MaskFlag) Set(s string) error {
+	mask, err := f.d.ParseMask(s)
+	if err != nil {
+		return err
+	}
+	f.d.SetMask(mask)
+	if mask != 0 {
+		f.d.Enable()
+	}
+	return nil
+}
+
+// Usage returns a help string that lists the registered names and groups.
+// Register the names and define the groups before calling it.
+func (f *MaskFlag) Usage() string {
+	names := append(f.d.BitNames(), f.d.GroupNames()...)
+	if len(names) == 0 {
+		return "debug mask, a comma separated list of numbers or all"
+	}
+	return "debug categories, a comma separated list of " +
+		strings.Join(names, ", ") + ", numbers or all"
+}
@@ -0,0 +1,130 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestMaskFlag(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		mask    uint64
+		str     string
+		enabled bool
+		err     bool
+	}{
+		{name: "unset", str: ""},
+		{
+			name:    "names",
+			args:    []string{"-debug", "db,net"},
+			mask:    0x3,
+			str:     "net,db",
+			enabled: true,
+		},
+		{
+			name:    "group",
+			args:    []string{"-debug=storage"},
+			mask:    0x6,
+			str:     "db,disk",
+			enabled: true,
+		},
+		{
+			name:    "number",
+			args:    []string{"-debug", "0x1"},
+			mask:    0x1,
+			str:     "net",
+			enabled: true,
+		},
+		{
+			name:    "unnamed bits",
+			args:    []string{"-debug", "0xff"},
+			mask:    0xff,
+			str:     "0xff",
+			enabled: true,
+		},
+		{name: "empty", args: []string{"-debug", ""}, str: ""},
+		{name: "unknown", args: []string{"-debug", "rpc"}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewNop()
+			d.RegisterBit("net")
+			db := d.RegisterBit("db")
+			disk := d.RegisterBit("disk")
+			err := d.DefineGroup("storage", db|disk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			fs.Var(d.MaskFlag(), "debug", d.MaskFlag().Usage())
+			err = fs.Parse(tt.args)
+			if tt.err {
+				if err == nil {
+					t.Fatal("no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := d.GetMask(); got != tt.mask {
+				t.Fatalf("got %#x, want %#x", got, tt.mask)
+			}
+			if got := d.Enabled(); got != tt.enabled {
+				t.Fatalf("enabled %v, want %v", got, tt.enabled)
+			}
+			f := fs.Lookup("debug")
+			if got := f.Value.String(); got != tt.str {
+				t.Fatalf("string %q, want %q", got, tt.str)
+			}
+
+			// The string parses back to the same mask.
+			d.SetMask(0)
+			if err := f.Value.Set(tt.str); err != nil {
+				t.Fatal(err)
+			}
+			if got := d.GetMask(); got != tt.mask {
+				t.Fatalf("round trip: got %#x, want %#x", got,
+					tt.mask)
+			}
+		})
+	}
+}
+
+func TestMaskFlagUsage(t *testing.T) {
+	d := NewNop()
+	f := d.MaskFlag()
+	want := "debug mask, a comma separated list of numbers or all"
+	if got := f.Usage(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	d.RegisterBit("net")
+	d.RegisterBit("db")
+	d.DefineGroup("storage", 0x2)
+	want = "debug categories, a comma separated list of net, db, " +
+		"storage, numbers or all"
+	if got := f.Usage(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
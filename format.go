@@ -0,0 +1,211 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format selects the encoding of the lines printed by the Debug functions.
+type Format int
+
+// Output formats.
+const (
+	FormatText   Format = iota // log.Logger layout, the default
+	FormatJSON                 // one JSON object per line
+	FormatLogfmt               // logfmt key=value pairs
+)
+
+var formatNames = []string{
+	FormatText:   "text",
+	FormatJSON:   "json",
+	FormatLogfmt: "logfmt",
+}
+
+// String returns the lower case name of the format.
+func (f Format) String() string {
+	if f < 0 || int(f) >= len(formatNames) {
+		return "format(" + strconv.Itoa(int(f)) + ")"
+	}
+	return formatNames[f]
+}
+
+// ParseFormat returns the format named s, case is ignored.
+func ParseFormat(s string) (Format, error) {
+	for f, name := range formatNames {
+		if strings.EqualFold(s, name) {
+			return Format(f), nil
+		}
+	}
+	return 0, fmt.Errorf("dbglog: unknown format %q", s)
+}
+
+// SetFormat sets the encoding of printed lines.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) SetFormat(f Format) {
+	d.mu.Lock()
+	d.format = f
+	d.mu.Unlock()
+}
+
+// renderTime returns the time of a message for the structured formats.  It
+// uses the layout set with SetTimeFormat or RFC3339 with nanoseconds.  Must be
+// called with mu held.
+func (d *DbgLogger) renderTime(t time.Time, flag int) string {
+	if flag&log.LUTC != 0 {
+		t = t.UTC()
+	}
+	layout := d.timeFormat
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	return t.Format(layout)
+}
+
+// caller returns file:line of e, shortened as requested by flag, or "".
+func caller(e *Entry, flag int) string {
+	if e.File == "" {
+		return ""
+	}
+	file := e.File
+	if flag&log.Lshortfile != 0 {
+		if i := strings.LastIndexByte(file, '/'); i >= 0 {
+			file = file[i+1:]
+		}
+	}
+	return file + ":" + itoa10(e.Line)
+}
+
+// itoa10 returns the decimal representation of a non-negative i.
+func itoa10(i int) string {
+	var b []byte
+	itoa(&b, i, -1)
+	return string(b)
+}
+
+// jsonValue returns v in a form that encodes well as JSON.  Errors are
+// rendered as their message and values that can not be encoded as their
+// fmt representation.
+func jsonValue(v interface{}) []byte {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmtValue(v))
+	}
+	return b
+}
+
+// appendJSON appends "key":value to buf, comma separated from what is already
+// in the object.
+func appendJSON(buf []byte, key string, value []byte) []byte {
+	if buf[len(buf)-1] != '{' {
+		buf = append(buf, ',')
+	}
+	k, _ := json.Marshal(key)
+	buf = append(buf, k...)
+	buf = append(buf, ':')
+	return append(buf, value...)
+}
+
+// formatJSON appends e to buf as a single line JSON object with the time,
+// prefix, process and goroutine ids, level, bit, caller, message and fields
+// of the entry.  Must be called with mu held.
+func (d *DbgLogger) formatJSON(e *Entry, flag int) {
+	d.buf = append(d.buf, '{')
+	d.buf = appendJSON(d.buf, "time", jsonValue(d.renderTime(e.Time, flag)))
+	if e.Prefix != "" {
+		d.buf = appendJSON(d.buf, "prefix", jsonValue(e.Prefix))
+	}
+	if flag&Lpid != 0 {
+		d.buf = appendJSON(d.buf, "pid", jsonValue(pid))
+	}
+	if flag&Lgoroutine != 0 {
+		d.buf = appendJSON(d.buf, "goroutine", jsonValue(e.Goroutine))
+	}
+	d.buf = appendJSON(d.buf, "level", jsonValue(e.Level.String()))
+	if e.Bit != 0 {
+		d.buf = appendJSON(d.buf, "bit", jsonValue(e.Bit))
+		if d.bitTag != BitTagNone {
+			d.buf = appendJSON(d.buf, "bit_name",
+				jsonValue(d.bitLabel(e.Bit)))
+		}
+	}
+	if c := caller(e, flag); c != "" {
+		d.buf = appendJSON(d.buf, "caller", jsonValue(c))
+	}
+	d.buf = appendJSON(d.buf, "message",
+		jsonValue(strings.TrimSuffix(e.Message, "\n")))
+	if len(e.Fields) != 0 {
+		d.buf = appendJSON(d.buf, "fields", []byte{'{'})
+		for _, f := range e.Fields {
+			d.buf = appendJSON(d.buf, f.Key, jsonValue(f.Value))
+		}
+		d.buf = append(d.buf, '}')
+	}
+	d.buf = append(d.buf, "}\n"...)
+}
+
+// formatLogfmt appends e to buf as logfmt, i.e.
+// ts=... level=debug bit=0x4 msg="...", followed by the fields.  Must be called
+// with mu held.
+func (d *DbgLogger) formatLogfmt(e *Entry, flag int) {
+	d.buf = appendField(d.buf,
+		Field{Key: "ts", Value: d.renderTime(e.Time, flag)})
+	if e.Prefix != "" {
+		d.buf = append(d.buf, ' ')
+		d.buf = appendField(d.buf,
+			Field{Key: "prefix", Value: e.Prefix})
+	}
+	if flag&Lpid != 0 {
+		d.buf = append(d.buf, " pid="...)
+		d.buf = strconv.AppendInt(d.buf, int64(pid), 10)
+	}
+	if flag&Lgoroutine != 0 {
+		d.buf = append(d.buf, " goroutine="...)
+		d.buf = strconv.AppendUint(d.buf, e.Goroutine, 10)
+	}
+	d.buf = append(d.buf, ' ')
+	d.buf = appendField(d.buf, Field{Key: "level", Value: e.Level})
+	if e.Bit != 0 {
+		d.buf = append(d.buf, " bit=0x"...)
+		d.buf = strconv.AppendUint(d.buf, e.Bit, 16)
+		if d.bitTag != BitTagNone {
+			d.buf = append(d.buf, ' ')
+			d.buf = appendField(d.buf, Field{Key: "bit_name",
+				Value: d.bitLabel(e.Bit)})
+		}
+	}
+	if c := caller(e, flag); c != "" {
+		d.buf = append(d.buf, ' ')
+		d.buf = appendField(d.buf, Field{Key: "caller", Value: c})
+	}
+	d.buf = append(d.buf, ' ')
+	d.buf = appendField(d.buf,
+		Field{Key: "msg", Value: strings.TrimSuffix(e.Message, "\n")})
+	for _, f := range e.Fields {
+		d.buf = append(d.buf, ' ')
+		d.buf = appendField(d.buf, f)
+	}
+	d.buf = append(d.buf, '\n')
+}
@@ -0,0 +1,146 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// formatLogger returns a logger with mask that writes in format f with a
+// pinned clock.
+func formatLogger(f Format, prefix string, mask uint64) (*DbgLogger,
+	interface{ String() string }) {
+	d, b := newTestLogger(prefix, 0, mask)
+	d.SetFormat(f)
+	d.SetTimeFunc(func() time.Time {
+		return time.Date(2024, 2, 29, 13, 4, 5, 0, time.UTC)
+	})
+	return d, b
+}
+
+// jsonTime starts the lines of formatLogger in FormatJSON.
+const jsonTime = `{"time":"2024-02-29T13:04:05Z",`
+
+func TestFormatJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		print  func(d *DbgLogger)
+		want   string
+	}{
+		{
+			name:  "plain",
+			print: func(d *DbgLogger) { d.Debugf("msg\n") },
+			want:  jsonTime + `"level":"debug","message":"msg"}`,
+		},
+		{
+			name:   "prefix and bit",
+			prefix: "app",
+			print:  func(d *DbgLogger) { d.InfofM(4, "msg") },
+			want: jsonTime + `"prefix":"app","level":"info",` +
+				`"bit":4,"message":"msg"}`,
+		},
+		{
+			name: "escaping",
+			print: func(d *DbgLogger) {
+				d.Debugf("say \"hi\"\t<b>\\ \x01 ü")
+			},
+			want: jsonTime + `"level":"debug","message":"say ` +
+				`\"hi\"\t\u003cb\u003e\\ \u0001 ü"}`,
+		},
+		{
+			name: "fields",
+			print: func(d *DbgLogger) {
+				d.Debugw("msg", "err", errors.New("boom"),
+					"n", 3, "ok", true, "f", 1.5,
+					"nil", nil,
+					"s", struct{ A int }{1},
+					"nan", math.NaN(), "quote\"key", "v")
+			},
+			want: jsonTime + `"level":"debug","message":"msg",` +
+				`"fields":{"err":"boom","n":3,"ok":true,` +
+				`"f":1.5,"nil":null,"s":{"A":1},"nan":"NaN",` +
+				`"quote\"key":"v"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := formatLogger(FormatJSON, tt.prefix, 4)
+			tt.print(d)
+			if got := b.String(); got != tt.want+"\n" {
+				t.Fatalf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatLogfmt(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		print  func(d *DbgLogger)
+		want   string
+	}{
+		{
+			name:  "plain",
+			print: func(d *DbgLogger) { d.Debugf("msg\n") },
+			want:  "ts=2024-02-29T13:04:05Z level=debug msg=msg",
+		},
+		{
+			name:   "prefix and bit",
+			prefix: "app",
+			print:  func(d *DbgLogger) { d.WarnfM(4, "msg") },
+			want: "ts=2024-02-29T13:04:05Z prefix=app level=warn " +
+				"bit=0x4 msg=msg",
+		},
+		{
+			name:   "quoted prefix",
+			prefix: "my app",
+			print:  func(d *DbgLogger) { d.Debugf("msg") },
+			want: `ts=2024-02-29T13:04:05Z prefix="my app" ` +
+				`level=debug msg=msg`,
+		},
+		{
+			name: "quoting",
+			print: func(d *DbgLogger) {
+				d.Debugw("two words", "empty", "", "eq", "a=b",
+					"quote", `say "hi"`, "nl", "a\nb",
+					"ctl", "\x01", "utf8", "ü",
+					"my key", 1,
+					"err", errors.New("no such file"))
+			},
+			want: `ts=2024-02-29T13:04:05Z level=debug ` +
+				`msg="two words" empty="" eq="a=b" ` +
+				`quote="say \"hi\"" nl="a\nb" ctl="\x01" ` +
+				`utf8=ü "my key"=1 err="no such file"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := formatLogger(FormatLogfmt, tt.prefix, 4)
+			tt.print(d)
+			if got := b.String(); got != tt.want+"\n" {
+				t.Fatalf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
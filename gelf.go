@@ -0,0 +1,271 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// GELF chunking, see the GELF specification.
+const (
+	gelfChunkSize = 1420 // fits an Ethernet MTU
+	gelfHeader    = 12   // magic, message id, sequence number and count
+	gelfMaxChunks = 128
+)
+
+// GELFOption configures a GELF backend.
+type GELFOption func(*GELF)
+
+// GELFChunkSize sets the maximum size of the UDP datagrams, the default of
+// 1420 bytes fits an Ethernet MTU.  Larger messages are sent in chunks.
+func GELFChunkSize(n int) GELFOption {
+	return func(g *GELF) {
+		g.chunkSize = n
+	}
+}
+
+// GELFHost sets the host field, the default is the host name.
+func GELFHost(host string) GELFOption {
+	return func(g *GELF) {
+		g.host = host
+	}
+}
+
+// GELFBitNames sets the function that names the bits of messages, usually
+// the BitName method of the logger, i.e. GELFBitNames(d.BitName).  The name
+// is sent as _bit_name.
+func GELFBitNames(f func(bit uint64) string) GELFOption {
+	return func(g *GELF) {
+		g.names = f
+	}
+}
+
+// GELF is a Backend that sends messages to Graylog in the Graylog Extended Log
+// Format.  The level is sent as the syslog severity and the prefix, bit,
+// caller and fields of an entry are sent as additional fields, i.e. _prefix,
+// _bit, _file and _field_user for the field user.  Over UDP large messages are
+// chunked, over TCP messages are null byte delimited.
+type GELF struct {
+	network   string
+	addr      string
+	host      string
+	chunkSize int
+	names     func(uint64) string
+
+	mu   sync.Mutex // protects conn
+	conn net.Conn
+}
+
+var _ Backend = (*GELF)(nil)
+
+// NewGELF connects to the GELF input at addr on network, "udp" or "tcp".
+func NewGELF(network, addr string, opts ...GELFOption) (*GELF, error) {
+	hostname, _ := os.Hostname()
+	g := &GELF{
+		network:   network,
+		addr:      addr,
+		host:      hostname,
+		chunkSize: gelfChunkSize,
+	}
+	for _, o := range opts {
+		o(g)
+	}
+	if g.chunkSize <= gelfHeader {
+		return nil, fmt.Errorf("dbglog: GELF chunk size %v too small",
+			g.chunkSize)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.connect(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// connect (re)connects to the input.  Must be called with mu held.
+func (g *GELF) connect() error {
+	if g.conn != nil {
+		g.conn.Close()
+		g.conn = nil
+	}
+	c, err := net.Dial(g.network, g.addr)
+	if err != nil {
+		return err
+	}
+	g.conn = c
+	return nil
+}
+
+// gelfKey returns key as an additional field name.  User fields are prefixed
+// with _field_ so that they cannot clash with the reserved _id or the fields
+// the backend sets, i.e. prefix becomes _field_prefix instead of overriding
+// _prefix.  Characters that GELF does not allow are replaced with underscores.
+func gelfKey(key string) string {
+	k := []byte(key)
+	for i, c := range k {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z',
+			c >= '0' && c <= '9', c == '_', c == '.', c == '-':
+		default:
+			k[i] = '_'
+		}
+	}
+	if len(k) == 0 {
+		return "_field"
+	}
+	return "_field_" + string(k)
+}
+
+// encode returns the GELF document for e.
+func (g *GELF) encode(e *Entry) ([]byte, error) {
+	msg := strings.TrimRight(e.Message, "\n")
+	m := map[string]interface{}{
+		"version":       "1.1",
+		"host":          g.host,
+		"short_message": msg,
+		"level":         severity(e.Level),
+		"_level_name":   e.Level.String(),
+	}
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		m["short_message"] = msg[:i]
+		m["full_message"] = msg
+	}
+	if !e.Time.IsZero() {
+		m["timestamp"] = float64(e.Time.UnixMilli()) / 1000
+	}
+	if e.Prefix != "" {
+		m["_prefix"] = e.Prefix
+	}
+	if e.Bit != 0 {
+		m["_bit"] = maskString(e.Bit)
+		if g.names != nil {
+			if name := g.names(e.Bit); name != "" {
+				m["_bit_name"] = name
+			}
+		}
+	}
+	if e.File != "" {
+		m["_file"] = e.File
+		m["_line"] = e.Line
+	}
+	for _, f := range e.Fields {
+		m[gelfKey(f.Key)] = json.RawMessage(jsonValue(f.Value))
+	}
+	return json.Marshal(m)
+}
+
+// send sends a GELF document, reconnecting once if the connection failed.
+func (g *GELF) send(b []byte) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.network == "udp" || strings.HasPrefix(g.network, "udp") {
+		return g.sendUDP(b)
+	}
+	b = append(b, 0)
+	if g.conn != nil {
+		if _, err := g.conn.Write(b); err == nil {
+			return nil
+		}
+	}
+	if err := g.connect(); err != nil {
+		return err
+	}
+	_, err := g.conn.Write(b)
+	return err
+}
+
+// sendUDP sends b in one datagram or in chunks, redialing if the backend was
+// closed.  Must be called with mu held.
+func (g *GELF) sendUDP(b []byte) error {
+	if g.conn == nil {
+		if err := g.connect(); err != nil {
+			return err
+		}
+	}
+	if len(b) <= g.chunkSize {
+		_, err := g.conn.Write(b)
+		return err
+	}
+	size := g.chunkSize - gelfHeader
+	n := (len(b) + size - 1) / size
+	if n > gelfMaxChunks {
+		return fmt.Errorf("dbglog: GELF message of %v bytes too large",
+			len(b))
+	}
+	var id [8]byte
+	for i, r := 0, rand.Uint64(); i < len(id); i, r = i+1, r>>8 {
+		id[i] = byte(r)
+	}
+	var chunk bytes.Buffer
+	for i := 0; i < n; i++ {
+		chunk.Reset()
+		chunk.Write([]byte{0x1e, 0x0f})
+		chunk.Write(id[:])
+		chunk.Write([]byte{byte(i), byte(n)})
+		end := (i + 1) * size
+		if end > len(b) {
+			end = len(b)
+		}
+		chunk.Write(b[i*size : end])
+		if _, err := g.conn.Write(chunk.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write sends p as an informational message.
+func (g *GELF) Write(p []byte) (int, error) {
+	b, err := g.encode(&Entry{Level: LevelInfo, Message: string(p)})
+	if err != nil {
+		return 0, err
+	}
+	if err := g.send(b); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteEntry sends e, the rendered line is not used.
+func (g *GELF) WriteEntry(e *Entry, line []byte) error {
+	b, err := g.encode(e)
+	if err != nil {
+		return err
+	}
+	return g.send(b)
+}
+
+// Close closes the connection, a later Write reconnects.
+func (g *GELF) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.conn == nil {
+		return nil
+	}
+	err := g.conn.Close()
+	g.conn = nil
+	return err
+}
@@ -0,0 +1,130 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGELFWriteAfterClose(t *testing.T) {
+	tests := []struct {
+		name      string
+		chunkSize int
+		msg       string
+		chunked   bool
+	}{
+		{"datagram", gelfChunkSize, "hello", false},
+		{"chunked", 64, strings.Repeat("x", 200), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc, err := net.ListenUDP("udp",
+				&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pc.Close()
+
+			g, err := NewGELF("udp", pc.LocalAddr().String(),
+				GELFChunkSize(tt.chunkSize))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := g.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := g.Write([]byte(tt.msg)); err != nil {
+				t.Fatal(err)
+			}
+			defer g.Close()
+
+			buf := make([]byte, 65536)
+			pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+			n, _, err := pc.ReadFrom(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chunk := n >= 2 && buf[0] == 0x1e && buf[1] == 0x0f
+			if chunk != tt.chunked {
+				t.Fatalf("chunked %v, want %v: %q", chunk,
+					tt.chunked, buf[:n])
+			}
+			if !tt.chunked &&
+				!strings.Contains(string(buf[:n]), tt.msg) {
+				t.Fatalf("message missing: %q", buf[:n])
+			}
+		})
+	}
+}
+
+func TestGELFFields(t *testing.T) {
+	g := &GELF{host: "h"}
+	b, err := g.encode(&Entry{
+		Level:   LevelWarn,
+		Prefix:  "app",
+		Bit:     1,
+		Message: "hello",
+		File:    "main.go",
+		Line:    7,
+		Fields: []Field{
+			{Key: "id", Value: "user"},
+			{Key: "_prefix", Value: "user"},
+			{Key: "bit", Value: 2},
+			{Key: "level_name", Value: "user"},
+			{Key: "file", Value: "x.go"},
+			{Key: "_line", Value: 9},
+			{Key: "user id", Value: 42},
+			{Key: "", Value: "empty"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"version":           "1.1",
+		"host":              "h",
+		"short_message":     "hello",
+		"level":             4.0,
+		"_level_name":       "warn",
+		"_prefix":           "app",
+		"_bit":              "0x1",
+		"_file":             "main.go",
+		"_line":             7.0,
+		"_field_id":         "user",
+		"_field__prefix":    "user",
+		"_field_bit":        2.0,
+		"_field_level_name": "user",
+		"_field_file":       "x.go",
+		"_field__line":      9.0,
+		"_field_user_id":    42.0,
+		"_field":            "empty",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bytes"
+	"os"
+	"runtime"
+	"strconv"
+)
+
+// Flags that extend the log.Logger flags, i.e.
+// New(os.Stderr, "app ", log.LstdFlags|dbglog.Lpid|dbglog.Lgoroutine).  They
+// only apply to the lines rendered by dbglog, not to the log.Logger functions
+// such as Printf.
+const (
+	Lpid       = 1 << 16 // process id, i.e. [4242]
+	Lgoroutine = 1 << 17 // id of the calling goroutine, i.e. g17
+)
+
+// pid is the process id printed for Lpid.
+var pid = os.Getpid()
+
+// goroutineID is goid, replaced by tests.  It is only called when Lgoroutine
+// is set because parsing the stack trace is not cheap.
+var goroutineID = goid
+
+// goid returns the id of the calling goroutine.  The runtime does not export
+// it so it is parsed from the header of the goroutine's stack trace.
+func goid() uint64 {
+	var b [64]byte
+	s := b[:runtime.Stack(b[:], false)]
+	s = bytes.TrimPrefix(s, []byte("goroutine "))
+	if i := bytes.IndexByte(s, ' '); i >= 0 {
+		s = s[:i]
+	}
+	id, _ := strconv.ParseUint(string(s), 10, 64)
+	return id
+}
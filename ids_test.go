@@ -0,0 +1,111 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIDFlags(t *testing.T) {
+	p := strconv.Itoa(pid)
+	tests := []struct {
+		name   string
+		flag   int
+		format Format
+		want   string // with G for the goroutine id
+		calls  int    // of goroutineID
+	}{
+		{
+			name: "none",
+			flag: log.Ltime,
+			want: "p 13:04:05 msg\n",
+		},
+		{
+			name: "pid",
+			flag: log.Ltime | Lpid,
+			want: "p 13:04:05 [" + p + "] msg\n",
+		},
+		{
+			name:  "goroutine",
+			flag:  log.Ltime | Lgoroutine,
+			want:  "p 13:04:05 gG msg\n",
+			calls: 1,
+		},
+		{
+			name:  "both",
+			flag:  log.Ltime | Lpid | Lgoroutine,
+			want:  "p 13:04:05 [" + p + "] gG msg\n",
+			calls: 1,
+		},
+		{
+			name:   "json",
+			flag:   Lpid | Lgoroutine,
+			format: FormatJSON,
+			want: `{"time":"2024-02-29T13:04:05Z","prefix":"p",` +
+				`"pid":` + p + `,"goroutine":G,` +
+				`"level":"debug","message":"msg"}` + "\n",
+			calls: 1,
+		},
+		{
+			name:   "logfmt",
+			flag:   Lpid | Lgoroutine,
+			format: FormatLogfmt,
+			want: "ts=2024-02-29T13:04:05Z prefix=p pid=" + p +
+				" goroutine=G level=debug msg=msg\n",
+			calls: 1,
+		},
+		{
+			name:   "logfmt without ids",
+			format: FormatLogfmt,
+			want: "ts=2024-02-29T13:04:05Z prefix=p " +
+				"level=debug msg=msg\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			goroutineID = func() uint64 {
+				calls++
+				return goid()
+			}
+			defer func() { goroutineID = goid }()
+
+			d, b := newTestLogger("p ", tt.flag, 0)
+			d.SetFormat(tt.format)
+			d.SetTimeFunc(func() time.Time {
+				return time.Date(2024, 2, 29, 13, 4, 5, 0,
+					time.UTC)
+			})
+			d.Debugf("msg")
+			g := strconv.FormatUint(goid(), 10)
+			want := strings.ReplaceAll(tt.want, "G", g)
+			if got := b.String(); got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+			if calls != tt.calls {
+				t.Fatalf("got %v goroutine id calls, want %v",
+					calls, tt.calls)
+			}
+		})
+	}
+}
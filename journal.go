@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// journalSocket is the socket of the native journald protocol.
+const journalSocket = "/run/systemd/journal/socket"
+
+// JournalOption configures a Journal backend.
+type JournalOption func(*Journal)
+
+// JournalBitNames sets the function that names the bits of messages, usually
+// the BitName method of the logger.  The name is sent as DBGLOG_BIT_NAME.
+func JournalBitNames(f func(bit uint64) string) JournalOption {
+	return func(j *Journal) {
+		j.names = f
+	}
+}
+
+// Journal is a Backend that sends messages to the systemd journal with the
+// native protocol.  Every message carries PRIORITY, SYSLOG_IDENTIFIER and,
+// when it was printed for a bit, DBGLOG_BIT so that categories can be selected
+// with journalctl DBGLOG_BIT=0x4.  The prefix, caller and fields of an entry
+// are sent as DBGLOG_PREFIX, CODE_FILE, CODE_LINE and DBGLOG_FIELD_ followed
+// by the upper cased key.  The journal adds its own time stamp so the logger
+// is best created with flags 0.
+// Messages larger than a datagram are not supported.
+type Journal struct {
+	identifier string
+	names      func(uint64) string
+
+	mu   sync.Mutex // protects conn
+	conn net.Conn
+}
+
+var _ Backend = (*Journal)(nil)
+
+// NewJournal connects to the journal.  identifier is sent as
+// SYSLOG_IDENTIFIER, the program name is used if it is empty.
+func NewJournal(identifier string, opts ...JournalOption) (*Journal, error) {
+	if identifier == "" {
+		identifier = filepath.Base(os.Args[0])
+	}
+	j := &Journal{identifier: identifier}
+	for _, o := range opts {
+		o(j)
+	}
+	c, err := net.Dial("unixgram", journalSocket)
+	if err != nil {
+		return nil, err
+	}
+	j.conn = c
+	return j, nil
+}
+
+// journalField appends a field in the native journal format.
+func journalField(b []byte, key, value string) []byte {
+	if !strings.ContainsRune(value, '\n') {
+		b = append(b, key...)
+		b = append(b, '=')
+		b = append(b, value...)
+		return append(b, '\n')
+	}
+	b = append(b, key...)
+	b = append(b, '\n')
+	for n, i := uint64(len(value)), 0; i < 8; i++ {
+		b = append(b, byte(n>>(8*i))) // little endian length
+	}
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+// journalKey returns key as a journal field name.  User fields are prefixed
+// with DBGLOG_FIELD_ so that they cannot clash with the fields journald and
+// the backend set, i.e. message becomes DBGLOG_FIELD_MESSAGE instead of
+// overriding MESSAGE.  Characters other than letters and digits are replaced
+// with underscores.
+func journalKey(key string) string {
+	k := []byte(strings.ToUpper(key))
+	for i, c := range k {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			k[i] = '_'
+		}
+	}
+	if len(k) == 0 {
+		return "DBGLOG_FIELD"
+	}
+	return "DBGLOG_FIELD_" + string(k)
+}
+
+// encode returns the journal datagram for e.
+func (j *Journal) encode(e *Entry) []byte {
+	var b []byte
+	b = journalField(b, "MESSAGE", strings.TrimRight(e.Message, "\n"))
+	b = journalField(b, "PRIORITY", strconv.Itoa(severity(e.Level)))
+	b = journalField(b, "SYSLOG_IDENTIFIER", j.identifier)
+	b = journalField(b, "DBGLOG_LEVEL", e.Level.String())
+	if e.Prefix != "" {
+		b = journalField(b, "DBGLOG_PREFIX", e.Prefix)
+	}
+	if e.Bit != 0 {
+		b = journalField(b, "DBGLOG_BIT", maskString(e.Bit))
+		if j.names != nil {
+			if name := j.names(e.Bit); name != "" {
+				b = journalField(b, "DBGLOG_BIT_NAME", name)
+			}
+		}
+	}
+	if e.File != "" {
+		b = journalField(b, "CODE_FILE", e.File)
+		b = journalField(b, "CODE_LINE", strconv.Itoa(e.Line))
+	}
+	for _, f := range e.Fields {
+		b = journalField(b, journalKey(f.Key), fmtValue(f.Value))
+	}
+	return b
+}
+
+// send sends a datagram to the journal.
+func (j *Journal) send(b []byte) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.conn == nil {
+		return net.ErrClosed
+	}
+	_, err := j.conn.Write(b)
+	return err
+}
+
+// Write sends p as an informational message.
+func (j *Journal) Write(p []byte) (int, error) {
+	if err := j.send(j.encode(&Entry{Level: LevelInfo,
+		Message: string(bytes.TrimRight(p, "\n"))})); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteEntry sends e, the rendered line is not used.
+func (j *Journal) WriteEntry(e *Entry, line []byte) error {
+	return j.send(j.encode(e))
+}
+
+// Close closes the connection to the journal.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.conn == nil {
+		return nil
+	}
+	err := j.conn.Close()
+	j.conn = nil
+	return err
+}
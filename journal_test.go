@@ -0,0 +1,64 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJournalFields(t *testing.T) {
+	j := &Journal{identifier: "test"}
+	b := j.encode(&Entry{
+		Level:   LevelWarn,
+		Message: "hello",
+		File:    "main.go",
+		Line:    7,
+		Fields: []Field{
+			{Key: "message", Value: "user"},
+			{Key: "priority", Value: 1},
+			{Key: "code_file", Value: "x.go"},
+			{Key: "user-id", Value: 42},
+			{Key: "", Value: "empty"},
+		},
+	})
+	got := map[string][]string{}
+	for _, l := range strings.Split(strings.TrimSuffix(string(b), "\n"),
+		"\n") {
+		k, v, _ := strings.Cut(l, "=")
+		got[k] = append(got[k], v)
+	}
+	want := map[string][]string{
+		"MESSAGE":                {"hello"},
+		"PRIORITY":               {"4"},
+		"SYSLOG_IDENTIFIER":      {"test"},
+		"DBGLOG_LEVEL":           {"warn"},
+		"CODE_FILE":              {"main.go"},
+		"CODE_LINE":              {"7"},
+		"DBGLOG_FIELD_MESSAGE":   {"user"},
+		"DBGLOG_FIELD_PRIORITY":  {"1"},
+		"DBGLOG_FIELD_CODE_FILE": {"x.go"},
+		"DBGLOG_FIELD_USER_ID":   {"42"},
+		"DBGLOG_FIELD":           {"empty"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
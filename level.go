@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Level is the severity of a message.  Levels are ordered, a higher level is
+// more severe.
+type Level int
+
+// Levels in increasing order of severity.  All Debug functions print at
+// LevelDebug, the other levels have their own functions, i.e. Infof.
+const (
+	LevelTrace Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+var levelNames = []string{
+	LevelTrace: "trace",
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+	LevelFatal: "fatal",
+}
+
+// String returns the lower case name of the level.
+func (l Level) String() string {
+	if l < 0 || int(l) >= len(levelNames) {
+		return "level(" + strconv.Itoa(int(l)) + ")"
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the level named s, case is ignored.
+func ParseLevel(s string) (Level, error) {
+	for l, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(l), nil
+		}
+	}
+	return 0, fmt.Errorf("dbglog: unknown level %q", s)
+}
+
+// SetLevel sets the minimum level of the messages printed by the leveled
+// functions, Tracef through Fatalf.  The default is LevelInfo.  The Debug
+// functions are controlled by Enable, Disable and the mask instead.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) SetLevel(l Level) {
+	d.level.Store(int32(l))
+}
+
+// GetLevel returns the level set with SetLevel.
+func (d *DbgLogger) GetLevel() Level {
+	return Level(d.level.Load())
+}
+
+// logf prints the message if l is at least the configured level.
+func (d *DbgLogger) logf(l Level, format string, v ...interface{}) {
+	if l < d.GetLevel() {
+		d.count(l, 0, false)
+		return
+	}
+	d.output(3, l, 0, fmt.Sprintf(format, v...))
+}
+
+// log.Printf equivalent but only prints when the level is LevelTrace.
+func (d *DbgLogger) Tracef(format string, v ...interface{}) {
+	d.logf(LevelTrace, format, v...)
+}
+
+// log.Printf equivalent but only prints when the level is LevelInfo or lower.
+func (d *DbgLogger) Infof(format string, v ...interface{}) {
+	d.logf(LevelInfo, format, v...)
+}
+
+// log.Printf equivalent but only prints when the level is LevelWarn or lower.
+func (d *DbgLogger) Warnf(format string, v ...interface{}) {
+	d.logf(LevelWarn, format, v...)
+}
+
+// log.Printf equivalent but only prints when the level is LevelError or lower.
+func (d *DbgLogger) Errorf(format string, v ...interface{}) {
+	d.logf(LevelError, format, v...)
+}
+
+// log.Fatalf equivalent, prints when the level is LevelFatal or lower and then
+// always calls os.Exit(1) after flushing buffered outputs such as an
+// AsyncWriter.
+func (d *DbgLogger) Fatalf(format string, v ...interface{}) {
+	d.logf(LevelFatal, format, v...)
+	d.exit()
+}
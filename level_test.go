@@ -0,0 +1,97 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package dbglog
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSetLevel(t *testing.T) {
+	print := func(d *DbgLogger) {
+		d.Tracef("t")
+		d.Debugf("d")
+		d.Infof("i")
+		d.Warnf("w")
+		d.Errorf("e")
+		d.Fatalf("f")
+	}
+	all := []string{"[TRACE] t", "d", "[INFO] i", "[WARN] w",
+		"[ERROR] e", "[FATAL] f"}
+	tests := []struct {
+		level Level
+		want  []string
+	}{
+		{LevelTrace, all},
+		{LevelDebug, all[1:]},
+		{LevelInfo, all[1:]},
+		{LevelWarn, append([]string{"d"}, all[3:]...)},
+		{LevelError, append([]string{"d"}, all[4:]...)},
+		{LevelFatal, []string{"d", "[FATAL] f"}},
+	}
+
+	var exits int
+	osExit = func(int) { exits++ }
+	defer func() { osExit = os.Exit }()
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			exits = 0
+			d, b := newTestLogger("", 0, 0)
+			d.SetLevel(tt.level)
+			if got := d.GetLevel(); got != tt.level {
+				t.Fatalf("GetLevel %v, want %v", got, tt.level)
+			}
+			print(d)
+			got := strings.Join(lines(b), "|")
+			if want := strings.Join(tt.want, "|"); got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+			if exits != 1 {
+				t.Fatalf("Fatalf exited %v times", exits)
+			}
+		})
+	}
+}
+
+func TestLevelDefault(t *testing.T) {
+	d, b := newTestLogger("", 0, 0)
+	if got := d.GetLevel(); got != LevelInfo {
+		t.Fatalf("default level %v", got)
+	}
+	d.Tracef("t")
+	d.Infof("i")
+	if got := b.String(); got != "[INFO] i\n" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for l := LevelTrace; l <= LevelFatal; l++ {
+		got, err := ParseLevel(strings.ToUpper(l.String()))
+		if err != nil || got != l {
+			t.Fatalf("ParseLevel(%v) = %v, %v", l, got, err)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := Level(9).String(); got != "level(9)" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,34 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+// Logger is the set of debug functions provided by DbgLogger.  Code that only
+// needs to emit debug output can accept a Logger so that a NewNop logger or a
+// test fake can be passed in instead.
+type Logger interface {
+	Debugf(format string, v ...interface{})
+	Debug(v ...interface{})
+	Debugln(v ...interface{})
+	DebugfM(bit uint64, format string, v ...interface{})
+	DebugM(bit uint64, format string, v ...interface{})
+	DebuglnM(bit uint64, format string, v ...interface{})
+	Enable()
+	Disable()
+	SetMask(mask uint64)
+}
+
+var _ Logger = (*DbgLogger)(nil)
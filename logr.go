@@ -0,0 +1,152 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+// LogSink adapts a DbgLogger to the logr.LogSink interface of
+// github.com/go-logr/logr without depending on it.  It has the LogSink method
+// set except that Init takes the call depth of logr.RuntimeInfo and
+// WithValues, WithName and WithCallDepth return a *LogSink, so it is not a
+// logr.LogSink by itself.  A few lines in the program that imports logr
+// complete the adapter, which is then also a logr.CallDepthLogSink:
+//
+//	type sink struct{ *dbglog.LogSink }
+//
+//	func (s sink) Init(ri logr.RuntimeInfo) { s.LogSink.Init(ri.CallDepth) }
+//	func (s sink) WithValues(kv ...interface{}) logr.LogSink {
+//		return sink{s.LogSink.WithValues(kv...)}
+//	}
+//	func (s sink) WithName(name string) logr.LogSink {
+//		return sink{s.LogSink.WithName(name)}
+//	}
+//	func (s sink) WithCallDepth(depth int) logr.LogSink {
+//		return sink{s.LogSink.WithCallDepth(depth)}
+//	}
+//
+//	log := logr.New(sink{dbglog.NewLogSink(d, 2, myDebugNet)})
+//
+// V(0) messages print at LevelInfo and are filtered by SetLevel.  V(n)
+// messages with n > 0 are debug messages, see NewLogSink.  Errors always print
+// at LevelError with the error in the "error" field.
+type LogSink struct {
+	d         *DbgLogger
+	verbosity int      // highest V-level that is printed
+	bits      []uint64 // bits[n-1] controls V(n)
+	name      string
+	values    []Field
+	depth     int
+}
+
+// NameKey is the field that carries the name of a LogSink, see WithName.
+const NameKey = "logger"
+
+// NewLogSink returns a LogSink that prints through d.  V-levels above
+// verbosity are never printed.  V(n) with n > 0 is controlled by bits[n-1],
+// the last bit also controls the levels beyond len(bits), just like DebugfM.
+// Without bits the V-levels above 0 are controlled by Enable and Disable.
+func NewLogSink(d *DbgLogger, verbosity int, bits ...uint64) *LogSink {
+	return &LogSink{d: d, verbosity: verbosity, bits: bits}
+}
+
+// bit returns the mask bit of V-level level.
+func (s *LogSink) bit(level int) uint64 {
+	switch {
+	case len(s.bits) == 0:
+		return 0
+	case level > len(s.bits):
+		return s.bits[len(s.bits)-1]
+	}
+	return s.bits[level-1]
+}
+
+// Init sets the number of logr frames between the caller and the LogSink.
+func (s *LogSink) Init(callDepth int) {
+	s.depth = callDepth
+}
+
+// Enabled reports whether messages of V-level level are printed.
+func (s *LogSink) Enabled(level int) bool {
+	switch {
+	case level > s.verbosity:
+		return false
+	case level <= 0:
+		return LevelInfo >= s.d.GetLevel()
+	}
+	if bit := s.bit(level); bit != 0 {
+		return s.d.DebugEnabledM(bit)
+	}
+	return s.d.DebugEnabled()
+}
+
+// fields returns the fields of s followed by keysAndValues.
+func (s *LogSink) fields(keysAndValues []interface{}) []Field {
+	fields := make([]Field, 0, 1+len(s.values)+(len(keysAndValues)+1)/2)
+	if s.name != "" {
+		fields = append(fields, Field{Key: NameKey, Value: s.name})
+	}
+	fields = append(fields, s.values...)
+	return append(fields, fieldsOf(keysAndValues)...)
+}
+
+// Info prints msg at V-level level.  logr only calls it when Enabled returns
+// true.
+func (s *LogSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	e := &Entry{Level: LevelInfo, Message: msg,
+		Fields: s.fields(keysAndValues)}
+	if level > 0 {
+		e.Level = LevelDebug
+		e.Bit = s.bit(level)
+	}
+	s.d.emit(2+s.depth, e)
+}
+
+// Error prints msg and err at LevelError unless the level is set higher.
+func (s *LogSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	if LevelError < s.d.GetLevel() {
+		return
+	}
+	fields := append(s.fields(nil), Field{Key: "error", Value: err})
+	fields = append(fields, fieldsOf(keysAndValues)...)
+	s.d.emit(2+s.depth, &Entry{Level: LevelError, Message: msg,
+		Fields: fields})
+}
+
+// WithValues returns a LogSink that adds keysAndValues to every message.
+func (s *LogSink) WithValues(keysAndValues ...interface{}) *LogSink {
+	n := *s
+	n.values = append(append([]Field(nil), s.values...),
+		fieldsOf(keysAndValues)...)
+	return &n
+}
+
+// WithName returns a LogSink with name appended to its name, separated by a
+// slash as logr does.
+func (s *LogSink) WithName(name string) *LogSink {
+	n := *s
+	if n.name != "" {
+		n.name += "/"
+	}
+	n.name += name
+	return &n
+}
+
+// WithCallDepth returns a LogSink that reports the caller depth frames
+// further up the stack, see logr.CallDepthLogSink.
+func (s *LogSink) WithCallDepth(depth int) *LogSink {
+	n := *s
+	n.depth += depth
+	return &n
+}
@@ -0,0 +1,210 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// The logr types the adapter in the LogSink documentation is written against.
+type (
+	logrRuntimeInfo struct{ CallDepth int }
+
+	logrLogSink interface {
+		Init(info logrRuntimeInfo)
+		Enabled(level int) bool
+		Info(level int, msg string, keysAndValues ...interface{})
+		Error(err error, msg string, keysAndValues ...interface{})
+		WithValues(keysAndValues ...interface{}) logrLogSink
+		WithName(name string) logrLogSink
+	}
+
+	logrCallDepthLogSink interface {
+		WithCallDepth(depth int) logrLogSink
+	}
+)
+
+// logrSink is the adapter of the LogSink documentation.
+type logrSink struct{ *LogSink }
+
+func (s logrSink) Init(ri logrRuntimeInfo) { s.LogSink.Init(ri.CallDepth) }
+func (s logrSink) WithValues(kv ...interface{}) logrLogSink {
+	return logrSink{s.LogSink.WithValues(kv...)}
+}
+func (s logrSink) WithName(name string) logrLogSink {
+	return logrSink{s.LogSink.WithName(name)}
+}
+func (s logrSink) WithCallDepth(depth int) logrLogSink {
+	return logrSink{s.LogSink.WithCallDepth(depth)}
+}
+
+var (
+	_ logrLogSink          = logrSink{}
+	_ logrCallDepthLogSink = logrSink{}
+)
+
+func TestLogSinkEnabled(t *testing.T) {
+	tests := []struct {
+		name      string
+		verbosity int
+		bits      []uint64
+		disabled  bool
+		mask      uint64
+		level     Level
+		want      []bool // Enabled of V(0) through V(3)
+	}{
+		{
+			name:      "bits",
+			verbosity: 3,
+			bits:      []uint64{2, 4},
+			mask:      2,
+			want:      []bool{true, true, false, false},
+		},
+		{
+			name:      "last bit beyond bits",
+			verbosity: 3,
+			bits:      []uint64{2, 4},
+			mask:      4,
+			want:      []bool{true, false, true, true},
+		},
+		{
+			name:      "verbosity",
+			verbosity: 1,
+			bits:      []uint64{2, 4},
+			mask:      2 | 4,
+			want:      []bool{true, true, false, false},
+		},
+		{
+			name:      "no bits",
+			verbosity: 3,
+			want:      []bool{true, true, true, true},
+		},
+		{
+			name:      "no bits disabled",
+			verbosity: 3,
+			disabled:  true,
+			want:      []bool{true, false, false, false},
+		},
+		{
+			name:      "level",
+			verbosity: 3,
+			bits:      []uint64{2},
+			mask:      2,
+			level:     LevelWarn,
+			want:      []bool{false, true, true, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := newTestLogger("", 0, tt.mask)
+			if tt.disabled {
+				d.Disable()
+			}
+			d.SetLevel(tt.level)
+			s := NewLogSink(d, tt.verbosity, tt.bits...)
+			got := make([]bool, len(tt.want))
+			for i := range got {
+				got[i] = s.Enabled(i)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogSinkInfo(t *testing.T) {
+	d, b := newTestLogger("", 0, 2|4)
+	d.SetBitTag(BitTagLine)
+	s := NewLogSink(d, 3, 2, 4)
+	s.Info(0, "zero", "k", 1)
+	s.Info(1, "one")
+	s.Info(2, "two")
+	s.Info(3, "three")
+	want := []string{
+		"[INFO] zero k=1",
+		"[0x2] one",
+		"[0x4] two",
+		"[0x4] three",
+	}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestLogSinkWith(t *testing.T) {
+	d, b := newTestLogger("", 0, 2)
+	s := NewLogSink(d, 1, 2)
+	a := s.WithName("a").WithValues("x", 1)
+	ab := a.WithName("b").WithValues("y", 2)
+	ab.Info(1, "ab", "z", 3)
+	a.Info(1, "a")
+	s.Info(1, "none")
+	want := []string{
+		"ab logger=a/b x=1 y=2 z=3",
+		"a logger=a x=1",
+		"none",
+	}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestLogSinkError(t *testing.T) {
+	d, b := newTestLogger("", 0, 0)
+	d.Disable()
+	s := NewLogSink(d, 0).WithValues("x", 1)
+	s.Error(errors.New("boom"), "failed", "k", "v")
+	d.SetLevel(LevelFatal)
+	s.Error(errors.New("boom"), "filtered")
+	want := []string{"[ERROR] failed x=1 error=boom k=v"}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+// logrInfo stands in for logr.Logger.Info, which calls the LogSink with a
+// call depth of 1.
+func logrInfo(s logrLogSink, msg string) {
+	s.Info(0, msg)
+}
+
+func TestLogSinkCallDepth(t *testing.T) {
+	d, b := newTestLogger("", log.Lshortfile, 0)
+	s := logrSink{NewLogSink(d, 0)}
+	s.Init(logrRuntimeInfo{CallDepth: 1})
+	helper := func() {
+		logrInfo(s.WithCallDepth(1), "helper")
+	}
+	_, _, line, _ := runtime.Caller(0)
+	logrInfo(s, "direct")
+	helper()
+	want := []string{
+		fmt.Sprintf("logr_test.go:%d: [INFO] direct", line+1),
+		fmt.Sprintf("logr_test.go:%d: [INFO] helper", line+2),
+	}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,288 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+)
+
+// defaultMaxBody is the default number of body bytes HTTPMiddleware prints.
+const defaultMaxBody = 64 << 10
+
+// HTTPOption configures HTTPMiddleware.
+type HTTPOption func(*httpTrace)
+
+// httpTrace is the configuration of HTTPMiddleware.
+type httpTrace struct {
+	d       *DbgLogger
+	bit     uint64
+	reqBit  uint64 // request bodies, 0 for none
+	respBit uint64 // response bodies, 0 for none
+	maxBody int
+	next    http.Handler
+}
+
+// HTTPRequestBody prints request bodies when bit is enabled in the mask.
+func HTTPRequestBody(bit uint64) HTTPOption {
+	return func(t *httpTrace) {
+		t.reqBit = bit
+	}
+}
+
+// HTTPResponseBody prints response bodies when bit is enabled in the mask.
+func HTTPResponseBody(bit uint64) HTTPOption {
+	return func(t *httpTrace) {
+		t.respBit = bit
+	}
+}
+
+// HTTPMaxBody sets the number of bytes of a body that are printed, the
+// default is 64KiB.  Longer bodies are truncated.
+func HTTPMaxBody(n int) HTTPOption {
+	return func(t *httpTrace) {
+		t.maxBody = n
+	}
+}
+
+// HTTPMiddleware returns middleware that prints a line for every request when
+// debug is enabled and bit is enabled in the mask, i.e.
+//
+//	GET /users status=200 bytes=512 took=1.2ms
+//
+// Request and response bodies are printed under their own bits, see
+// HTTPRequestBody and HTTPResponseBody.  Requests are passed through
+// untouched when none of the bits are enabled.  The handler sees the
+// http.Flusher, http.Hijacker and http.Pusher of the original writer, a
+// hijacked connection is printed with hijacked=true instead of the status.
+func (d *DbgLogger) HTTPMiddleware(bit uint64,
+	opts ...HTTPOption) func(http.Handler) http.Handler {
+	t := httpTrace{d: d, bit: bit, maxBody: defaultMaxBody}
+	for _, o := range opts {
+		o(&t)
+	}
+	return func(next http.Handler) http.Handler {
+		n := t
+		n.next = next
+		return &n
+	}
+}
+
+// capped is an io.Writer that keeps the first max bytes written to it and
+// counts the rest.
+type capped struct {
+	buf bytes.Buffer
+	max int
+	n   int64
+}
+
+func (c *capped) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	if room := c.max - c.buf.Len(); room > 0 {
+		if len(p) > room {
+			c.buf.Write(p[:room])
+		} else {
+			c.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// body returns the kept bytes, marked when truncated.
+func (c *capped) body() string {
+	if c.n > int64(c.buf.Len()) {
+		return c.buf.String() + "... (truncated)"
+	}
+	return c.buf.String()
+}
+
+// teeBody is a request body that copies what is read to a capped.
+type teeBody struct {
+	io.Reader
+	io.Closer
+}
+
+// recorder is an http.ResponseWriter that records the status, the size and
+// optionally the body of a response.
+type recorder struct {
+	http.ResponseWriter
+	status   int
+	n        int64
+	body     *capped
+	hijacked bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.n += int64(n)
+	if r.body != nil {
+		r.body.Write(p[:n])
+	}
+	return n, err
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// flusher, hijacker and pusher forward the optional interfaces of the
+// wrapped writer, see writer.
+type (
+	flusher  struct{ r *recorder }
+	hijacker struct{ r *recorder }
+	pusher   struct{ r *recorder }
+)
+
+func (f flusher) Flush() {
+	f.r.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.r.hijacked = true
+	return h.r.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (p pusher) Push(target string, opts *http.PushOptions) error {
+	return p.r.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+// writer returns r as an http.ResponseWriter that implements http.Flusher,
+// http.Hijacker and http.Pusher exactly when the wrapped writer does, so that
+// streaming and protocol upgrades keep working through the middleware.
+func (r *recorder) writer() http.ResponseWriter {
+	_, f := r.ResponseWriter.(http.Flusher)
+	_, h := r.ResponseWriter.(http.Hijacker)
+	_, p := r.ResponseWriter.(http.Pusher)
+	switch {
+	case f && h && p:
+		return struct {
+			*recorder
+			flusher
+			hijacker
+			pusher
+		}{r, flusher{r}, hijacker{r}, pusher{r}}
+	case f && h:
+		return struct {
+			*recorder
+			flusher
+			hijacker
+		}{r, flusher{r}, hijacker{r}}
+	case f && p:
+		return struct {
+			*recorder
+			flusher
+			pusher
+		}{r, flusher{r}, pusher{r}}
+	case h && p:
+		return struct {
+			*recorder
+			hijacker
+			pusher
+		}{r, hijacker{r}, pusher{r}}
+	case f:
+		return struct {
+			*recorder
+			flusher
+		}{r, flusher{r}}
+	case h:
+		return struct {
+			*recorder
+			hijacker
+		}{r, hijacker{r}}
+	case p:
+		return struct {
+			*recorder
+			pusher
+		}{r, pusher{r}}
+	}
+	return r
+}
+
+func (t *httpTrace) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d := t.d
+	line := d.isSet(t.bit)
+	reqBody := d.isSet(t.reqBit)
+	respBody := d.isSet(t.respBit)
+	if !line && !reqBody && !respBody {
+		t.next.ServeHTTP(w, r)
+		return
+	}
+
+	var req *capped
+	if reqBody && r.Body != nil && r.Body != http.NoBody {
+		req = &capped{max: t.maxBody}
+		r.Body = teeBody{
+			Reader: io.TeeReader(r.Body, req),
+			Closer: r.Body,
+		}
+	}
+	rec := &recorder{ResponseWriter: w}
+	if respBody {
+		rec.body = &capped{max: t.maxBody}
+	}
+
+	start := d.clock()
+	t.next.ServeHTTP(rec.writer(), r)
+	took := d.clock().Sub(start)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	what := r.Method + " " + r.URL.RequestURI()
+	if req != nil {
+		d.emit(2, &Entry{Level: LevelDebug, Bit: t.reqBit,
+			Message: what + " request body (" + itoa10(int(req.n)) +
+				" bytes):\n" + req.body()})
+	}
+	if line {
+		fields := []Field{
+			{Key: "status", Value: rec.status},
+			{Key: "bytes", Value: rec.n},
+			{Key: "took", Value: took},
+		}
+		if rec.hijacked {
+			// The handler owns the connection, status and size
+			// are unknown.
+			fields = []Field{
+				{Key: "hijacked", Value: true},
+				{Key: "took", Value: took},
+			}
+		}
+		d.emit(2, &Entry{Level: LevelDebug, Bit: t.bit, Message: what,
+			Fields: fields})
+	}
+	if rec.body != nil {
+		d.emit(2, &Entry{Level: LevelDebug, Bit: t.respBit,
+			Message: what + " response body (" +
+				itoa10(int(rec.body.n)) + " bytes):\n" +
+				rec.body.body()})
+	}
+}
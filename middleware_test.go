@@ -0,0 +1,118 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPMiddlewareUpgrade(t *testing.T) {
+	d, b := newTestLogger("", 0, 1)
+	upgrade := http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		h, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "no hijacker",
+				http.StatusInternalServerError)
+			return
+		}
+		c, rw, err := h.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer c.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+			"Upgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		rw.WriteString(line)
+		rw.Flush()
+	})
+	traced := d.HTTPMiddleware(1)(upgrade)
+	done := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		traced.ServeHTTP(w, r)
+		close(done)
+	}))
+	defer s.Close()
+
+	c, err := net.Dial("tcp", s.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	c.Write([]byte("GET /ws HTTP/1.1\r\nHost: test\r\n" +
+		"Upgrade: echo\r\nConnection: Upgrade\r\n\r\n"))
+	br := bufio.NewReader(c)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status %v", resp.Status)
+	}
+	c.Write([]byte("ping\n"))
+	if line, err := br.ReadString('\n'); err != nil || line != "ping\n" {
+		t.Fatalf("echo %q %v", line, err)
+	}
+
+	// The request line is printed once the handler returned.
+	<-done
+	if got := b.String(); !strings.HasPrefix(got, "GET /ws hijacked=true") {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestHTTPMiddlewareInterfaces(t *testing.T) {
+	d, _ := newTestLogger("", 0, 1)
+	var flush, hijack, push bool
+	h := d.HTTPMiddleware(1)(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		var f http.Flusher
+		f, flush = w.(http.Flusher)
+		_, hijack = w.(http.Hijacker)
+		_, push = w.(http.Pusher)
+		w.Write([]byte("x"))
+		if flush {
+			f.Flush()
+		}
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if !flush || hijack || push {
+		t.Fatalf("flusher %v hijacker %v pusher %v", flush, hijack,
+			push)
+	}
+	if !rec.Flushed {
+		t.Fatal("not flushed")
+	}
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import "unicode/utf8"
+
+// MultilineMode selects how the lines of a message that spans multiple lines,
+// i.e. a stack trace or a hex dump, are printed in the text format.
+type MultilineMode int
+
+// Multiline modes.
+const (
+	MultilineNone   MultilineMode = iota // print as is, the default
+	MultilinePrefix                      // repeat the header on every line
+	MultilineIndent                      // indent to the end of the header
+)
+
+// SetMultilineMode sets how the continuation lines of a message are printed.
+// MultilinePrefix repeats the header, prefix, time and caller, on every line
+// which keeps collectors that treat each line independently in context.
+// MultilineIndent aligns continuation lines with the message start.
+// In both modes a colored message is colored line by line.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) SetMultilineMode(m MultilineMode) {
+	d.mu.Lock()
+	d.multiline = m
+	d.mu.Unlock()
+}
+
+// continueLines starts every continuation line of the message that follows
+// the header of hdr bytes in buf as set by SetMultilineMode.  The color c of
+// the message is reset at the end of every line and set again after the
+// header of the next, so every line is colored on its own.  buf must not end
+// in a newline.  Must be called with mu held.
+func (d *DbgLogger) continueLines(hdr int, c Color) {
+	body := d.buf[hdr:]
+	n := 0
+	for _, ch := range body {
+		if ch == '\n' {
+			n++
+		}
+	}
+	if n == 0 || hdr == 0 {
+		return
+	}
+
+	var cont []byte
+	if d.multiline == MultilinePrefix {
+		cont = append(cont, d.buf[:hdr]...)
+	} else {
+		w := utf8.RuneCount(d.buf[:hdr])
+		for i := 0; i < w; i++ {
+			cont = append(cont, ' ')
+		}
+	}
+	if c != ColorNone {
+		cont = append(cont, "\x1b["...)
+		itoa(&cont, int(c), -1)
+		cont = append(cont, 'm')
+	}
+	body = append([]byte(nil), body...)
+	d.buf = d.buf[:hdr]
+	for _, ch := range body {
+		if ch == '\n' && c != ColorNone {
+			d.buf = append(d.buf, "\x1b[0m"...)
+		}
+		d.buf = append(d.buf, ch)
+		if ch == '\n' {
+			d.buf = append(d.buf, cont...)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"log"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSetMultilineMode(t *testing.T) {
+	tests := []struct {
+		name  string
+		mode  MultilineMode
+		color bool
+		want  []string
+	}{
+		{
+			name: "none",
+			mode: MultilineNone,
+			want: []string{
+				"p 13:04:05 [WARN] one",
+				"two",
+				"p 13:04:05 single",
+			},
+		},
+		{
+			name: "prefix",
+			mode: MultilinePrefix,
+			want: []string{
+				"p 13:04:05 [WARN] one",
+				"p 13:04:05 two",
+				"p 13:04:05 single",
+			},
+		},
+		{
+			name: "indent",
+			mode: MultilineIndent,
+			want: []string{
+				"p 13:04:05 [WARN] one",
+				"           two",
+				"p 13:04:05 single",
+			},
+		},
+		{
+			name:  "none color",
+			mode:  MultilineNone,
+			color: true,
+			want: []string{
+				"p 13:04:05 \x1b[33m[WARN] one",
+				"two\x1b[0m",
+				"p 13:04:05 single",
+			},
+		},
+		{
+			name:  "prefix color",
+			mode:  MultilinePrefix,
+			color: true,
+			want: []string{
+				"p 13:04:05 \x1b[33m[WARN] one\x1b[0m",
+				"p 13:04:05 \x1b[33mtwo\x1b[0m",
+				"p 13:04:05 single",
+			},
+		},
+		{
+			name:  "indent color",
+			mode:  MultilineIndent,
+			color: true,
+			want: []string{
+				"p 13:04:05 \x1b[33m[WARN] one\x1b[0m",
+				"           \x1b[33mtwo\x1b[0m",
+				"p 13:04:05 single",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("p ", log.Ltime, 0)
+			d.SetTimeFunc(func() time.Time {
+				return time.Date(2024, 2, 29, 13, 4, 5, 0,
+					time.UTC)
+			})
+			d.SetMultilineMode(tt.mode)
+			d.SetColor(tt.color)
+			d.Warnf("one\ntwo\n")
+			d.Debugf("single")
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultilineNoHeader(t *testing.T) {
+	d, b := newTestLogger("", 0, 0)
+	d.SetMultilineMode(MultilineIndent)
+	d.Debugw("one\ntwo", "k", "v")
+	want := []string{"one", "two k=v"}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,311 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RegisterBit returns a mask bit for the named debug category, allocating the
+// next free bit the first time a name is seen.  Registering the same name again
+// returns the same bit.  It panics when all 64 bits are in use or when name is
+// a group defined with DefineGroup.
+func (d *DbgLogger) RegisterBit(name string) uint64 {
+	d.nmu.Lock()
+	if bit, ok := d.bits[name]; ok {
+		d.nmu.Unlock()
+		return bit
+	}
+	if _, ok := d.groups[name]; ok {
+		d.nmu.Unlock()
+		panic("dbglog: bit name " + strconv.Quote(name) + " is a group")
+	}
+	if len(d.bitNames) == 64 {
+		d.nmu.Unlock()
+		panic("dbglog: all mask bits are registered")
+	}
+	if d.bits == nil {
+		d.bits = make(map[string]uint64)
+	}
+	bit := uint64(1) << uint(len(d.bitNames))
+	d.bits[name] = bit
+	d.bitNames = append(d.bitNames, name)
+	changed := d.claimPending(name, bit)
+	d.nmu.Unlock()
+
+	if changed {
+		d.notify()
+	}
+	return bit
+}
+
+// BitByName returns the bit registered for name.
+func (d *DbgLogger) BitByName(name string) (uint64, bool) {
+	d.nmu.Lock()
+	defer d.nmu.Unlock()
+	bit, ok := d.bits[name]
+	return bit, ok
+}
+
+// BitName returns the name registered for a single bit or "" if there is none.
+func (d *DbgLogger) BitName(bit uint64) string {
+	if bits.OnesCount64(bit) != 1 {
+		return ""
+	}
+	n := bits.TrailingZeros64(bit)
+
+	d.nmu.Lock()
+	defer d.nmu.Unlock()
+	if n >= len(d.bitNames) {
+		return ""
+	}
+	return d.bitNames[n]
+}
+
+// BitNames returns the registered names in order of their bits.
+func (d *DbgLogger) BitNames() []string {
+	d.nmu.Lock()
+	defer d.nmu.Unlock()
+	return append([]string(nil), d.bitNames...)
+}
+
+// DefineGroup defines name as an alias for the bits in mask, i.e.
+// d.DefineGroup("storage", bitDisk|bitCache|bitWAL).  Groups can be used
+// wherever registered names are accepted, i.e. SetMaskByNames, ParseMask and
+// MaskFlag.  Defining a group again replaces it.  It fails if name is a
+// registered bit name.
+func (d *DbgLogger) DefineGroup(name string, mask uint64) error {
+	d.nmu.Lock()
+	if _, ok := d.bits[name]; ok {
+		d.nmu.Unlock()
+		return fmt.Errorf("dbglog: group %q is a bit name", name)
+	}
+	if d.groups == nil {
+		d.groups = make(map[string]uint64)
+	}
+	d.groups[name] = mask
+	changed := d.claimPending(name, mask)
+	d.nmu.Unlock()
+
+	if changed {
+		d.notify()
+	}
+	return nil
+}
+
+// GroupNames returns the names of the groups in sorted order.
+func (d *DbgLogger) GroupNames() []string {
+	d.nmu.Lock()
+	defer d.nmu.Unlock()
+	names := make([]string, 0, len(d.groups))
+	for name := range d.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// maskByName returns the bit registered for name or the bits of the group
+// name.
+func (d *DbgLogger) maskByName(name string) (uint64, bool) {
+	d.nmu.Lock()
+	defer d.nmu.Unlock()
+	if bit, ok := d.bits[name]; ok {
+		return bit, true
+	}
+	mask, ok := d.groups[name]
+	return mask, ok
+}
+
+// SetMaskByNames sets the mask to the bits of a comma separated list of
+// registered names and groups, i.e. "net,db,rpc".  The mask is not changed
+// if any of the names is not registered.
+func (d *DbgLogger) SetMaskByNames(names string) error {
+	var mask uint64
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		bit, ok := d.maskByName(name)
+		if !ok {
+			return fmt.Errorf("dbglog: unknown bit name %q", name)
+		}
+		mask |= bit
+	}
+	d.SetMask(mask)
+	return nil
+}
+
+// ParseMask returns the mask described by a comma separated list of registered
+// names and groups, numbers, i.e. 0x1f, and the wildcards "*" and "all" which
+// select all bits.  An entry prefixed with "-" clears its bits from the
+// entries before it, i.e. "all,-net".  This makes it easy to feed a command
+// line option into SetMask.
+func (d *DbgLogger) ParseMask(spec string) (uint64, error) {
+	d.nmu.Lock()
+	defer d.nmu.Unlock()
+	mask, _, err := d.parseMask(spec, false)
+	return mask, err
+}
+
+// parseMask parses spec like ParseMask.  If lazy is set names that are not
+// registered are returned as pending instead of failing, prefixed with "-"
+// when negated.  Must be called with nmu held.
+func (d *DbgLogger) parseMask(spec string, lazy bool) (uint64, []string,
+	error) {
+	var (
+		mask    uint64
+		pending []string
+	)
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		name, neg := strings.CutPrefix(s, "-")
+		name = strings.TrimSpace(name)
+		var (
+			m   uint64
+			err error
+		)
+		bit, isBit := d.bits[name]
+		group, isGroup := d.groups[name]
+		switch {
+		case name == "*" || name == "all":
+			m = DebugAll
+		case isBit:
+			m = bit
+		case isGroup:
+			m = group
+		case lazy && name != "" && (name[0] < '0' || name[0] > '9'):
+			if neg {
+				name = "-" + name
+			}
+			pending = append(pending, name)
+			continue
+		default:
+			m, err = strconv.ParseUint(name, 0, 64)
+		}
+		if err != nil {
+			return 0, nil, fmt.Errorf("dbglog: invalid mask %q", s)
+		}
+		if neg {
+			mask &^= m
+		} else {
+			mask |= m
+		}
+	}
+	return mask, pending, nil
+}
+
+// SetMaskSpec sets the mask to the ParseMask mask of spec except that names
+// that are not registered yet are not an error.  Their bits are added to the
+// mask, or cleared from it when negated, once they are registered with
+// RegisterBit or defined with DefineGroup.
+// This allows a mask from the command line to be applied before the bits are
+// registered.  A later SetMaskSpec replaces the pending names.
+func (d *DbgLogger) SetMaskSpec(spec string) error {
+	d.nmu.Lock()
+	mask, pending, err := d.parseMask(spec, true)
+	if err != nil {
+		d.nmu.Unlock()
+		return err
+	}
+	d.pending = pending
+	old := d.mask.Swap(mask)
+	d.nmu.Unlock()
+
+	if old != mask {
+		d.notify()
+	}
+	return nil
+}
+
+// SetExcludeMaskSpec is SetExcludeMask with the ParseMask mask of spec.  Like
+// with SetMaskSpec names that are not registered yet are added to the exclude
+// mask once they are registered.
+func (d *DbgLogger) SetExcludeMaskSpec(spec string) error {
+	d.nmu.Lock()
+	defer d.nmu.Unlock()
+	mask, pending, err := d.parseMask(spec, true)
+	if err != nil {
+		return err
+	}
+	d.pendingExclude = pending
+	d.exclude.Store(mask)
+	return nil
+}
+
+// claimPending adds mask to the mask if name is pending since SetMaskSpec and
+// reports whether that changed the mask.  It is added to the exclude mask if
+// it is pending since SetExcludeMaskSpec.  Negated names clear mask instead.
+// Must be called with nmu held.
+func (d *DbgLogger) claimPending(name string, mask uint64) bool {
+	if removeName(&d.pendingExclude, name) {
+		d.exclude.Or(mask)
+	}
+	if removeName(&d.pendingExclude, "-"+name) {
+		d.exclude.And(^mask)
+	}
+	var changed bool
+	if removeName(&d.pending, name) {
+		old := d.mask.Or(mask)
+		changed = old|mask != old
+	}
+	if removeName(&d.pending, "-"+name) {
+		old := d.mask.And(^mask)
+		changed = changed || old&mask != 0
+	}
+	return changed
+}
+
+// removeName removes name from names and reports whether it was there.
+func removeName(names *[]string, name string) bool {
+	for i, n := range *names {
+		if n == name {
+			*names = append((*names)[:i], (*names)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// MaskNames returns the registered names of the bits that are enabled in the
+// mask.
+func (d *DbgLogger) MaskNames() []string {
+	mask := d.GetMask()
+
+	d.nmu.Lock()
+	defer d.nmu.Unlock()
+	var names []string
+	for i, name := range d.bitNames {
+		if mask&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// maskString returns mask in hexadecimal, i.e. 0x1f.
+func maskString(mask uint64) string {
+	return "0x" + strconv.FormatUint(mask, 16)
+}
@@ -0,0 +1,257 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetMaskSpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		register []string // bits registered after SetMaskSpec
+		group    bool     // define "grp" as bits 0x30 afterwards
+		want     uint64
+		err      bool
+	}{
+		{name: "numbers", spec: "0x1,0x4", want: 0x5},
+		{name: "all", spec: "all", want: DebugAll},
+		{name: "registered", spec: "net", want: 1},
+		{
+			name:     "pending",
+			spec:     "db,0x10",
+			register: []string{"disk", "db"},
+			want:     4 | 0x10,
+		},
+		{
+			name:     "never registered",
+			spec:     "rpc",
+			register: []string{"disk"},
+			want:     0,
+		},
+		{name: "group", spec: "grp", group: true, want: 0x30},
+		{
+			name:     "pending negated",
+			spec:     "all,-db",
+			register: []string{"disk", "db"},
+			want:     DebugAll &^ 4,
+		},
+		{
+			name:  "pending negated group",
+			spec:  "all,-grp",
+			group: true,
+			want:  DebugAll &^ 0x30,
+		},
+		{name: "invalid number", spec: "0xzz", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewNop()
+			d.RegisterBit("net")
+			err := d.SetMaskSpec(tt.spec)
+			if tt.err {
+				if err == nil {
+					t.Fatal("no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range tt.register {
+				d.RegisterBit(name)
+			}
+			if tt.group {
+				d.DefineGroup("grp", 0x30)
+			}
+			if got := d.GetMask(); got != tt.want {
+				t.Fatalf("got %#x, want %#x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetExcludeMaskSpec(t *testing.T) {
+	d, b := newTestLogger("", 0, DebugAll)
+	net := d.RegisterBit("net")
+	if err := d.SetExcludeMaskSpec("net,disk"); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.GetExcludeMask(); got != net {
+		t.Fatalf("got %#x, want %#x", got, net)
+	}
+	disk := d.RegisterBit("disk")
+	if got := d.GetExcludeMask(); got != net|disk {
+		t.Fatalf("pending: got %#x, want %#x", got, net|disk)
+	}
+	if got := d.GetMask(); got != DebugAll {
+		t.Fatalf("mask changed to %#x", got)
+	}
+	db := d.RegisterBit("db")
+	d.DebugfM(net, "net")
+	d.DebugfM(db|disk, "db and disk")
+	d.DebugfM(db, "db")
+	if err := d.SetExcludeMaskSpec("0xzz"); err == nil {
+		t.Fatal("no error")
+	}
+	if got := d.GetExcludeMask(); got != net|disk {
+		t.Fatalf("after error: got %#x, want %#x", got, net|disk)
+	}
+	if got := b.String(); got != "db\n" {
+		t.Fatalf("got %q, want %q", got, "db\n")
+	}
+}
+
+func TestRegisterBit(t *testing.T) {
+	d := NewNop()
+	net := d.RegisterBit("net")
+	db := d.RegisterBit("db")
+	if net != 1 || db != 2 {
+		t.Fatalf("got %#x %#x", net, db)
+	}
+	if got := d.RegisterBit("net"); got != net {
+		t.Fatalf("registered again: got %#x, want %#x", got, net)
+	}
+	if err := d.DefineGroup("net", db); err == nil {
+		t.Fatal("group with a bit name")
+	}
+	if err := d.DefineGroup("storage", db); err != nil {
+		t.Fatal(err)
+	}
+
+	var r interface{}
+	func() {
+		defer func() { r = recover() }()
+		d.RegisterBit("storage")
+	}()
+	if r != `dbglog: bit name "storage" is a group` {
+		t.Fatalf("recovered %v", r)
+	}
+	want := []string{"net", "db"}
+	if got := d.BitNames(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	for i := len(want); i < 64; i++ {
+		d.RegisterBit(itoa10(i))
+	}
+	r = nil
+	func() {
+		defer func() { r = recover() }()
+		d.RegisterBit("full")
+	}()
+	if r != "dbglog: all mask bits are registered" {
+		t.Fatalf("recovered %v", r)
+	}
+}
+
+func TestSetMaskByNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		names string
+		want  uint64
+		err   bool
+	}{
+		{name: "empty", names: "", want: 0},
+		{name: "single", names: "net", want: 0x1},
+		{name: "list", names: "net, rpc", want: 0x5},
+		{name: "group", names: "storage", want: 0x6},
+		{name: "group and bit", names: "net,storage", want: 0x7},
+		{name: "empty entries", names: ",net,,", want: 0x1},
+		{name: "unknown", names: "net,nope", err: true},
+		{name: "number", names: "0x1", err: true},
+		{name: "wildcard", names: "all", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewNop()
+			d.RegisterBit("net")
+			db := d.RegisterBit("db")
+			rpc := d.RegisterBit("rpc")
+			d.DefineGroup("storage", db|rpc)
+			d.SetMask(0x100)
+			err := d.SetMaskByNames(tt.names)
+			if tt.err {
+				if err == nil {
+					t.Fatal("no error")
+				}
+				if got := d.GetMask(); got != 0x100 {
+					t.Fatalf("mask changed to %#x", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := d.GetMask(); got != tt.want {
+				t.Fatalf("got %#x, want %#x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMask(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want uint64
+		err  bool
+	}{
+		{name: "empty", spec: "", want: 0},
+		{name: "names", spec: "net, rpc", want: 0x5},
+		{name: "group", spec: "storage", want: 0x6},
+		{name: "numbers", spec: "0x10,8,0b1", want: 0x19},
+		{name: "names and numbers", spec: "db,0x100", want: 0x102},
+		{name: "star", spec: "*", want: DebugAll},
+		{name: "all", spec: "all", want: DebugAll},
+		{name: "negated name", spec: "all,-net", want: DebugAll &^ 1},
+		{name: "negated group", spec: "*,-storage", want: ^uint64(6)},
+		{name: "negated number", spec: "storage,-0x2", want: 0x4},
+		{name: "negation is ordered", spec: "-net,net", want: 0x1},
+		{name: "negated all", spec: "net,-all", want: 0},
+		{name: "unknown", spec: "net,nope", err: true},
+		{name: "negated unknown", spec: "all,-nope", err: true},
+		{name: "bare negation", spec: "net,-", err: true},
+		{name: "invalid number", spec: "0xzz", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewNop()
+			d.RegisterBit("net")
+			db := d.RegisterBit("db")
+			rpc := d.RegisterBit("rpc")
+			d.DefineGroup("storage", db|rpc)
+			got, err := d.ParseMask(tt.spec)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("no error, got %#x", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %#x, want %#x", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// errNetClosed is returned when writing to a closed NetWriter.
+var errNetClosed = errors.New("dbglog: write to closed NetWriter")
+
+// Defaults of a NetWriter.
+const (
+	defaultNetBuffer  = 1 << 20
+	defaultNetTimeout = 5 * time.Second
+	defaultMinBackoff = 100 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// NetOption configures a NetWriter.
+type NetOption func(*NetWriter)
+
+// NetBuffer sets how many bytes of messages a NetWriter keeps while it is
+// disconnected, the default is 1MiB.  The oldest messages are dropped first.
+func NetBuffer(bytes int) NetOption {
+	return func(n *NetWriter) {
+		n.maxBuffer = bytes
+	}
+}
+
+// NetBackoff sets the delay between reconnection attempts of a NetWriter.  It
+// starts at min and doubles after every failure up to max.  The defaults are
+// 100ms and 30s.
+func NetBackoff(min, max time.Duration) NetOption {
+	return func(n *NetWriter) {
+		n.minBackoff = min
+		n.maxBackoff = max
+	}
+}
+
+// NetTimeout sets the dial and write timeout of a NetWriter, the default is 5s.
+func NetTimeout(d time.Duration) NetOption {
+	return func(n *NetWriter) {
+		n.timeout = d
+	}
+}
+
+// NetWriter streams messages to a remote host over TCP or UDP, i.e. from
+// devices without a local disk.  Messages written while the connection is down
+// are buffered and sent once it is back.  Reconnection is attempted on the
+// next Write after the backoff delay so a Write never waits for more than a
+// dial and a write.  It is created with NewNetWriter.
+type NetWriter struct {
+	network    string
+	addr       string
+	maxBuffer  int
+	timeout    time.Duration
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu      sync.Mutex // protects everything below
+	conn    net.Conn
+	pending [][]byte      // messages written while disconnected
+	size    int           // bytes in pending
+	backoff time.Duration // current reconnection delay
+	retry   time.Time     // no reconnection attempt before
+	dropped uint64        // messages dropped from pending
+	closed  bool
+}
+
+// NewNetWriter returns a NetWriter that sends to addr on network, i.e.
+// NewNetWriter("tcp", "loghost:5140").  The first connection is attempted
+// right away but failing to connect is not an error, messages are buffered
+// until the host can be reached.
+func NewNetWriter(network, addr string, opts ...NetOption) *NetWriter {
+	n := &NetWriter{
+		network:    network,
+		addr:       addr,
+		maxBuffer:  defaultNetBuffer,
+		timeout:    defaultNetTimeout,
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+	for _, o := range opts {
+		o(n)
+	}
+	n.mu.Lock()
+	n.connect(time.Now())
+	n.mu.Unlock()
+	return n
+}
+
+// connect dials the host unless the backoff delay has not passed yet.  Must be
+// called with mu held.
+func (n *NetWriter) connect(now time.Time) bool {
+	if now.Before(n.retry) {
+		return false
+	}
+	c, err := net.DialTimeout(n.network, n.addr, n.timeout)
+	if err != nil {
+		n.fail(now)
+		return false
+	}
+	n.conn = c
+	n.backoff = 0
+	return true
+}
+
+// fail drops the connection and delays the next attempt.  Must be called with
+// mu held.
+func (n *NetWriter) fail(now time.Time) {
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+	switch {
+	case n.backoff == 0:
+		n.backoff = n.minBackoff
+	case n.backoff < n.maxBackoff:
+		n.backoff *= 2
+	}
+	if n.backoff > n.maxBackoff {
+		n.backoff = n.maxBackoff
+	}
+	n.retry = now.Add(n.backoff)
+}
+
+// send writes p to the connection.  It returns the number of bytes written,
+// which is only less than len(p) when the write failed.  Must be called with
+// mu held.
+func (n *NetWriter) send(p []byte) (int, error) {
+	if n.timeout > 0 {
+		n.conn.SetWriteDeadline(time.Now().Add(n.timeout))
+	}
+	return n.conn.Write(p)
+}
+
+// buffer adds a copy of p to the pending messages, dropping the oldest ones to
+// stay within the buffer size.  Must be called with mu held.
+func (n *NetWriter) buffer(p []byte) {
+	if len(p) > n.maxBuffer {
+		n.dropped++
+		return
+	}
+	for n.size+len(p) > n.maxBuffer {
+		n.size -= len(n.pending[0])
+		n.pending[0] = nil
+		n.pending = n.pending[1:]
+		n.dropped++
+	}
+	n.pending = append(n.pending, append([]byte(nil), p...))
+	n.size += len(p)
+}
+
+// Write sends p or, when the host cannot be reached, buffers it.  A message
+// that was partially sent when the connection failed is dropped instead of
+// being sent again.  It only fails after Close.
+func (n *NetWriter) Write(p []byte) (int, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed {
+		return 0, errNetClosed
+	}
+	now := time.Now()
+	if n.conn == nil && !n.connect(now) {
+		n.buffer(p)
+		return len(p), nil
+	}
+	for len(n.pending) > 0 {
+		sent, err := n.send(n.pending[0])
+		if err != nil {
+			n.fail(now)
+			if sent > 0 {
+				n.size -= len(n.pending[0])
+				n.pending[0] = nil
+				n.pending = n.pending[1:]
+				n.dropped++
+			}
+			n.buffer(p)
+			return len(p), nil
+		}
+		n.size -= len(n.pending[0])
+		n.pending[0] = nil
+		n.pending = n.pending[1:]
+	}
+	sent, err := n.send(p)
+	if err != nil {
+		n.fail(now)
+		if sent > 0 {
+			n.dropped++
+		} else {
+			n.buffer(p)
+		}
+	}
+	return len(p), nil
+}
+
+// DroppedCount returns the number of messages that were dropped because the
+// buffer was full or because they were only partially sent.
+func (n *NetWriter) DroppedCount() uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.dropped
+}
+
+// Close closes the connection.  Buffered messages that could not be sent are
+// discarded.
+func (n *NetWriter) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.closed = true
+	n.pending = nil
+	n.size = 0
+	if n.conn == nil {
+		return nil
+	}
+	err := n.conn.Close()
+	n.conn = nil
+	return err
+}
@@ -0,0 +1,218 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// freeAddr returns a local TCP address that nothing listens on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+// readLines reads n lines from c.
+func readLines(t *testing.T, c net.Conn, n int) []string {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(c)
+	var got []string
+	for i := 0; i < n; i++ {
+		l, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read %q: %v", got, err)
+		}
+		got = append(got, strings.TrimSuffix(l, "\n"))
+	}
+	return got
+}
+
+func TestNetWriterBuffersWhileDown(t *testing.T) {
+	addr := freeAddr(t)
+	n := NewNetWriter("tcp", addr, NetBuffer(4),
+		NetBackoff(time.Millisecond, time.Millisecond))
+	defer n.Close()
+	for _, s := range []string{"a\n", "b\n", "c\n", "too long\n"} {
+		if _, err := n.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a is dropped to make room for c and the long line does not fit.
+	if d := n.DroppedCount(); d != 2 {
+		t.Fatalf("dropped %v, want 2", d)
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+	time.Sleep(2 * time.Millisecond)
+	n.Write([]byte("d\n"))
+	c, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	got := readLines(t, c, 3)
+	if strings.Join(got, ",") != "b,c,d" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestNetWriterReconnects(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	conns := make(chan net.Conn, 2)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns <- c
+		}
+	}()
+
+	n := NewNetWriter("tcp", l.Addr().String(),
+		NetBackoff(time.Millisecond, time.Millisecond))
+	defer n.Close()
+	first := <-conns
+	n.Write([]byte("one\n"))
+	if got := readLines(t, first, 1); got[0] != "one" {
+		t.Fatalf("got %q", got)
+	}
+	first.Close()
+
+	// Writes to the dead connection eventually fail, after which the
+	// writer dials again and sends what it buffered.
+	var second net.Conn
+	for deadline := time.Now().Add(5 * time.Second); second == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("no reconnection")
+		}
+		n.Write([]byte("again\n"))
+		select {
+		case second = <-conns:
+		case <-time.After(time.Millisecond):
+		}
+	}
+	defer second.Close()
+	if got := readLines(t, second, 1); got[0] != "again" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestNetWriterBackoff(t *testing.T) {
+	n := &NetWriter{
+		network:    "tcp",
+		addr:       freeAddr(t),
+		minBackoff: 10 * time.Millisecond,
+		maxBackoff: 40 * time.Millisecond,
+	}
+	now := time.Now()
+	var got []time.Duration
+	for i := 0; i < 4; i++ {
+		n.fail(now)
+		got = append(got, n.backoff)
+	}
+	want := []time.Duration{10, 20, 40, 40}
+	for i := range want {
+		if got[i] != want[i]*time.Millisecond {
+			t.Fatalf("got %v, want %v ms", got, want)
+		}
+	}
+	if n.connect(now.Add(39 * time.Millisecond)) {
+		t.Fatal("connected before the backoff passed")
+	}
+	if !n.retry.Equal(now.Add(40 * time.Millisecond)) {
+		t.Fatal("early attempt moved the retry time")
+	}
+}
+
+// partialConn is a connection whose writes fail after n bytes.
+type partialConn struct {
+	net.Conn
+	n int
+}
+
+func (c *partialConn) Write(p []byte) (int, error) {
+	if len(p) > c.n {
+		return c.n, errors.New("connection reset")
+	}
+	return len(p), nil
+}
+
+func (c *partialConn) SetWriteDeadline(time.Time) error { return nil }
+
+func (c *partialConn) Close() error { return nil }
+
+func TestNetWriterPartialWrite(t *testing.T) {
+	n := NewNetWriter("tcp", freeAddr(t), NetBackoff(time.Hour, time.Hour))
+	defer n.Close()
+	n.mu.Lock()
+	n.conn = &partialConn{n: 3}
+	n.mu.Unlock()
+	n.Write([]byte("hello\n"))
+	if d := n.DroppedCount(); d != 1 {
+		t.Fatalf("dropped %v, want 1", d)
+	}
+	if len(n.pending) != 0 {
+		t.Fatalf("buffered %q", n.pending)
+	}
+
+	// Nothing sent, the whole message is kept.
+	n.mu.Lock()
+	n.conn = &partialConn{}
+	n.mu.Unlock()
+	n.Write([]byte("a\n"))
+	n.mu.Lock()
+	n.conn = &partialConn{n: 1}
+	n.mu.Unlock()
+	n.Write([]byte("b\n"))
+	if d := n.DroppedCount(); d != 2 {
+		t.Fatalf("dropped %v, want 2", d)
+	}
+	if len(n.pending) != 1 || string(n.pending[0]) != "b\n" {
+		t.Fatalf("buffered %q", n.pending)
+	}
+}
+
+func TestNetWriterClosed(t *testing.T) {
+	n := NewNetWriter("tcp", freeAddr(t))
+	n.Close()
+	if _, err := n.Write([]byte("x\n")); err == nil {
+		t.Fatal("write after Close succeeded")
+	}
+}
@@ -0,0 +1,424 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"io"
+	"log"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SetPrefixSeparator sets the string that is inserted between the rendered
+// header (prefix, date, time and file) and the message of the Debug
+// functions.  Trailing blanks of the header are dropped before the separator
+// is added.  Until it is called the header is left as is, which yields the
+// same lines as log.Logger.
+func (d *DbgLogger) SetPrefixSeparator(s string) {
+	d.mu.Lock()
+	d.sep = s
+	d.sepSet = true
+	d.mu.Unlock()
+}
+
+// SetCallDepth sets the number of additional stack frames to skip when the
+// file and line of the caller are printed, see log.Lshortfile.  Wrappers
+// around the Debug functions set this to the number of frames they add so
+// that the file and line of their caller is printed instead of their own.
+func (d *DbgLogger) SetCallDepth(depth int) {
+	d.depth.Store(int32(depth))
+}
+
+// RFC3339Micro is RFC 3339 with microseconds, a layout for SetTimeFormat that
+// is required by many log pipelines.
+const RFC3339Micro = "2006-01-02T15:04:05.000000Z07:00"
+
+// SetTimeFormat sets the layout, as used by time.Time.Format, that is used to
+// render the time of the Debug functions, i.e. RFC3339Micro.  When set the
+// time is always printed and the date and time flags of log.Logger are
+// ignored, LUTC is still honored.  An empty layout restores the log.Logger
+// flag behavior.
+func (d *DbgLogger) SetTimeFormat(layout string) {
+	d.mu.Lock()
+	d.timeFormat = layout
+	d.mu.Unlock()
+}
+
+// SetTimeFunc sets the function used to obtain the time of a message and to
+// measure durations, i.e. of Trace.  A nil function restores time.Now.  A fake
+// clock makes the output deterministic in tests.
+func (d *DbgLogger) SetTimeFunc(f func() time.Time) {
+	d.mu.Lock()
+	d.timeFunc = f
+	d.mu.Unlock()
+}
+
+// clock returns the current time as set by SetTimeFunc.
+func (d *DbgLogger) clock() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.now()
+}
+
+// now returns the current time.  Must be called with mu held.
+func (d *DbgLogger) now() time.Time {
+	if d.timeFunc != nil {
+		return d.timeFunc()
+	}
+	return time.Now()
+}
+
+// itoa appends the decimal representation of i zero padded to wid digits.
+func itoa(buf *[]byte, i int, wid int) {
+	var b [20]byte
+	bp := len(b) - 1
+	for i >= 10 || wid > 1 {
+		wid--
+		q := i / 10
+		b[bp] = byte('0' + i - q*10)
+		bp--
+		i = q
+	}
+	b[bp] = byte('0' + i)
+	*buf = append(*buf, b[bp:]...)
+}
+
+// formatHeader appends the log.Logger style header to buf.  If layout is not
+// empty it is used to render the time instead of the date and time flags.
+// The process and goroutine ids of Lpid and Lgoroutine follow the time.
+func formatHeader(buf *[]byte, prefix string, flag int, layout string,
+	t time.Time, gid uint64, file string, line int) {
+	if flag&log.Lmsgprefix == 0 {
+		*buf = append(*buf, prefix...)
+	}
+	if layout != "" {
+		if flag&log.LUTC != 0 {
+			t = t.UTC()
+		}
+		*buf = t.AppendFormat(*buf, layout)
+		*buf = append(*buf, ' ')
+	} else if flag&(log.Ldate|log.Ltime|log.Lmicroseconds) != 0 {
+		if flag&log.LUTC != 0 {
+			t = t.UTC()
+		}
+		if flag&log.Ldate != 0 {
+			year, month, day := t.Date()
+			itoa(buf, year, 4)
+			*buf = append(*buf, '/')
+			itoa(buf, int(month), 2)
+			*buf = append(*buf, '/')
+			itoa(buf, day, 2)
+			*buf = append(*buf, ' ')
+		}
+		if flag&(log.Ltime|log.Lmicroseconds) != 0 {
+			hour, min, sec := t.Clock()
+			itoa(buf, hour, 2)
+			*buf = append(*buf, ':')
+			itoa(buf, min, 2)
+			*buf = append(*buf, ':')
+			itoa(buf, sec, 2)
+			if flag&log.Lmicroseconds != 0 {
+				*buf = append(*buf, '.')
+				itoa(buf, t.Nanosecond()/1e3, 6)
+			}
+			*buf = append(*buf, ' ')
+		}
+	}
+	if flag&Lpid != 0 {
+		*buf = append(*buf, '[')
+		itoa(buf, pid, -1)
+		*buf = append(*buf, "] "...)
+	}
+	if flag&Lgoroutine != 0 {
+		*buf = append(*buf, 'g')
+		*buf = strconv.AppendUint(*buf, gid, 10)
+		*buf = append(*buf, ' ')
+	}
+	if flag&(log.Lshortfile|log.Llongfile) != 0 {
+		if flag&log.Lshortfile != 0 {
+			for i := len(file) - 1; i > 0; i-- {
+				if file[i] == '/' {
+					file = file[i+1:]
+					break
+				}
+			}
+		}
+		*buf = append(*buf, file...)
+		*buf = append(*buf, ':')
+		itoa(buf, line, -1)
+		*buf = append(*buf, ": "...)
+	}
+	if flag&log.Lmsgprefix != 0 {
+		*buf = append(*buf, prefix...)
+	}
+}
+
+// Entry is a single message on its way to the output.  It is passed to hooks,
+// see AddHook.
+type Entry struct {
+	Time    time.Time
+	Prefix  string // prefix of the logger without surrounding blanks
+	Level   Level
+	Bit     uint64 // 0 for messages printed without a mask bit
+	Message string
+	Fields  []Field // key/value pairs, see Debugw
+	File    string  // only set when log.Lshortfile or log.Llongfile is set
+	Line    int
+
+	// Goroutine is the id of the goroutine that printed the message.  It
+	// is only set when Lgoroutine is set.
+	Goroutine uint64
+
+	ringOnly bool   // only captured by the ring buffer, not printed
+	tag      string // the tag of DebugfTagged that leads the message
+}
+
+// output prints s at level and bit.  calldepth has the same meaning as in
+// log.Logger.Output.
+func (d *DbgLogger) output(calldepth int, level Level, bit uint64,
+	s string) error {
+	return d.emit(calldepth+1, &Entry{Level: level, Bit: bit, Message: s})
+}
+
+// emit renders an entry and writes it to the writer selected by the routes or
+// else to the output of the embedded log.Logger.  calldepth has the same
+// meaning as in log.Logger.Output.  The time, caller and goroutine are filled
+// in unless they are already set.
+func (d *DbgLogger) emit(calldepth int, e *Entry) error {
+	flag := d.Flags()
+	if flag&(log.Lshortfile|log.Llongfile) != 0 && e.File == "" {
+		var ok bool
+		_, e.File, e.Line, ok = runtime.Caller(calldepth +
+			int(d.depth.Load()))
+		if !ok {
+			e.File = "???"
+			e.Line = 0
+		}
+	}
+
+	if flag&Lgoroutine != 0 && e.Goroutine == 0 {
+		e.Goroutine = goroutineID()
+	}
+	if e.Prefix == "" {
+		e.Prefix = strings.TrimSpace(d.Prefix())
+	}
+
+	d.mu.Lock()
+	bp := getBuf()
+	d.buf = *bp
+	if e.Time.IsZero() {
+		e.Time = d.now()
+	}
+	if len(d.fields) != 0 {
+		e.Fields = append(append([]Field(nil), d.fields...),
+			e.Fields...)
+	}
+	if len(d.redactors) != 0 {
+		d.redactEntry(e)
+	}
+	if d.maxMessage > 0 {
+		e.Message = truncated(e.Message, d.maxMessage)
+	}
+	d.render(e, flag, d.format)
+	if d.ring != nil {
+		d.capture(d.buf)
+	}
+	if e.ringOnly || (e.Level == LevelDebug && (!d.printed(e.Bit) ||
+		!d.sampled(e.Bit))) || d.filtered(e) {
+		// Only rendered for the ring.
+		putBuf(bp, d.buf)
+		d.buf = nil
+		d.mu.Unlock()
+		d.count(e.Level, e.Bit, false)
+		return nil
+	}
+	var (
+		repeat        bool
+		err           error
+		failed, serrs []error // write and Sync errors
+	)
+	if d.dedup {
+		var rerr error
+		if repeat, rerr = d.dedupe(e, flag); rerr != nil {
+			failed = append(failed, rerr)
+		}
+	}
+	if !repeat {
+		var tf, ts []error
+		if err, tf, ts = d.deliver(e, flag); err != nil {
+			failed = append(failed, err)
+		}
+		failed = append(failed, tf...)
+		serrs = append(serrs, ts...)
+	}
+	d.count(e.Level, e.Bit, !repeat)
+	handler := d.errorHandler
+	var hooks []func(Entry)
+	if !repeat {
+		hooks = d.hooks
+	}
+	putBuf(bp, d.buf)
+	d.buf = nil
+	d.mu.Unlock()
+
+	for _, h := range hooks {
+		h(*e)
+	}
+	for _, ferr := range failed {
+		d.dropped.Add(1)
+		if handler != nil {
+			handler(ferr)
+		}
+	}
+	for _, serr := range serrs {
+		if handler != nil {
+			handler(serr)
+		}
+	}
+	return err
+}
+
+// render formats e into buf in format f.  Must be called with mu held.
+func (d *DbgLogger) render(e *Entry, flag int, f Format) {
+	d.buf = d.buf[:0]
+	switch f {
+	case FormatJSON:
+		d.formatJSON(e, flag)
+	case FormatLogfmt:
+		d.formatLogfmt(e, flag)
+	default:
+		if d.template == nil || !d.formatTemplate(e, flag) {
+			d.formatText(e, flag)
+		}
+	}
+
+}
+
+// deliver writes the line in buf for e to its route and to the outputs added
+// with AddOutput and Syncs them if requested.  A Sub logger uses the routes and
+// outputs of its parents as well, a message that none of its own rules selects
+// goes to the route of its parent.  It returns the write error of the route
+// and the write and Sync errors of the outputs.  Must be called with mu held.
+func (d *DbgLogger) deliver(e *Entry, flag int) (error, []error, []error) {
+	return d.deliverVia(d, e, flag, false)
+}
+
+// deliverVia is deliver for a line rendered by r, which is d or a logger
+// derived from it, with the routes and outputs of d.  routed is set when the
+// line was already written to a route.  Must be called with the mu of d and r
+// held.
+func (d *DbgLogger) deliverVia(r *DbgLogger, e *Entry, flag int,
+	routed bool) (error, []error, []error) {
+	var (
+		err  error
+		serr error
+	)
+	if !routed {
+		w, ok := d.route(e.Level, e.Bit)
+		if _, up := w.(parentWriter); ok || !up {
+			err, serr = r.deliverTo(w, e)
+			routed = true
+		}
+	}
+	failed, serrs := r.tee(d.outputs, e, flag)
+	if serr != nil {
+		serrs = append(serrs, serr)
+	}
+	if d.parent == nil {
+		return err, failed, serrs
+	}
+
+	d.parent.mu.Lock()
+	perr, pf, ps := d.parent.deliverVia(r, e, flag, routed)
+	d.parent.mu.Unlock()
+	if err == nil {
+		err = perr
+	}
+	return err, append(failed, pf...), append(serrs, ps...)
+}
+
+// deliverTo is deliver to w.  Must be called with mu held.
+func (d *DbgLogger) deliverTo(w io.Writer, e *Entry) (error, error) {
+	var err error
+	if b, ok := w.(Backend); ok {
+		err = b.WriteEntry(e, d.buf)
+	} else {
+		err = d.writeLines(w, d.buf)
+	}
+	if err != nil || !d.syncWrites {
+		return err, nil
+	}
+	if s, ok := w.(syncer); ok {
+		return nil, s.Sync()
+	}
+	return nil, nil
+}
+
+// formatText appends e to buf in the log.Logger layout.  Messages of levels
+// other than LevelDebug are tagged with their level and fields are appended as
+// key=value pairs.  Must be called with mu held.
+func (d *DbgLogger) formatText(e *Entry, flag int) {
+	d.appendBitTag(e, BitTagLine)
+	formatHeader(&d.buf, d.Prefix(), flag, d.timeFormat, e.Time,
+		e.Goroutine, e.File, e.Line)
+	if d.sepSet {
+		for len(d.buf) > 0 && d.buf[len(d.buf)-1] == ' ' {
+			d.buf = d.buf[:len(d.buf)-1]
+		}
+		if len(d.buf) > 0 {
+			d.buf = append(d.buf, d.sep...)
+		}
+	}
+	hdr := len(d.buf)
+	var c Color
+	if d.color {
+		c = d.lineColor(e)
+	}
+	if c != ColorNone {
+		d.buf = append(d.buf, "\x1b["...)
+		itoa(&d.buf, int(c), -1)
+		d.buf = append(d.buf, 'm')
+	}
+	if e.Level != LevelDebug {
+		d.buf = append(d.buf, '[')
+		d.buf = append(d.buf, strings.ToUpper(e.Level.String())...)
+		d.buf = append(d.buf, "] "...)
+	}
+	d.appendBitTag(e, BitTagMessage)
+	msg := e.Message
+	if len(e.Fields) != 0 {
+		msg = strings.TrimSuffix(msg, "\n")
+	}
+	d.appendMessage(e, msg, c)
+	for _, f := range e.Fields {
+		d.buf = append(d.buf, ' ')
+		d.buf = appendField(d.buf, f)
+	}
+	if n := len(d.buf); n != 0 && d.buf[n-1] == '\n' {
+		d.buf = d.buf[:n-1]
+	}
+	d.appendBitTag(e, BitTagEnd)
+	if c != ColorNone {
+		d.buf = append(d.buf, "\x1b[0m"...)
+	}
+	if d.multiline != MultilineNone {
+		d.continueLines(hdr, c)
+	}
+	d.buf = append(d.buf, '\n')
+}
@@ -0,0 +1,154 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSetTimeFormat(t *testing.T) {
+	pinned := time.Date(2024, 2, 29, 13, 4, 5, 123456789,
+		time.FixedZone("CET", 3600))
+	tests := []struct {
+		name   string
+		flag   int
+		layout string
+		want   string
+	}{
+		{
+			name:   "rfc3339nano",
+			layout: time.RFC3339Nano,
+			want:   "2024-02-29T13:04:05.123456789+01:00 msg\n",
+		},
+		{
+			name:   "rfc3339micro utc",
+			flag:   log.LUTC,
+			layout: RFC3339Micro,
+			want:   "2024-02-29T12:04:05.123456Z msg\n",
+		},
+		{
+			name:   "rfc3339micro",
+			layout: RFC3339Micro,
+			want:   "2024-02-29T13:04:05.123456+01:00 msg\n",
+		},
+		{
+			name:   "overrides flags",
+			flag:   log.LstdFlags,
+			layout: "15:04",
+			want:   "13:04 msg\n",
+		},
+		{
+			name: "flags",
+			flag: log.LstdFlags | log.Lmicroseconds,
+			want: "2024/02/29 13:04:05.123456 msg\n",
+		},
+		{name: "no time", want: "msg\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", tt.flag, 0)
+			d.SetTimeFunc(func() time.Time { return pinned })
+			d.SetTimeFormat(tt.layout)
+			d.Debugf("msg")
+			if got := b.String(); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// callerLine returns the line it is called from.
+func callerLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+// debugWrapper is a wrapper around Debugf that adds one frame.  It returns the
+// line of its call to Debugf.
+func debugWrapper(d *DbgLogger, format string, v ...interface{}) int {
+	line := callerLine() + 1
+	d.Debugf(format, v...)
+	return line
+}
+
+func TestSetCallDepth(t *testing.T) {
+	tests := []struct {
+		name  string
+		depth int
+		call  func(d *DbgLogger) int // returns the line of the call
+	}{
+		{
+			name: "Debug",
+			call: func(d *DbgLogger) int {
+				line := callerLine() + 1
+				d.Debug("msg")
+				return line
+			},
+		},
+		{
+			name: "DebugfM",
+			call: func(d *DbgLogger) int {
+				line := callerLine() + 1
+				d.DebugfM(1, "msg")
+				return line
+			},
+		},
+		{
+			name: "Writer",
+			call: func(d *DbgLogger) int {
+				w := d.Writer(1)
+				line := callerLine() + 1
+				w.Write([]byte("msg\n"))
+				return line
+			},
+		},
+		{
+			name:  "wrapper",
+			depth: 1,
+			call: func(d *DbgLogger) int {
+				line := callerLine() + 1
+				debugWrapper(d, "msg")
+				return line
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", log.Lshortfile, 1)
+			d.SetCallDepth(tt.depth)
+			line := tt.call(d)
+			want := fmt.Sprintf("output_test.go:%v: msg\n", line)
+			if got := b.String(); got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+		})
+	}
+
+	// Without the depth the wrapper itself is reported.
+	d, b := newTestLogger("", log.Lshortfile, 1)
+	line := debugWrapper(d, "msg")
+	want := fmt.Sprintf("output_test.go:%v: msg\n", line)
+	if got := b.String(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
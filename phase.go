@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// phase is a named checkpoint of a PhaseTimer.
+type phase struct {
+	name string
+	t    time.Time
+}
+
+// PhaseTimer measures the duration of consecutive named phases.  It is created
+// with NewPhaseTimer.
+type PhaseTimer struct {
+	d   *DbgLogger
+	bit uint64
+
+	mu     sync.Mutex // protects phases
+	start  time.Time
+	phases []phase
+}
+
+// NewPhaseTimer returns a PhaseTimer that starts timing now.  Time is obtained
+// from the clock set with SetTimeFunc.
+func (d *DbgLogger) NewPhaseTimer(bit uint64) *PhaseTimer {
+	return &PhaseTimer{d: d, bit: bit, start: d.clock()}
+}
+
+// Mark ends the current phase and names it.  The duration of a phase is the
+// time since the previous Mark or, for the first phase, since the timer was
+// created.
+func (p *PhaseTimer) Mark(name string) {
+	t := p.d.clock()
+	p.mu.Lock()
+	p.phases = append(p.phases, phase{name: name, t: t})
+	p.mu.Unlock()
+}
+
+// Report prints a table of all phases, longest first, followed by the total.
+// It only prints when debug is enabled and the bit of the timer is enabled in
+// the mask.
+func (p *PhaseTimer) Report() {
+	if !p.d.wantedM(p.bit) {
+		return
+	}
+
+	type row struct {
+		name string
+		dur  time.Duration
+	}
+	p.mu.Lock()
+	rows := make([]row, 0, len(p.phases))
+	last := p.start
+	for _, ph := range p.phases {
+		rows = append(rows, row{name: ph.name, dur: ph.t.Sub(last)})
+		last = ph.t
+	}
+	total := last.Sub(p.start)
+	p.mu.Unlock()
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].dur > rows[j].dur
+	})
+	width := len("total")
+	for _, r := range rows {
+		if len(r.name) > width {
+			width = len(r.name)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("phases:")
+	for _, r := range rows {
+		fmt.Fprintf(&b, "\n  %-*v %v", width, r.name, r.dur)
+	}
+	fmt.Fprintf(&b, "\n  %-*v %v", width, "total", total)
+	p.d.output(2, LevelDebug, p.bit, b.String())
+}
@@ -0,0 +1,76 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPhaseTimer(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []time.Duration // time spent before each mark
+		marks []string
+		want  string
+	}{
+		{
+			name: "sorted",
+			steps: []time.Duration{
+				time.Second,
+				3 * time.Second,
+				2 * time.Second,
+			},
+			marks: []string{"parse", "query", "render"},
+			want: "phases:\n" +
+				"  query  3s\n" +
+				"  render 2s\n" +
+				"  parse  1s\n" +
+				"  total  6s\n",
+		},
+		{
+			name:  "long name",
+			steps: []time.Duration{time.Millisecond},
+			marks: []string{"connect"},
+			want: "phases:\n" +
+				"  connect 1ms\n" +
+				"  total   1ms\n",
+		},
+		{
+			name: "no phases",
+			want: "phases:\n  total 0s\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 1)
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			d.SetTimeFunc(func() time.Time { return now })
+			p := d.NewPhaseTimer(1)
+			for i, step := range tt.steps {
+				now = now.Add(step)
+				p.Mark(tt.marks[i])
+			}
+			p.Report()
+			if got := b.String(); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pretty renders arbitrary values over multiple lines.  Pointers, maps and
+// slices that are already being rendered further up are printed as <cycle>
+// which makes it safe to use on cyclic data structures.
+type pretty struct {
+	b        strings.Builder
+	visited  map[uintptr]bool // pointers on the current path
+	maxDepth int              // nesting limit, 0 for none
+	types    bool             // show pointer addresses and scalar types
+}
+
+// address returns the pointer that identifies v for cycle detection.
+func address(v reflect.Value) (uintptr, bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map:
+		return v.Pointer(), !v.IsNil()
+	case reflect.Slice:
+		return v.Pointer(), v.Len() > 0
+	}
+	return 0, false
+}
+
+// indent starts a new line at depth.
+func (p *pretty) indent(depth int) {
+	p.b.WriteByte('\n')
+	p.b.WriteString(strings.Repeat("  ", depth))
+}
+
+// opaque renders v as its type and, when available, its address.
+func (p *pretty) opaque(v reflect.Value) {
+	if v.CanAddr() {
+		fmt.Fprintf(&p.b, "<%v @%#x>", v.Type(), v.UnsafeAddr())
+		return
+	}
+	fmt.Fprintf(&p.b, "<%v>", v.Type())
+}
+
+// elided reports whether the contents of v at depth are beyond the nesting
+// limit and, if so, renders v as T{...}.
+func (p *pretty) elided(v reflect.Value, depth int) bool {
+	if p.maxDepth == 0 || depth < p.maxDepth {
+		return false
+	}
+	p.b.WriteString(v.Type().String())
+	p.b.WriteString("{...}")
+	return true
+}
+
+// value renders v at depth.
+func (p *pretty) value(v reflect.Value, depth int) {
+	if !v.IsValid() {
+		p.b.WriteString("nil")
+		return
+	}
+	if a, ok := address(v); ok {
+		if p.visited[a] {
+			p.b.WriteString("<cycle>")
+			return
+		}
+		p.visited[a] = true
+		defer delete(p.visited, a)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			if p.types {
+				fmt.Fprintf(&p.b, "(%v)(nil)", v.Type())
+				return
+			}
+			p.b.WriteString("nil")
+			return
+		}
+		if p.types {
+			fmt.Fprintf(&p.b, "(%v @%#x) ", v.Type(), v.Pointer())
+		} else {
+			p.b.WriteByte('&')
+		}
+		p.value(v.Elem(), depth)
+
+	case reflect.Interface:
+		if v.IsNil() {
+			p.b.WriteString("nil")
+			return
+		}
+		p.value(v.Elem(), depth)
+
+	case reflect.Struct:
+		if v.NumField() > 0 && p.elided(v, depth) {
+			return
+		}
+		t := v.Type()
+		p.b.WriteString(t.String())
+		p.b.WriteByte('{')
+		for i := 0; i < v.NumField(); i++ {
+			f := t.Field(i)
+			p.indent(depth + 1)
+			p.b.WriteString(f.Name)
+			p.b.WriteString(": ")
+			if f.IsExported() {
+				p.value(v.Field(i), depth+1)
+			} else {
+				p.opaque(v.Field(i))
+			}
+			p.b.WriteByte(',')
+		}
+		if v.NumField() > 0 {
+			p.indent(depth)
+		}
+		p.b.WriteByte('}')
+
+	case reflect.Map:
+		if v.IsNil() {
+			p.b.WriteString("nil")
+			return
+		}
+		if v.Len() > 0 && p.elided(v, depth) {
+			return
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		p.b.WriteString(v.Type().String())
+		p.b.WriteByte('{')
+		for _, k := range keys {
+			p.indent(depth + 1)
+			p.value(k, depth+1)
+			p.b.WriteString(": ")
+			p.value(v.MapIndex(k), depth+1)
+			p.b.WriteByte(',')
+		}
+		if len(keys) > 0 {
+			p.indent(depth)
+		}
+		p.b.WriteByte('}')
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			p.b.WriteString("nil")
+			return
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			fmt.Fprintf(&p.b, "%v(%q)", v.Type(), bytesOf(v))
+			return
+		}
+		if v.Len() > 0 && p.elided(v, depth) {
+			return
+		}
+		p.b.WriteString(v.Type().String())
+		p.b.WriteByte('{')
+		for i := 0; i < v.Len(); i++ {
+			p.indent(depth + 1)
+			p.value(v.Index(i), depth+1)
+			p.b.WriteByte(',')
+		}
+		if v.Len() > 0 {
+			p.indent(depth)
+		}
+		p.b.WriteByte('}')
+
+	case reflect.String:
+		if p.types {
+			fmt.Fprintf(&p.b, "%v(%q)", v.Type(), v.String())
+			return
+		}
+		p.b.WriteString(strconv.Quote(v.String()))
+
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if v.IsNil() {
+			p.b.WriteString("nil")
+			return
+		}
+		fmt.Fprintf(&p.b, "<%v %#x>", v.Type(), v.Pointer())
+
+	default:
+		if !v.CanInterface() {
+			p.opaque(v)
+			return
+		}
+		if p.types {
+			fmt.Fprintf(&p.b, "%v(%v)", v.Type(), v.Interface())
+			return
+		}
+		fmt.Fprintf(&p.b, "%v", v.Interface())
+	}
+}
+
+// bytesOf returns the contents of a byte slice or array.
+func bytesOf(v reflect.Value) []byte {
+	b := make([]byte, v.Len())
+	for i := range b {
+		b[i] = byte(v.Index(i).Uint())
+	}
+	return b
+}
+
+// prettyString renders v with pretty.
+func prettyString(v interface{}) string {
+	p := pretty{visited: make(map[uintptr]bool)}
+	p.value(reflect.ValueOf(v), 0)
+	return p.b.String()
+}
+
+// spewString renders v with pretty showing types and addresses and limiting
+// the nesting to maxDepth levels, 0 for no limit.
+func spewString(v interface{}, maxDepth int) string {
+	p := pretty{
+		visited:  make(map[uintptr]bool),
+		maxDepth: maxDepth,
+		types:    true,
+	}
+	p.value(reflect.ValueOf(v), 0)
+	return p.b.String()
+}
+
+// SetSpewDepth limits the nesting DebugSpew prints to n levels, deeper
+// structs, maps, slices and arrays are printed as T{...}.  0 removes the
+// limit.
+func (d *DbgLogger) SetSpewDepth(n int) {
+	if n < 0 {
+		n = 0
+	}
+	d.mu.Lock()
+	d.spewDepth = n
+	d.mu.Unlock()
+}
+
+// DebugSpew prints label=v like DebugfPretty but also shows the type of every
+// value and the address of every pointer, and stops at the depth set with
+// SetSpewDepth.  It only prints when debug is enabled and bit is enabled in the
+// mask.
+func (d *DbgLogger) DebugSpew(bit uint64, label string, v interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if !d.wantedM(bit) {
+		return
+	}
+
+	d.mu.Lock()
+	depth := d.spewDepth
+	d.mu.Unlock()
+
+	d.output(2, LevelDebug, bit, label+"="+spewString(v, depth))
+}
+
+// DebugfPretty prints label=v with v rendered over multiple lines.  Pointers
+// are followed and cycles are detected and printed as <cycle>.  Unexported
+// fields are printed as their type and address only.  It only prints when
+// debug is enabled and bit is enabled in the mask.
+func (d *DbgLogger) DebugfPretty(bit uint64, label string, v interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wantedM(bit) {
+		d.output(2, LevelDebug, bit, label+"="+prettyString(v))
+	}
+}
@@ -0,0 +1,187 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"testing"
+)
+
+type prettySelf struct {
+	Name string
+	Self *prettySelf
+}
+
+type prettyNode struct {
+	V          int
+	Prev, Next *prettyNode
+}
+
+type prettyHidden struct {
+	n   int
+	Pub string
+}
+
+type prettyPair struct {
+	A, B *prettySelf
+}
+
+func TestDebugfPretty(t *testing.T) {
+	self := &prettySelf{Name: "a"}
+	self.Self = self
+
+	head := &prettyNode{V: 1}
+	head.Next = &prettyNode{V: 2, Prev: head}
+
+	m := map[string]interface{}{}
+	m["m"] = m
+
+	shared := &prettySelf{Name: "x"}
+
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{
+			name: "self referential",
+			v:    self,
+			want: "v=&dbglog.prettySelf{\n" +
+				"  Name: \"a\",\n" +
+				"  Self: <cycle>,\n" +
+				"}\n",
+		},
+		{
+			name: "doubly linked list",
+			v:    head,
+			want: "v=&dbglog.prettyNode{\n" +
+				"  V: 1,\n" +
+				"  Prev: nil,\n" +
+				"  Next: &dbglog.prettyNode{\n" +
+				"    V: 2,\n" +
+				"    Prev: <cycle>,\n" +
+				"    Next: nil,\n" +
+				"  },\n" +
+				"}\n",
+		},
+		{
+			name: "map",
+			v:    m,
+			want: "v=map[string]interface {}{\n" +
+				"  \"m\": <cycle>,\n" +
+				"}\n",
+		},
+		{
+			name: "unexported",
+			v:    prettyHidden{n: 1, Pub: "x"},
+			want: "v=dbglog.prettyHidden{\n" +
+				"  n: <int>,\n" +
+				"  Pub: \"x\",\n" +
+				"}\n",
+		},
+		{
+			name: "shared is not a cycle",
+			v:    prettyPair{A: shared, B: shared},
+			want: "v=dbglog.prettyPair{\n" +
+				"  A: &dbglog.prettySelf{\n" +
+				"    Name: \"x\",\n" +
+				"    Self: nil,\n" +
+				"  },\n" +
+				"  B: &dbglog.prettySelf{\n" +
+				"    Name: \"x\",\n" +
+				"    Self: nil,\n" +
+				"  },\n" +
+				"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 1)
+			d.DebugfPretty(1, "v", tt.v)
+			if got := b.String(); got != tt.want {
+				t.Fatalf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDebugSpew(t *testing.T) {
+	self := &prettySelf{Name: "a"}
+	self.Self = self
+	at := fmt.Sprintf("%p", self)
+
+	m := map[string]interface{}{}
+	m["m"] = m
+
+	tests := []struct {
+		name  string
+		mask  uint64
+		depth int
+		v     interface{}
+		want  string
+	}{
+		{
+			name: "cyclic pointer",
+			mask: 1,
+			v:    self,
+			want: "v=(*dbglog.prettySelf @" + at + ") " +
+				"dbglog.prettySelf{\n" +
+				"  Name: string(\"a\"),\n" +
+				"  Self: <cycle>,\n" +
+				"}\n",
+		},
+		{
+			name: "cyclic map",
+			mask: 1,
+			v:    m,
+			want: "v=map[string]interface {}{\n" +
+				"  string(\"m\"): <cycle>,\n" +
+				"}\n",
+		},
+		{
+			name:  "depth",
+			mask:  1,
+			depth: 1,
+			v:     m,
+			want: "v=map[string]interface {}{\n" +
+				"  string(\"m\"): <cycle>,\n" +
+				"}\n",
+		},
+		{
+			name:  "depth elides",
+			mask:  1,
+			depth: 1,
+			v:     []prettyPair{{}},
+			want: "v=[]dbglog.prettyPair{\n" +
+				"  dbglog.prettyPair{...},\n" +
+				"}\n",
+		},
+		{name: "masked", v: self},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, tt.mask)
+			d.SetSpewDepth(tt.depth)
+			d.DebugSpew(1, "v", tt.v)
+			if got := b.String(); got != tt.want {
+				t.Fatalf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
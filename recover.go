@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import "fmt"
+
+// Recover recovers from a panic and prints the panic value followed by the
+// stack trace of the panicking goroutine when debug is enabled and bit is
+// enabled in the mask.  It must be deferred directly:
+//
+//	go func() {
+//		defer d.Recover(myDebugWorker)
+//		work()
+//	}()
+//
+// The panic is swallowed, use RecoverRepanic to let it continue.  So that it
+// never goes unnoticed, it is printed at LevelError when debug or bit is
+// disabled.
+func (d *DbgLogger) Recover(bit uint64) {
+	if r := recover(); r != nil {
+		d.recovered(bit, r, true)
+	}
+}
+
+// RecoverRepanic is Recover but panics again with the same value after
+// printing it.  It only prints when debug and bit are enabled, the runtime
+// reports the panic otherwise.  It must be deferred directly.
+func (d *DbgLogger) RecoverRepanic(bit uint64) {
+	if r := recover(); r != nil {
+		d.recovered(bit, r, false)
+		panic(r)
+	}
+}
+
+// recovered prints the panic value r and the stack trace as a debug message
+// or, if the panic is swallowed, at LevelError when bit is disabled.
+func (d *DbgLogger) recovered(bit uint64, r interface{}, swallowed bool) {
+	l := LevelDebug
+	switch {
+	case d.wantedM(bit):
+	case swallowed && d.levelOK(LevelError, bit):
+		l = LevelError
+	default:
+		return
+	}
+	// Skip stack, recovered and Recover, the trace starts at panic.
+	s := d.filterStack(skipFrames(stack(false), 3))
+	d.output(3, l, bit, fmt.Sprintf("panic: %v\n%s", r, s))
+}
@@ -0,0 +1,87 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		mask    uint64
+		level   Level
+		want    string // first line, empty if nothing is printed
+	}{
+		{name: "enabled", enabled: true, mask: 1, want: "panic: boom"},
+		{name: "masked", enabled: true, want: "[ERROR] panic: boom"},
+		{name: "disabled", mask: 1, want: "[ERROR] panic: boom"},
+		{name: "disabled filtered", mask: 1, level: LevelFatal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, tt.mask)
+			if !tt.enabled {
+				d.Disable()
+			}
+			d.SetLevel(tt.level)
+			func() {
+				defer d.Recover(1)
+				panic("boom")
+			}()
+			got := b.String()
+			if tt.want == "" {
+				if got != "" {
+					t.Fatalf("got %q", got)
+				}
+				return
+			}
+			if !strings.HasPrefix(got, tt.want+"\n") ||
+				!strings.Contains(got, "recover_test.go") {
+				t.Fatalf("got %q, want %q and the stack", got,
+					tt.want)
+			}
+		})
+	}
+}
+
+func TestRecoverRepanic(t *testing.T) {
+	for _, mask := range []uint64{0, 1} {
+		d, b := newTestLogger("", 0, mask)
+		var r interface{}
+		func() {
+			defer func() { r = recover() }()
+			defer d.RecoverRepanic(1)
+			panic("boom")
+		}()
+		if r != "boom" {
+			t.Fatalf("mask %v: got %v, want boom", mask, r)
+		}
+		got := b.String()
+		if mask == 0 && got != "" {
+			t.Fatalf("mask %v: got %q", mask, got)
+		}
+		if mask == 1 && (!strings.HasPrefix(got, "panic: boom\n") ||
+			!strings.Contains(got, "recover_test.go")) {
+			t.Fatalf("mask %v: got %q", mask, got)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import "regexp"
+
+// Redactor scrubs sensitive data from messages, see AddCustomRedactor.
+type Redactor interface {
+	// Redact returns b with the sensitive data replaced.  It may modify b
+	// in place.
+	Redact(b []byte) []byte
+}
+
+// regexpRedactor replaces the matches of a regular expression.
+type regexpRedactor struct {
+	re   *regexp.Regexp
+	repl []byte
+}
+
+func (r regexpRedactor) Redact(b []byte) []byte {
+	return r.re.ReplaceAll(b, r.repl)
+}
+
+// AddRedactor replaces the matches of re in every printed message with
+// replacement, which may refer to submatches as in regexp.Expand, i.e.
+//
+//	d.AddRedactor(regexp.MustCompile(`(password=)\S+`), "${1}***")
+//
+// See AddCustomRedactor.
+func (d *DbgLogger) AddRedactor(re *regexp.Regexp, replacement string) {
+	d.AddCustomRedactor(regexpRedactor{re: re, repl: []byte(replacement)})
+}
+
+// AddCustomRedactor adds r to the redactors.  Redactors run in the order they
+// were added on the message and the fields of every entry before it is
+// rendered, so outputs, backends, hooks and the ring buffer only see the
+// redacted data.  Redacted fields become strings.  The log.Logger functions,
+// i.e. Printf, write directly to the output and are not redacted.
+func (d *DbgLogger) AddCustomRedactor(r Redactor) {
+	d.mu.Lock()
+	d.redactors = append(d.redactors, r)
+	d.mu.Unlock()
+}
+
+// ClearRedactors removes all redactors.
+func (d *DbgLogger) ClearRedactors() {
+	d.mu.Lock()
+	d.redactors = nil
+	d.mu.Unlock()
+}
+
+// redact returns b scrubbed by all redactors.  Must be called with mu held.
+func (d *DbgLogger) redact(b []byte) []byte {
+	for _, r := range d.redactors {
+		b = r.Redact(b)
+	}
+	return b
+}
+
+// redactEntry scrubs the message and the fields of e.  Fields whose value
+// changes are replaced with their redacted string.  Must be called with mu
+// held.
+func (d *DbgLogger) redactEntry(e *Entry) {
+	e.Message = string(d.redact([]byte(e.Message)))
+	var fields []Field
+	for i, f := range e.Fields {
+		s := fmtValue(f.Value)
+		r := string(d.redact([]byte(s)))
+		if r == s {
+			continue
+		}
+		if fields == nil {
+			// The fields may be shared with the caller.
+			fields = append([]Field(nil), e.Fields...)
+		}
+		fields[i].Value = r
+	}
+	if fields != nil {
+		e.Fields = fields
+	}
+}
@@ -0,0 +1,103 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAddRedactor(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    Format
+		multiline MultilineMode
+	}{
+		{name: "text", format: FormatText},
+		{
+			name:      "text prefix",
+			format:    FormatText,
+			multiline: MultilinePrefix,
+		},
+		{
+			name:      "text indent",
+			format:    FormatText,
+			multiline: MultilineIndent,
+		},
+		{name: "json", format: FormatJSON},
+		{name: "logfmt", format: FormatLogfmt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := formatLogger(tt.format, "p ", 1)
+			d.SetMultilineMode(tt.multiline)
+			d.SetRingSize(8)
+			var hooked []Entry
+			d.AddHook(func(e Entry) { hooked = append(hooked, e) })
+			d.AddRedactor(regexp.MustCompile(`(password=)\S+`),
+				"${1}***")
+			fields := []interface{}{
+				"cred", "password=hunter2",
+				"n", 1,
+			}
+			d.Debugw("login password=hunter2\n"+
+				"retry password=hunter2", fields...)
+			d.DebugfM(2, "ring only password=hunter2")
+
+			if fields[1] != "password=hunter2" {
+				t.Fatalf("caller fields modified: %v", fields)
+			}
+			got := b.String()
+			if strings.Contains(got, "hunter2") ||
+				strings.Count(got, "password=***") != 3 {
+				t.Fatalf("output not redacted: %q", got)
+			}
+			var ring bytes.Buffer
+			d.DumpRing(&ring)
+			r := ring.String()
+			if strings.Contains(r, "hunter2") ||
+				strings.Count(r, "password=***") != 4 {
+				t.Fatalf("ring not redacted: %q", r)
+			}
+			if len(hooked) != 1 {
+				t.Fatalf("got %v hooked entries", len(hooked))
+			}
+			e := hooked[0]
+			msg := "login password=***\nretry password=***"
+			if e.Message != msg ||
+				e.Fields[0].Value != "password=***" ||
+				e.Fields[1].Value != 1 {
+				t.Fatalf("hook entry not redacted: %+v", e)
+			}
+		})
+	}
+}
+
+func TestClearRedactors(t *testing.T) {
+	d, b := newTestLogger("", 0, 1)
+	d.AddRedactor(regexp.MustCompile(`secret`), "***")
+	d.Debugf("secret")
+	d.ClearRedactors()
+	d.Debugf("secret")
+	if got := b.String(); got != "***\nsecret\n" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"io"
+)
+
+// SetRingSize makes the logger keep the last n rendered lines in memory.  While
+// the ring is in use every debug message is rendered and captured, even when
+// debug is disabled or its bit is not enabled in the mask, so that DumpRing
+// can provide the context leading up to an error.  A size of 0 stops capturing
+// and discards the ring.
+func (d *DbgLogger) SetRingSize(n int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if n <= 0 {
+		d.ring = nil
+		d.ringNext = 0
+		d.ringOn.Store(false)
+		return
+	}
+	ring := make([][]byte, 0, n)
+	ring = append(ring, d.ringLines()...)
+	if len(ring) > n {
+		ring = ring[len(ring)-n:]
+	}
+	d.ring = ring[:len(ring):n]
+	d.ringNext = len(ring) % n
+	d.ringOn.Store(true)
+}
+
+// capture adds a copy of line to the ring.  Must be called with mu held.
+func (d *DbgLogger) capture(line []byte) {
+	l := append([]byte(nil), line...)
+	if len(d.ring) < cap(d.ring) {
+		d.ring = append(d.ring, l)
+	} else {
+		d.ring[d.ringNext] = l
+	}
+	d.ringNext = (d.ringNext + 1) % cap(d.ring)
+}
+
+// ringLines returns the lines in the ring, oldest first.  Must be called with
+// mu held.
+func (d *DbgLogger) ringLines() [][]byte {
+	if len(d.ring) < cap(d.ring) {
+		return append([][]byte(nil), d.ring...)
+	}
+	lines := append([][]byte(nil), d.ring[d.ringNext:]...)
+	return append(lines, d.ring[:d.ringNext]...)
+}
+
+// DumpRing writes the lines in the ring, oldest first, to w and empties the
+// ring.  It is meant to be called when an error occurs.
+func (d *DbgLogger) DumpRing(w io.Writer) error {
+	d.mu.Lock()
+	lines := d.ringLines()
+	if d.ring != nil {
+		d.ring = d.ring[:0]
+		d.ringNext = 0
+	}
+	d.mu.Unlock()
+
+	for _, l := range lines {
+		if _, err := w.Write(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
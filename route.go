@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"io"
+)
+
+// route sends messages of at least level that carry any of the bits in bit to
+// w.  A zero bit matches all messages, including the ones without a bit.
+// bitOutput marks the rules added by SetBitOutput.
+type route struct {
+	level     Level
+	bit       uint64
+	w         io.Writer
+	bitOutput bool
+}
+
+// SetRoute adds a routing rule that sends messages of level or higher with any
+// of the bits in bit set to w.  A bit of 0 matches every message regardless of
+// its bit, messages printed by the non-mask functions, such as Debugf, carry no
+// bit and therefore only match such rules.
+//
+// Rules are checked in the order they were added and the first match wins.
+// Messages that match no rule are written to the output of the embedded
+// log.Logger.
+func (d *DbgLogger) SetRoute(level Level, bit uint64, w io.Writer) {
+	d.mu.Lock()
+	d.routes = append(d.routes, route{level: level, bit: bit, w: w})
+	d.mu.Unlock()
+}
+
+// ClearRoutes removes all routing rules added by SetRoute.  The rules added by
+// SetBitOutput are kept, use SetBitOutput with a nil writer to remove them.
+func (d *DbgLogger) ClearRoutes() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var routes []route
+	for _, r := range d.routes {
+		if r.bitOutput {
+			routes = append(routes, r)
+		}
+	}
+	d.routes = routes
+}
+
+// route returns the writer for a message and whether a rule selected it.  Must
+// be called with mu held.
+func (d *DbgLogger) route(level Level, bit uint64) (io.Writer, bool) {
+	for _, r := range d.routes {
+		if level < r.level {
+			continue
+		}
+		if r.bit != 0 && bit&r.bit == 0 {
+			continue
+		}
+		return r.w, true
+	}
+	return d.Logger.Writer(), false
+}
+
+// SetBitOutput sends messages with any of the bits in bit set to w, i.e. to put
+// a chatty category in its own file.  It is shorthand for a SetRoute rule at
+// LevelTrace except that it replaces an earlier SetBitOutput for the same bit
+// instead of adding another rule.  A nil w removes the rule.  Rules added by
+// SetRoute are never replaced or removed.
+func (d *DbgLogger) SetBitOutput(bit uint64, w io.Writer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i, r := range d.routes {
+		if !r.bitOutput || r.bit != bit {
+			continue
+		}
+		if w == nil {
+			d.routes = append(d.routes[:i], d.routes[i+1:]...)
+		} else {
+			d.routes[i].w = w
+		}
+		return
+	}
+	if w != nil {
+		d.routes = append(d.routes, route{level: LevelTrace, bit: bit,
+			w: w, bitOutput: true})
+	}
+}
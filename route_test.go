@@ -0,0 +1,117 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSetRoute(t *testing.T) {
+	var warn, net, other bytes.Buffer
+	tests := []struct {
+		name  string
+		print func(d *DbgLogger)
+		want  *bytes.Buffer // nil for the default output
+	}{
+		{
+			"level first",
+			func(d *DbgLogger) { d.output(1, LevelWarn, 1, "x") },
+			&warn,
+		},
+		{"bit", func(d *DbgLogger) { d.DebugfM(1, "x") }, &net},
+		{"overlap", func(d *DbgLogger) { d.DebugfM(2, "x") }, &other},
+		{"no rule", func(d *DbgLogger) { d.DebugfM(4, "x") }, nil},
+		{"no bit", func(d *DbgLogger) { d.Debugf("x") }, nil},
+		{
+			"below level",
+			func(d *DbgLogger) { d.output(1, LevelInfo, 0, "x") },
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warn.Reset()
+			net.Reset()
+			other.Reset()
+			d, def := newTestLogger("", 0, 1|2|4)
+			d.SetRoute(LevelWarn, 0, &warn)
+			d.SetRoute(LevelTrace, 1, &net)
+			d.SetRoute(LevelTrace, 1|2, &other)
+
+			tt.print(d)
+			want := tt.want
+			if want == nil {
+				want = def
+			}
+			for _, b := range []*bytes.Buffer{&warn, &net, &other,
+				def} {
+				if got := b.Len() != 0; got != (b == want) {
+					t.Fatalf("wrong output: warn %q "+
+						"net %q other %q default %q",
+						warn.String(), net.String(),
+						other.String(), def.String())
+				}
+			}
+		})
+	}
+}
+
+func TestClearRoutes(t *testing.T) {
+	var w bytes.Buffer
+	d, def := newTestLogger("", 0, 1)
+	d.SetRoute(LevelTrace, 0, &w)
+	d.ClearRoutes()
+	d.DebugfM(1, "x")
+	if w.Len() != 0 || def.Len() == 0 {
+		t.Fatalf("route not cleared: %q %q", w.String(), def.String())
+	}
+}
+
+func TestClearRoutesBitOutput(t *testing.T) {
+	var user, file bytes.Buffer
+	d, def := newTestLogger("", 0, 3)
+	d.SetRoute(LevelTrace, 0, &user)
+	d.SetBitOutput(2, &file)
+	d.ClearRoutes()
+	d.DebugfM(1, "a")
+	d.DebugfM(2, "b")
+	if user.Len() != 0 || def.String() != "a\n" ||
+		file.String() != "b\n" {
+		t.Fatalf("got %q %q %q", user.String(), def.String(),
+			file.String())
+	}
+}
+
+func TestSetBitOutput(t *testing.T) {
+	var user, file, again bytes.Buffer
+	d, _ := newTestLogger("", 0, 1)
+	d.SetRoute(LevelTrace, 1, &user)
+	d.SetBitOutput(1, &file)
+	d.SetBitOutput(1, &again)
+	d.SetBitOutput(1, nil)
+	d.DebugfM(1, "x")
+	if user.Len() == 0 || file.Len() != 0 || again.Len() != 0 {
+		t.Fatalf("SetBitOutput touched a SetRoute rule: %q %q %q",
+			user.String(), file.String(), again.String())
+	}
+	if len(d.routes) != 1 {
+		t.Fatalf("got %v rules, want 1", len(d.routes))
+	}
+}
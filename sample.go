@@ -0,0 +1,53 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import "sync/atomic"
+
+// sampler prints one in every messages.
+type sampler struct {
+	every uint64
+	n     atomic.Uint64
+}
+
+// SetSample makes the logger print only one in every debug messages for bit,
+// starting with the first.  The count is shared with the loggers derived
+// afterwards with Sub.  An every of 1 or less prints all messages again.
+// Messages that are sampled out are still captured by the ring.
+func (d *DbgLogger) SetSample(bit uint64, every int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if every <= 1 {
+		delete(d.samples, bit)
+		return
+	}
+	if d.samples == nil {
+		d.samples = make(map[uint64]*sampler)
+	}
+	d.samples[bit] = &sampler{every: uint64(every)}
+}
+
+// sampled reports whether a message for bit is to be printed.  Must be called
+// with mu held.
+func (d *DbgLogger) sampled(bit uint64) bool {
+	s, ok := d.samples[bit]
+	if !ok {
+		return true
+	}
+	return (s.n.Add(1)-1)%s.every == 0
+}
@@ -0,0 +1,104 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package dbglog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSetSample(t *testing.T) {
+	tests := []struct {
+		name  string
+		every int
+		n     int
+		want  []string
+	}{
+		{"every 3", 3, 10, []string{"0", "3", "6", "9"}},
+		{"every 2", 2, 5, []string{"0", "2", "4"}},
+		{"every 1", 1, 3, []string{"0", "1", "2"}},
+		{"off", 0, 3, []string{"0", "1", "2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 0x6)
+			d.SetSample(4, tt.every)
+			for i := 0; i < tt.n; i++ {
+				d.DebugfM(4, "%v", i)
+			}
+			got := strings.Join(lines(b), " ")
+			if want := strings.Join(tt.want, " "); got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+
+			// Other bits are not sampled.
+			b.Reset()
+			for i := 0; i < 3; i++ {
+				d.DebugfM(2, "%v", i)
+			}
+			if got := strings.Join(lines(b), " "); got != "0 1 2" {
+				t.Fatalf("bit 2: got %q", got)
+			}
+		})
+	}
+}
+
+func TestSetSampleReset(t *testing.T) {
+	d, b := newTestLogger("", 0, 4)
+	d.SetSample(4, 100)
+	d.SetSample(4, 1)
+	for i := 0; i < 3; i++ {
+		d.DebugfM(4, "%v", i)
+	}
+	if got := strings.Join(lines(b), " "); got != "0 1 2" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestSetSampleSub(t *testing.T) {
+	d, b := newTestLogger("", 0, 4)
+	d.SetSample(4, 2)
+	s := d.Sub("sub ")
+	d.DebugfM(4, "a")
+	s.DebugfM(4, "b")
+	s.DebugfM(4, "c")
+	d.DebugfM(4, "d")
+	if got := strings.Join(lines(b), "|"); got != "a|sub c" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestSetSampleRing(t *testing.T) {
+	d, b := newTestLogger("", 0, 4)
+	d.SetRingSize(10)
+	d.SetSample(4, 2)
+	for i := 0; i < 4; i++ {
+		d.DebugfM(4, "%v", i)
+	}
+	if got := strings.Join(lines(b), " "); got != "0 2" {
+		t.Fatalf("printed %q", got)
+	}
+	var ring bytes.Buffer
+	if err := d.DumpRing(&ring); err != nil {
+		t.Fatal(err)
+	}
+	if got := ring.String(); got != "0\n1\n2\n3\n" {
+		t.Fatalf("ring %q", got)
+	}
+}
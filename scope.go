@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"math/bits"
+	"time"
+)
+
+// afterFunc is time.AfterFunc, replaced by tests.
+var afterFunc = time.AfterFunc
+
+// scoped tracks the bits and enabled state turned on temporarily by WithMask
+// and WithEnabled.  Only what was turned on by them is turned off again, when
+// the last overlapping scope ends, so concurrent scopes do not undo each other
+// or changes made by SetMask and Enable.
+type scoped struct {
+	refs    [65]int // active scopes per bit, the last one for enabled
+	added   uint64  // bits that were set by a scope
+	enabled bool    // debug was enabled by a scope
+
+	enableTimer *time.Timer // pending EnableFor revert
+	prevEnabled bool        // enabled state before EnableFor
+	maskTimer   *time.Timer // pending SetMaskFor revert
+	prevMask    uint64      // mask before SetMaskFor
+}
+
+// WithMask calls fn with extra added to the mask and afterwards clears the
+// bits of extra that were not set before, i.e. to trace a single request:
+//
+//	d.WithMask(bitRPC|bitDB, func() { handle(req) })
+//
+// Debug must be enabled for the bits to print, see WithEnabled.  Since the
+// mask is shared the bits print for all goroutines while fn runs.
+func (d *DbgLogger) WithMask(extra uint64, fn func()) {
+	d.scopeMask(extra, true)
+	defer d.scopeMask(extra, false)
+	fn()
+}
+
+// WithEnabled calls fn with debug enabled and afterwards disables it again
+// unless it was enabled before.
+func (d *DbgLogger) WithEnabled(fn func()) {
+	d.scopeEnabled(true)
+	defer d.scopeEnabled(false)
+	fn()
+}
+
+// scopeMask starts or ends a scope for the bits in extra.  The
+// OnConfigChange functions are called after tmu is released so that they may
+// start scopes themselves.
+func (d *DbgLogger) scopeMask(extra uint64, start bool) {
+	changed := false
+	d.tmu.Lock()
+	for b := extra; b != 0; b &= b - 1 {
+		i := bits.TrailingZeros64(b)
+		bit := uint64(1) << uint(i)
+		if start {
+			if d.scope.refs[i] == 0 && d.mask.Or(bit)&bit == 0 {
+				d.scope.added |= bit
+				changed = true
+			}
+			d.scope.refs[i]++
+			continue
+		}
+		d.scope.refs[i]--
+		if d.scope.refs[i] == 0 && d.scope.added&bit != 0 {
+			changed = d.mask.And(^bit)&bit != 0 || changed
+			d.scope.added &^= bit
+		}
+	}
+	d.tmu.Unlock()
+
+	if changed {
+		d.notify()
+	}
+}
+
+// scopeEnabled starts or ends a scope for the enabled state, see scopeMask.
+func (d *DbgLogger) scopeEnabled(start bool) {
+	changed := false
+	d.tmu.Lock()
+	if start {
+		if d.scope.refs[64] == 0 && !d.enabled.Swap(true) {
+			d.scope.enabled = true
+			changed = true
+		}
+		d.scope.refs[64]++
+	} else {
+		d.scope.refs[64]--
+		if d.scope.refs[64] == 0 && d.scope.enabled {
+			changed = d.enabled.Swap(false)
+			d.scope.enabled = false
+		}
+	}
+	d.tmu.Unlock()
+
+	if changed {
+		d.notify()
+	}
+}
+
+// EnableFor enables debug and disables it again after dur unless it was
+// enabled before, i.e. to make sure debugging enabled by an operator does not
+// stay on.  Calling it again while a revert is pending restarts the timeout
+// and keeps the state from before the first call.
+func (d *DbgLogger) EnableFor(dur time.Duration) {
+	d.tmu.Lock()
+	if d.scope.enableTimer != nil {
+		d.scope.enableTimer.Stop()
+	} else {
+		d.scope.prevEnabled = d.Enabled()
+	}
+	changed := !d.enabled.Swap(true)
+	var t *time.Timer
+	t = afterFunc(dur, func() {
+		changed := false
+		d.tmu.Lock()
+		if d.scope.enableTimer == t {
+			d.scope.enableTimer = nil
+			if !d.scope.prevEnabled {
+				changed = d.enabled.Swap(false)
+			}
+		}
+		d.tmu.Unlock()
+
+		if changed {
+			d.notify()
+		}
+	})
+	d.scope.enableTimer = t
+	d.tmu.Unlock()
+
+	if changed {
+		d.notify()
+	}
+}
+
+// SetMaskFor sets the mask and restores the previous one after dur.  Calling
+// it again while a restore is pending restarts the timeout and keeps the mask
+// from before the first call.
+func (d *DbgLogger) SetMaskFor(mask uint64, dur time.Duration) {
+	d.tmu.Lock()
+	if d.scope.maskTimer != nil {
+		d.scope.maskTimer.Stop()
+	} else {
+		d.scope.prevMask = d.GetMask()
+	}
+	changed := d.mask.Swap(mask) != mask
+	var t *time.Timer
+	t = afterFunc(dur, func() {
+		changed := false
+		d.tmu.Lock()
+		if d.scope.maskTimer == t {
+			d.scope.maskTimer = nil
+			prev := d.scope.prevMask
+			changed = d.mask.Swap(prev) != prev
+		}
+		d.tmu.Unlock()
+
+		if changed {
+			d.notify()
+		}
+	})
+	d.scope.maskTimer = t
+	d.tmu.Unlock()
+
+	if changed {
+		d.notify()
+	}
+}
@@ -0,0 +1,176 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithMask(t *testing.T) {
+	d, _ := newTestLogger("", 0, 1)
+	check := func(what string, want uint64) {
+		t.Helper()
+		if got := d.GetMask(); got != want {
+			t.Fatalf("%v: got mask %#x, want %#x", what, got, want)
+		}
+	}
+	d.WithMask(1|2, func() {
+		check("scope", 1|2)
+		d.WithMask(2|4, func() {
+			check("nested scope", 1|2|4)
+		})
+		check("after nested scope", 1|2)
+	})
+	check("after scope", 1)
+
+	func() {
+		defer func() { recover() }()
+		d.WithMask(2, func() { panic("boom") })
+	}()
+	check("after panic", 1)
+}
+
+func TestWithEnabled(t *testing.T) {
+	d, _ := newTestLogger("", 0, 0)
+	d.Disable()
+	check := func(what string, want bool) {
+		t.Helper()
+		if got := d.Enabled(); got != want {
+			t.Fatalf("%v: got enabled %v, want %v", what, got, want)
+		}
+	}
+	d.WithEnabled(func() {
+		check("scope", true)
+		d.WithEnabled(func() {
+			check("nested scope", true)
+		})
+		check("after nested scope", true)
+	})
+	check("after scope", false)
+
+	func() {
+		defer func() { recover() }()
+		d.WithEnabled(func() { panic("boom") })
+	}()
+	check("after panic", false)
+
+	d.Enable()
+	d.WithEnabled(func() {})
+	check("enabled before", true)
+}
+
+func TestScopeNotify(t *testing.T) {
+	d, _ := newTestLogger("", 0, 1)
+	d.Disable()
+	var calls []uint64
+	d.OnConfigChange(func(enabled bool, mask uint64) {
+		calls = append(calls, mask)
+		if len(calls) == 1 {
+			// Scopes may be used by the functions.
+			d.WithMask(8, func() {})
+		}
+	})
+	done := make(chan struct{})
+	go func() {
+		d.WithEnabled(func() {
+			d.WithMask(2, func() {})
+		})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadlock")
+	}
+	// Enabled, 8 set and cleared by the function, 2 set and cleared and
+	// disabled.
+	want := []uint64{1, 1 | 8, 1, 1 | 2, 1, 1}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("got %#x, want %#x", calls, want)
+	}
+}
+
+// fakeTimers replaces afterFunc with timers that only fire when the test calls
+// the returned functions.
+func fakeTimers(t *testing.T) *[]func() {
+	var fs []func()
+	afterFunc = func(dur time.Duration, f func()) *time.Timer {
+		fs = append(fs, f)
+		return time.NewTimer(time.Hour)
+	}
+	t.Cleanup(func() { afterFunc = time.AfterFunc })
+	return &fs
+}
+
+func TestEnableFor(t *testing.T) {
+	timers := fakeTimers(t)
+	d, _ := newTestLogger("", 0, 0)
+	d.Disable()
+	d.EnableFor(time.Minute)
+	if !d.Enabled() {
+		t.Fatal("not enabled")
+	}
+	d.EnableFor(time.Minute)
+	(*timers)[0]()
+	if !d.Enabled() {
+		t.Fatal("disabled by the superseded timer")
+	}
+	(*timers)[1]()
+	if d.Enabled() {
+		t.Fatal("not disabled after expiry")
+	}
+
+	// Debug that was enabled before stays enabled.
+	d.Enable()
+	d.EnableFor(time.Minute)
+	(*timers)[2]()
+	if !d.Enabled() {
+		t.Fatal("disabled after expiry")
+	}
+}
+
+func TestSetMaskFor(t *testing.T) {
+	timers := fakeTimers(t)
+	d, _ := newTestLogger("", 0, 1)
+	d.SetMaskFor(2, time.Minute)
+	if got := d.GetMask(); got != 2 {
+		t.Fatalf("got mask %#x, want 0x2", got)
+	}
+	d.SetMaskFor(4, time.Minute)
+	if got := d.GetMask(); got != 4 {
+		t.Fatalf("got mask %#x, want 0x4", got)
+	}
+	(*timers)[0]()
+	if got := d.GetMask(); got != 4 {
+		t.Fatalf("superseded timer: got mask %#x, want 0x4", got)
+	}
+	(*timers)[1]()
+	if got := d.GetMask(); got != 1 {
+		t.Fatalf("after expiry: got mask %#x, want 0x1", got)
+	}
+}
+
+func TestEnableForExpiry(t *testing.T) {
+	d, _ := newTestLogger("", 0, 0)
+	d.Disable()
+	d.EnableFor(time.Millisecond)
+	waitFor(t, "disabled", func() bool { return !d.Enabled() })
+}
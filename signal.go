@@ -0,0 +1,88 @@
+//go:build !windows && !plan9 && !js
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// HandleSignals installs signal handlers so that debugging can be controlled
+// in a running program.  SIGUSR1 toggles between Enable and Disable and
+// SIGUSR2 moves on to the next of masks, wrapping around.  The first SIGUSR2
+// sets the mask that follows the mask at the time of the call in masks, or
+// the first of masks when it is not one of them.  Without masks SIGUSR2
+// toggles between the mask at the time of the call and all bits.  Every change
+// is announced with Infof and thus only printed when the level allows it.  The
+// returned function removes the handlers, calling it again does nothing.
+func (d *DbgLogger) HandleSignals(masks ...uint64) (stop func()) {
+	if len(masks) == 0 {
+		masks = []uint64{d.GetMask(), DebugAll}
+	}
+
+	next := -1
+	for i, m := range masks {
+		if m == d.GetMask() {
+			next = i
+			break
+		}
+	}
+
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case s := <-c:
+				switch s {
+				case syscall.SIGUSR1:
+					if d.Enabled() {
+						d.Disable()
+						d.Infof("debug disabled")
+					} else {
+						d.Enable()
+						d.Infof("debug enabled")
+					}
+				case syscall.SIGUSR2:
+					next = (next + 1) % len(masks)
+					m := masks[next]
+					d.SetMask(m)
+					d.Infof("debug mask %v", maskString(m))
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+	}
+}
+
+// hangup returns the signals that make WatchConfig reload.
+func hangup() []os.Signal {
+	return []os.Signal{syscall.SIGHUP}
+}
@@ -0,0 +1,63 @@
+//go:build !windows && !plan9 && !js && !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestHandleSignals(t *testing.T) {
+	var b lockedBuffer
+	d := New(&b, "", 0)
+	d.SetMask(1)
+	d.SetLevel(LevelWarn)
+	stop := d.HandleSignals(1, 3)
+	defer stop()
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	waitFor(t, "enable", d.Enabled)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	waitFor(t, "mask", func() bool { return d.GetMask() == 3 })
+	if b.String() != "" {
+		t.Fatalf("notice printed below the level: %q", b.String())
+	}
+
+	d.SetLevel(LevelInfo)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	waitFor(t, "notice", func() bool {
+		return strings.Contains(b.String(), "debug disabled")
+	})
+	if d.Enabled() {
+		t.Fatal("still enabled")
+	}
+}
+
+func TestHandleSignalsFirstMask(t *testing.T) {
+	d := New(&lockedBuffer{}, "", 0)
+	d.SetMask(8)
+	stop := d.HandleSignals(2, 4)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	waitFor(t, "first mask", func() bool { return d.GetMask() == 2 })
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	waitFor(t, "second mask", func() bool { return d.GetMask() == 4 })
+	stop()
+	stop()
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+)
+
+// BitKey is the attribute key that carries the mask bit of a slog record, i.e.
+// slog.Debug("sent", dbglog.BitKey, myDebugNet).
+const BitKey = "bit"
+
+// Handler is a slog.Handler that prints through a DbgLogger.  slog levels below
+// slog.LevelDebug map to LevelTrace, the others to the matching Level.  Debug
+// records are controlled by Enable and, when they carry a BitKey attribute, by
+// the mask just like DebugfM.  Records of the other levels are filtered by
+// SetLevel or, when they carry a BitKey attribute, by SetBitLevel.  Records
+// with a zero time are stamped with the time of the logger.
+type Handler struct {
+	d      *DbgLogger
+	attrs  []Field
+	bit    uint64
+	groups string // group prefix of attribute keys, i.e. "g1.g2."
+}
+
+var _ slog.Handler = (*Handler)(nil)
+
+// NewHandler returns a slog.Handler that prints through d.
+func NewHandler(d *DbgLogger) *Handler {
+	return &Handler{d: d}
+}
+
+// toLevel maps a slog level to a Level.
+func toLevel(l slog.Level) Level {
+	switch {
+	case l < slog.LevelDebug:
+		return LevelTrace
+	case l < slog.LevelInfo:
+		return LevelDebug
+	case l < slog.LevelWarn:
+		return LevelInfo
+	case l < slog.LevelError:
+		return LevelWarn
+	}
+	return LevelError
+}
+
+// Enabled reports whether records of level may be printed.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	l := toLevel(level)
+	if l == LevelDebug {
+		return h.d.wanted()
+	}
+	return l >= h.d.minLevel()
+}
+
+// bitOf returns the mask bit carried by v.
+func bitOf(v slog.Value) (uint64, bool) {
+	switch v.Kind() {
+	case slog.KindUint64:
+		return v.Uint64(), true
+	case slog.KindInt64:
+		return uint64(v.Int64()), true
+	}
+	return 0, false
+}
+
+// appendAttr appends a as fields, flattening groups into dotted keys.  The mask
+// bit is returned instead of being appended.
+func appendAttr(fields []Field, bit *uint64, groups string,
+	a slog.Attr) []Field {
+	v := a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if v.Kind() == slog.KindGroup {
+		g := groups
+		if a.Key != "" {
+			g += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			fields = appendAttr(fields, bit, g, ga)
+		}
+		return fields
+	}
+	if groups == "" && a.Key == BitKey {
+		if b, ok := bitOf(v); ok {
+			*bit = b
+			return fields
+		}
+	}
+	return append(fields, Field{Key: groups + a.Key, Value: v.Any()})
+}
+
+// Handle prints r with the trace and span ids of ctx, see DebugfCtx.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	bit := h.bit
+	fields := append([]Field(nil), h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, &bit, h.groups, a)
+		return true
+	})
+
+	e := &Entry{
+		Time:    r.Time,
+		Level:   toLevel(r.Level),
+		Bit:     bit,
+		Message: r.Message,
+		Fields:  fields,
+	}
+	switch {
+	case e.Level == LevelDebug && bit != 0:
+		if !h.d.wantedM(bit) {
+			return nil
+		}
+	case e.Level == LevelDebug:
+		if !h.d.wanted() {
+			return nil
+		}
+	case e.Level != LevelDebug && !h.d.levelOK(e.Level, bit):
+		return nil
+	}
+	if ctx != nil {
+		e.Fields, _ = h.d.traceFields(ctx, e.Fields)
+	}
+	if r.PC != 0 {
+		f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		e.File, e.Line = f.File, f.Line
+	}
+	return h.d.emit(2, e)
+}
+
+// WithAttrs returns a Handler that adds attrs to every record.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	n := *h
+	n.attrs = append([]Field(nil), h.attrs...)
+	for _, a := range attrs {
+		n.attrs = appendAttr(n.attrs, &n.bit, h.groups, a)
+	}
+	return &n
+}
+
+// WithGroup returns a Handler that qualifies the keys of later attributes with
+// name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	n := *h
+	n.groups += name + "."
+	return &n
+}
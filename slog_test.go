@@ -0,0 +1,105 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package dbglog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/slogtest"
+)
+
+// slogResult converts the FormatJSON line in b into the map expected by
+// slogtest: the message is stored under slog.MessageKey and the dotted field
+// keys are nested into groups.
+func slogResult(t *testing.T, b *bytes.Buffer) map[string]any {
+	var line struct {
+		Time    string         `json:"time"`
+		Level   string         `json:"level"`
+		Message string         `json:"message"`
+		Fields  map[string]any `json:"fields"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &line); err != nil {
+		t.Fatalf("%q: %v", b.String(), err)
+	}
+	m := map[string]any{
+		slog.TimeKey:    line.Time,
+		slog.LevelKey:   line.Level,
+		slog.MessageKey: line.Message,
+	}
+	for k, v := range line.Fields {
+		g, keys := m, strings.Split(k, ".")
+		for _, key := range keys[:len(keys)-1] {
+			sub, ok := g[key].(map[string]any)
+			if !ok {
+				sub = map[string]any{}
+				g[key] = sub
+			}
+			g = sub
+		}
+		g[keys[len(keys)-1]] = v
+	}
+	return m
+}
+
+func TestHandlerSlogtest(t *testing.T) {
+	buffers := make(map[*testing.T]*bytes.Buffer)
+	slogtest.Run(t, func(t *testing.T) slog.Handler {
+		// Unlike slog.JSONHandler, a zero record time is not omitted.
+		if strings.HasSuffix(t.Name(), "/zero-time") {
+			t.Skip("zero record times are stamped")
+		}
+		d, b := newTestLogger("", 0, 0)
+		d.SetFormat(FormatJSON)
+		buffers[t] = b
+		return NewHandler(d)
+	}, func(t *testing.T) map[string]any {
+		return slogResult(t, buffers[t])
+	})
+}
+
+func TestHandlerFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		level slog.Level
+		args  []any
+		want  string
+	}{
+		{"debug", slog.LevelDebug, nil, "msg"},
+		{"debug bit", slog.LevelDebug, []any{BitKey, 4}, "msg"},
+		{"debug masked", slog.LevelDebug, []any{BitKey, 8}, ""},
+		{"trace", slog.LevelDebug - 4, nil, ""},
+		{"info", slog.LevelInfo, nil, "[INFO] msg"},
+		{"warn", slog.LevelWarn + 2, nil, "[WARN] msg"},
+		{"error", slog.LevelError, []any{"k", "v"}, "[ERROR] msg k=v"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 4)
+			slog.New(NewHandler(d)).Log(context.Background(),
+				tt.level, "msg", tt.args...)
+			if got := strings.TrimSuffix(b.String(), "\n"); got !=
+				tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
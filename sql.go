@@ -0,0 +1,460 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"time"
+)
+
+// WrapDriver returns a database/sql driver that prints the queries and
+// statements run through drv, with their arguments, the number of affected
+// rows, errors and timings, when debug is enabled and bit is enabled in the
+// mask, i.e.
+//
+//	sql.Register("postgres-traced", d.WrapDriver(&pq.Driver{}, myDebugSQL))
+//	db, err := sql.Open("postgres-traced", dsn)
+//
+// The connector of drv, if it implements driver.DriverContext, and the
+// argument checks and conversions of its connections and statements are used
+// as if drv was not wrapped.
+func (d *DbgLogger) WrapDriver(drv driver.Driver, bit uint64) driver.Driver {
+	return &sqlDriver{drv: drv, t: sqlTracer{d: d, bit: bit}}
+}
+
+// sqlTracer prints the operations of a wrapped driver.
+type sqlTracer struct {
+	d   *DbgLogger
+	bit uint64
+}
+
+// start returns the start time of an operation and whether it is printed.
+func (t sqlTracer) start() (time.Time, bool) {
+	if !t.d.wantedM(t.bit) {
+		return time.Time{}, false
+	}
+	return t.d.clock(), true
+}
+
+// done prints op on query with args, the affected rows of res, if any, and
+// err.
+func (t sqlTracer) done(start time.Time, op, query string,
+	args []driver.NamedValue, res driver.Result, err error) {
+	fields := make([]Field, 0, 4)
+	if len(args) != 0 {
+		v := make([]interface{}, len(args))
+		for i, a := range args {
+			v[i] = a.Value
+		}
+		fields = append(fields, Field{Key: "args", Value: v})
+	}
+	if res != nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			fields = append(fields, Field{Key: "rows", Value: n})
+		}
+	}
+	if err != nil {
+		fields = append(fields, Field{Key: "error", Value: err})
+	}
+	fields = append(fields, Field{Key: "took",
+		Value: t.d.clock().Sub(start)})
+	msg := op
+	if query != "" {
+		msg += " " + query
+	}
+	t.d.emit(3, &Entry{Level: LevelDebug, Bit: t.bit, Message: msg,
+		Fields: fields})
+}
+
+// values converts args for the drivers that do not take named values.
+func values(args []driver.NamedValue) ([]driver.Value, error) {
+	v := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errors.New("dbglog: driver does not " +
+				"support named parameters")
+		}
+		v[i] = a.Value
+	}
+	return v, nil
+}
+
+// named converts args to named values.
+func named(args []driver.Value) []driver.NamedValue {
+	n := make([]driver.NamedValue, len(args))
+	for i, a := range args {
+		n[i] = driver.NamedValue{Ordinal: i + 1, Value: a}
+	}
+	return n
+}
+
+type sqlDriver struct {
+	drv driver.Driver
+	t   sqlTracer
+}
+
+var _ driver.DriverContext = (*sqlDriver)(nil)
+
+func (s *sqlDriver) Open(name string) (driver.Conn, error) {
+	start, ok := s.t.start()
+	c, err := s.drv.Open(name)
+	return s.conn(start, ok, c, err)
+}
+
+// OpenConnector uses the connector of the wrapped driver if it has one and
+// Open otherwise, like database/sql does for a driver that is not wrapped.
+func (s *sqlDriver) OpenConnector(name string) (driver.Connector, error) {
+	dc, ok := s.drv.(driver.DriverContext)
+	if !ok {
+		return &sqlConnector{name: name, drv: s}, nil
+	}
+	c, err := dc.OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConnector{c: c, drv: s}, nil
+}
+
+// conn prints the open that started at start, if ok, and wraps c.
+func (s *sqlDriver) conn(start time.Time, ok bool, c driver.Conn,
+	err error) (driver.Conn, error) {
+	if ok {
+		s.t.done(start, "open", "", nil, nil, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{c: c, t: s.t}, nil
+}
+
+// sqlConnector opens connections with the connector c of the wrapped driver or,
+// when it has none, with Open and name.
+type sqlConnector struct {
+	c    driver.Connector
+	name string
+	drv  *sqlDriver
+}
+
+func (c *sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	start, ok := c.drv.t.start()
+	var (
+		conn driver.Conn
+		err  error
+	)
+	if c.c != nil {
+		conn, err = c.c.Connect(ctx)
+	} else {
+		conn, err = c.drv.drv.Open(c.name)
+	}
+	return c.drv.conn(start, ok, conn, err)
+}
+
+func (c *sqlConnector) Driver() driver.Driver {
+	return c.drv
+}
+
+func (c *sqlConnector) Close() error {
+	if cl, ok := c.c.(io.Closer); ok {
+		return cl.Close()
+	}
+	return nil
+}
+
+type sqlConn struct {
+	c driver.Conn
+	t sqlTracer
+}
+
+var (
+	_ driver.ConnPrepareContext = (*sqlConn)(nil)
+	_ driver.ConnBeginTx        = (*sqlConn)(nil)
+	_ driver.ExecerContext      = (*sqlConn)(nil)
+	_ driver.QueryerContext     = (*sqlConn)(nil)
+	_ driver.Pinger             = (*sqlConn)(nil)
+	_ driver.SessionResetter    = (*sqlConn)(nil)
+	_ driver.Validator          = (*sqlConn)(nil)
+	_ driver.NamedValueChecker  = (*sqlConn)(nil)
+)
+
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *sqlConn) PrepareContext(ctx context.Context,
+	query string) (driver.Stmt, error) {
+	var (
+		s   driver.Stmt
+		err error
+	)
+	start, ok := c.t.start()
+	if p, pok := c.c.(driver.ConnPrepareContext); pok {
+		s, err = p.PrepareContext(ctx, query)
+	} else {
+		s, err = c.c.Prepare(query)
+	}
+	if ok {
+		c.t.done(start, "prepare", query, nil, nil, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	st := &sqlStmt{s: s, c: c.c, query: query, t: c.t}
+	if _, ok := s.(driver.ColumnConverter); ok {
+		return sqlConverterStmt{st}, nil
+	}
+	return st, nil
+}
+
+func (c *sqlConn) Close() error {
+	return c.c.Close()
+}
+
+func (c *sqlConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *sqlConn) BeginTx(ctx context.Context,
+	opts driver.TxOptions) (driver.Tx, error) {
+	var (
+		tx  driver.Tx
+		err error
+	)
+	start, ok := c.t.start()
+	if b, bok := c.c.(driver.ConnBeginTx); bok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else if opts.Isolation != 0 {
+		// Like database/sql refuse what Begin cannot honor.
+		err = errors.New("dbglog: driver does not support " +
+			"non-default isolation level")
+	} else if opts.ReadOnly {
+		err = errors.New("dbglog: driver does not support read-only " +
+			"transactions")
+	} else {
+		tx, err = c.c.Begin()
+	}
+	if ok {
+		c.t.done(start, "begin", "", nil, nil, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{tx: tx, t: c.t}, nil
+}
+
+func (c *sqlConn) ExecContext(ctx context.Context, query string,
+	args []driver.NamedValue) (driver.Result, error) {
+	var (
+		res driver.Result
+		err error
+	)
+	start, ok := c.t.start()
+	switch e := c.c.(type) {
+	case driver.ExecerContext:
+		res, err = e.ExecContext(ctx, query, args)
+	case driver.Execer:
+		var v []driver.Value
+		if v, err = values(args); err == nil {
+			res, err = e.Exec(query, v)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	if ok {
+		c.t.done(start, "exec", query, args, res, err)
+	}
+	return res, err
+}
+
+func (c *sqlConn) QueryContext(ctx context.Context, query string,
+	args []driver.NamedValue) (driver.Rows, error) {
+	var (
+		rows driver.Rows
+		err  error
+	)
+	start, ok := c.t.start()
+	switch q := c.c.(type) {
+	case driver.QueryerContext:
+		rows, err = q.QueryContext(ctx, query, args)
+	case driver.Queryer:
+		var v []driver.Value
+		if v, err = values(args); err == nil {
+			rows, err = q.Query(query, v)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	if ok {
+		c.t.done(start, "query", query, args, nil, err)
+	}
+	return rows, err
+}
+
+func (c *sqlConn) Ping(ctx context.Context) error {
+	if p, ok := c.c.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.c.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) IsValid() bool {
+	if v, ok := c.c.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *sqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.c.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type sqlStmt struct {
+	s     driver.Stmt
+	c     driver.Conn // connection that prepared s
+	query string
+	t     sqlTracer
+}
+
+var (
+	_ driver.StmtExecContext   = (*sqlStmt)(nil)
+	_ driver.StmtQueryContext  = (*sqlStmt)(nil)
+	_ driver.NamedValueChecker = (*sqlStmt)(nil)
+	_ driver.ColumnConverter   = sqlConverterStmt{}
+)
+
+// CheckNamedValue uses the checker of the statement or else that of the
+// connection, database/sql only asks the connection when the statement has
+// none.
+func (s *sqlStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := s.s.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	if n, ok := s.c.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (s *sqlStmt) Close() error {
+	return s.s.Close()
+}
+
+func (s *sqlStmt) NumInput() int {
+	return s.s.NumInput()
+}
+
+func (s *sqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), named(args))
+}
+
+func (s *sqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), named(args))
+}
+
+func (s *sqlStmt) ExecContext(ctx context.Context,
+	args []driver.NamedValue) (driver.Result, error) {
+	var (
+		res driver.Result
+		err error
+	)
+	start, ok := s.t.start()
+	if e, eok := s.s.(driver.StmtExecContext); eok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		var v []driver.Value
+		if v, err = values(args); err == nil {
+			res, err = s.s.Exec(v)
+		}
+	}
+	if ok {
+		s.t.done(start, "exec", s.query, args, res, err)
+	}
+	return res, err
+}
+
+func (s *sqlStmt) QueryContext(ctx context.Context,
+	args []driver.NamedValue) (driver.Rows, error) {
+	var (
+		rows driver.Rows
+		err  error
+	)
+	start, ok := s.t.start()
+	if q, qok := s.s.(driver.StmtQueryContext); qok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		var v []driver.Value
+		if v, err = values(args); err == nil {
+			rows, err = s.s.Query(v)
+		}
+	}
+	if ok {
+		s.t.done(start, "query", s.query, args, nil, err)
+	}
+	return rows, err
+}
+
+// sqlConverterStmt is a sqlStmt for a statement that implements
+// driver.ColumnConverter.  It is a separate type because database/sql converts
+// arguments differently for statements that implement it.
+type sqlConverterStmt struct {
+	*sqlStmt
+}
+
+func (s sqlConverterStmt) ColumnConverter(idx int) driver.ValueConverter {
+	return s.s.(driver.ColumnConverter).ColumnConverter(idx)
+}
+
+type sqlTx struct {
+	tx driver.Tx
+	t  sqlTracer
+}
+
+func (t *sqlTx) Commit() error {
+	start, ok := t.t.start()
+	err := t.tx.Commit()
+	if ok {
+		t.t.done(start, "commit", "", nil, nil, err)
+	}
+	return err
+}
+
+func (t *sqlTx) Rollback() error {
+	start, ok := t.t.start()
+	err := t.tx.Rollback()
+	if ok {
+		t.t.done(start, "rollback", "", nil, nil, err)
+	}
+	return err
+}
@@ -0,0 +1,185 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// beginDriver is a driver whose connections only implement the legacy Begin.
+type beginDriver struct{}
+
+func (beginDriver) Open(string) (driver.Conn, error) { return beginConn{}, nil }
+
+type beginConn struct{}
+
+func (beginConn) Prepare(string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+func (beginConn) Close() error              { return nil }
+func (beginConn) Begin() (driver.Tx, error) { return beginTx{}, nil }
+
+type beginTx struct{}
+
+func (beginTx) Commit() error   { return nil }
+func (beginTx) Rollback() error { return nil }
+
+func TestWrapDriverBeginTxOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts driver.TxOptions
+		err  string
+	}{
+		{name: "default"},
+		{
+			name: "isolation",
+			opts: driver.TxOptions{
+				Isolation: driver.IsolationLevel(
+					sql.LevelSerializable),
+			},
+			err: "isolation level",
+		},
+		{
+			name: "read only",
+			opts: driver.TxOptions{ReadOnly: true},
+			err:  "read-only",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 1)
+			c, err := d.WrapDriver(beginDriver{}, 1).Open("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tx, err := c.(driver.ConnBeginTx).BeginTx(
+				context.Background(), tt.opts)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				tx.Rollback()
+				return
+			}
+			if err == nil ||
+				!strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got %v, want %q", err, tt.err)
+			}
+			if !strings.Contains(b.String(), tt.err) {
+				t.Fatalf("error not printed: %q", b.String())
+			}
+		})
+	}
+}
+
+// convDriver is a driver with a connector whose statements check and convert
+// their arguments.  The arguments of the last Exec are stored in args.  Its
+// Open is never used by database/sql.
+type convDriver struct {
+	args *[]driver.Value
+}
+
+func (convDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("Open used")
+}
+
+func (c convDriver) OpenConnector(string) (driver.Connector, error) {
+	return convConnector(c), nil
+}
+
+type convConnector convDriver
+
+func (c convConnector) Connect(context.Context) (driver.Conn, error) {
+	return convConn{args: c.args}, nil
+}
+func (c convConnector) Driver() driver.Driver { return convDriver(c) }
+
+type convConn struct {
+	beginConn
+	args *[]driver.Value
+}
+
+func (c convConn) Prepare(string) (driver.Stmt, error) {
+	return convStmt(c), nil
+}
+
+// convStmt checks int64 arguments and converts all others to "conv".
+type convStmt convConn
+
+func (convStmt) Close() error  { return nil }
+func (convStmt) NumInput() int { return 1 }
+
+func (s convStmt) Exec(args []driver.Value) (driver.Result, error) {
+	*s.args = args
+	return driver.RowsAffected(1), nil
+}
+
+func (convStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("Query used")
+}
+
+func (convStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if _, ok := nv.Value.(int64); ok {
+		nv.Value = "checked"
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+func (convStmt) ColumnConverter(int) driver.ValueConverter {
+	return convConverter{}
+}
+
+type convConverter struct{}
+
+func (convConverter) ConvertValue(interface{}) (driver.Value, error) {
+	return "conv", nil
+}
+
+func TestWrapDriverForwards(t *testing.T) {
+	var args []driver.Value
+	d, _ := newTestLogger("", 0, 1)
+	w := d.WrapDriver(convDriver{args: &args}, 1)
+	c, err := w.(driver.DriverContext).OpenConnector("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(c)
+	defer db.Close()
+
+	for _, tt := range []struct {
+		arg  interface{}
+		want string
+	}{
+		{int64(1), "checked"},
+		{"x", "conv"},
+	} {
+		if _, err := db.Exec("q", tt.arg); err != nil {
+			t.Fatal(err)
+		}
+		if len(args) != 1 || args[0] != tt.want {
+			t.Fatalf("%v: got %v, want %v", tt.arg, args, tt.want)
+		}
+	}
+}
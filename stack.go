@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// SetStackFilter controls which frames are removed from the stack traces
+// printed by the Debug functions.  When skipRuntime is set frames of functions
+// in package runtime are dropped, as are frames of functions whose name starts
+// with any of dropPrefixes, i.e. "github.com/marcopeereboom/dbglog.".
+func (d *DbgLogger) SetStackFilter(skipRuntime bool, dropPrefixes []string) {
+	d.mu.Lock()
+	d.skipRuntime = skipRuntime
+	d.dropFrames = append([]string(nil), dropPrefixes...)
+	d.mu.Unlock()
+}
+
+// filterStack removes the frames selected by SetStackFilter from a stack trace
+// in the format produced by runtime.Stack.
+func (d *DbgLogger) filterStack(s []byte) []byte {
+	d.mu.Lock()
+	skipRuntime := d.skipRuntime
+	drop := d.dropFrames
+	d.mu.Unlock()
+
+	if !skipRuntime && len(drop) == 0 {
+		return s
+	}
+
+	dropped := func(fn string) bool {
+		fn = strings.TrimPrefix(fn, "created by ")
+		if skipRuntime && strings.HasPrefix(fn, "runtime.") {
+			return true
+		}
+		for _, p := range drop {
+			if strings.HasPrefix(fn, p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var out []byte
+	lines := bytes.SplitAfter(s, []byte("\n"))
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		// A frame is a function line followed by a tab indented file
+		// line.
+		if i+1 < len(lines) && len(l) > 0 && l[0] != '\t' &&
+			bytes.HasPrefix(lines[i+1], []byte("\t")) {
+			if dropped(string(l)) {
+				i++
+				continue
+			}
+			out = append(out, l...)
+			out = append(out, lines[i+1]...)
+			i++
+			continue
+		}
+		out = append(out, l...)
+	}
+	return out
+}
+
+// stack returns the stack trace of the calling goroutine or, if all is true,
+// of all goroutines.
+func stack(all bool) []byte {
+	buf := make([]byte, 8192)
+	for {
+		n := runtime.Stack(buf, all)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
+
+// DebugfGoroutines prints the number of goroutines and, if full is true, the
+// stack traces of all goroutines.  It only prints when debug is enabled and bit
+// is enabled in the mask.  Note that a full dump stops the world and is
+// expensive.
+func (d *DbgLogger) DebugfGoroutines(bit uint64, full bool) {
+	if !DebugCompiled {
+		return
+	}
+	if !d.wantedM(bit) {
+		return
+	}
+
+	s := fmt.Sprintf("goroutines: %v", runtime.NumGoroutine())
+	if full {
+		s += "\n" + string(d.filterStack(stack(true)))
+	}
+	d.output(2, LevelDebug, bit, s)
+}
+
+// SetStackAll makes DebugStack print the stack traces of all goroutines instead
+// of only the calling one.
+func (d *DbgLogger) SetStackAll(all bool) {
+	d.mu.Lock()
+	d.stackAll = all
+	d.mu.Unlock()
+}
+
+// DebugStack prints msg followed by the stack trace of the calling goroutine,
+// or of all goroutines when SetStackAll is set, filtered as set by
+// SetStackFilter.  It only prints when debug is enabled and bit is enabled in
+// the mask.
+func (d *DbgLogger) DebugStack(bit uint64, msg string) {
+	if !DebugCompiled {
+		return
+	}
+	if !d.wantedM(bit) {
+		return
+	}
+
+	d.mu.Lock()
+	all := d.stackAll
+	d.mu.Unlock()
+
+	s := skipFrames(stack(all), 2)
+	msg = strings.TrimSuffix(msg, "\n")
+	d.output(2, LevelDebug, bit, msg+"\n"+string(d.filterStack(s)))
+}
+
+// skipFrames removes the first n frames of the first goroutine from a stack
+// trace in the format produced by runtime.Stack.
+func skipFrames(s []byte, n int) []byte {
+	i := bytes.IndexByte(s, '\n') + 1
+	if i == 0 {
+		return s
+	}
+	rest := s[i:]
+	for ; n > 0; n-- {
+		// A frame is a function line followed by a tab indented file
+		// line.
+		j := bytes.IndexByte(rest, '\n') + 1
+		if j == 0 || j >= len(rest) || rest[j] != '\t' {
+			break
+		}
+		k := bytes.IndexByte(rest[j:], '\n') + 1
+		if k == 0 {
+			break
+		}
+		rest = rest[j+k:]
+	}
+	return append(s[:i:i], rest...)
+}
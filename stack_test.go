@@ -0,0 +1,134 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestDebugfGoroutines(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	go func() { <-block }()
+
+	count := regexp.MustCompile(`^goroutines: \d+\n`)
+	tests := []struct {
+		name   string
+		bit    uint64
+		full   bool
+		stacks int // minimum number of goroutine stacks
+	}{
+		{name: "count", bit: 1},
+		{name: "full", bit: 1, full: true, stacks: 2},
+		{name: "masked", bit: 2, full: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 1)
+			d.DebugfGoroutines(tt.bit, tt.full)
+			out := b.String()
+			if tt.bit != 1 {
+				if out != "" {
+					t.Fatalf("masked bit printed %q", out)
+				}
+				return
+			}
+			if !count.MatchString(out) {
+				t.Fatalf("no count line: %q", out)
+			}
+			n := strings.Count(out, "\ngoroutine ")
+			if tt.full && n < tt.stacks {
+				t.Fatalf("got %v stacks, want at least %v: %s",
+					n, tt.stacks, out)
+			}
+			if !tt.full && n != 0 {
+				t.Fatalf("unexpected stacks: %s", out)
+			}
+		})
+	}
+}
+
+func TestSetStackFilter(t *testing.T) {
+	const synthetic = `goroutine 1 [running]:
+runtime/debug.Stack()
+	/go/src/runtime/debug/stack.go:24 +0x5e
+github.com/marcopeereboom/dbglog.(*DbgLogger).DebugStack(...)
+	/src/dbglog/stack.go:140 +0x25
+main.handle(0x1)
+	/src/app/main.go:12 +0x1d
+runtime.gopanic({0x4b2f40, 0xc000012345})
+	/go/src/runtime/panic.go:770 +0x132
+main.main()
+	/src/app/main.go:20 +0x2a
+created by main.start in goroutine 1
+	/src/app/main.go:30 +0x3b
+`
+	tests := []struct {
+		name        string
+		skipRuntime bool
+		drop        []string
+		gone        []string
+	}{
+		{name: "none"},
+		{
+			name:        "runtime",
+			skipRuntime: true,
+			gone:        []string{"runtime.gopanic", "panic.go"},
+		},
+		{
+			name: "prefixes",
+			drop: []string{
+				"github.com/marcopeereboom/dbglog.",
+				"runtime/",
+			},
+			gone: []string{"DebugStack", "debug.Stack", "stack.go"},
+		},
+		{
+			name: "created by",
+			drop: []string{"main.start"},
+			gone: []string{"created by", "main.go:30"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewNop()
+			d.SetStackFilter(tt.skipRuntime, tt.drop)
+			got := string(d.filterStack([]byte(synthetic)))
+			for _, s := range tt.gone {
+				if strings.Contains(got, s) {
+					t.Errorf("%q not removed:\n%s", s, got)
+				}
+			}
+			for _, s := range []string{
+				"goroutine 1 [running]:",
+				"main.handle(0x1)\n\t/src/app/main.go:12",
+				"main.main()\n\t/src/app/main.go:20",
+			} {
+				if !strings.Contains(got, s) {
+					t.Errorf("%q missing:\n%s", s, got)
+				}
+			}
+			if len(tt.gone) == 0 && got != synthetic {
+				t.Errorf("unfiltered stack changed:\n%s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// counter counts the messages of a level or bit.
+type counter struct {
+	emitted    atomic.Uint64
+	suppressed atomic.Uint64
+}
+
+// stats are the message counters of a DbgLogger, see SetStats.
+type stats struct {
+	levels [LevelFatal + 1]counter
+	bits   [65]counter // the last one counts messages without a bit
+}
+
+// Counts are the numbers of printed and suppressed messages.
+type Counts struct {
+	Emitted    uint64 `json:"emitted"`
+	Suppressed uint64 `json:"suppressed"`
+}
+
+// Stats are the message counts of a logger by level name and by bit name.
+// Bits without a name are keyed by their value, i.e. 0x4, messages without a
+// bit by "none".
+type Stats struct {
+	Levels map[string]Counts `json:"levels"`
+	Bits   map[string]Counts `json:"bits"`
+}
+
+// SetStats enables or disables counting messages per level and per bit.  A
+// message is counted as suppressed when it is not printed, because debug or
+// its bit is disabled, its level is below the configured one or it was
+// sampled out or deduplicated.  Counting costs an atomic add per message,
+// including the ones that are not printed.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) SetStats(on bool) {
+	d.statsOn.Store(on)
+}
+
+// count counts a message of level l for bit when stats are enabled.
+func (d *DbgLogger) count(l Level, bit uint64, emitted bool) {
+	if !d.statsOn.Load() {
+		return
+	}
+	inc := func(c *counter) {
+		if emitted {
+			c.emitted.Add(1)
+		} else {
+			c.suppressed.Add(1)
+		}
+	}
+	if l >= 0 && int(l) < len(d.stats.levels) {
+		inc(&d.stats.levels[l])
+	}
+	if bit == 0 {
+		inc(&d.stats.bits[64])
+	}
+	for bit != 0 {
+		inc(&d.stats.bits[bits.TrailingZeros64(bit)])
+		bit &= bit - 1
+	}
+}
+
+// Stats returns the message counts collected since SetStats enabled counting.
+// Levels and bits without messages are left out.  The counts never decrease,
+// so a prometheus.Collector in the program can report them as counters from
+// its Collect method, i.e.
+//
+//	for name, c := range d.Stats().Bits {
+//		ch <- prometheus.MustNewConstMetric(emittedDesc,
+//			prometheus.CounterValue, float64(c.Emitted), name)
+//	}
+//
+// Package expvarstats publishes them with expvar.
+func (d *DbgLogger) Stats() Stats {
+	s := Stats{
+		Levels: make(map[string]Counts),
+		Bits:   make(map[string]Counts),
+	}
+	get := func(c *counter) (Counts, bool) {
+		n := Counts{
+			Emitted:    c.emitted.Load(),
+			Suppressed: c.suppressed.Load(),
+		}
+		return n, n.Emitted != 0 || n.Suppressed != 0
+	}
+	for l := range d.stats.levels {
+		if n, ok := get(&d.stats.levels[l]); ok {
+			s.Levels[Level(l).String()] = n
+		}
+	}
+	for i := range d.stats.bits {
+		n, ok := get(&d.stats.bits[i])
+		if !ok {
+			continue
+		}
+		name := "none"
+		if i < 64 {
+			bit := uint64(1) << uint(i)
+			if name = d.BitName(bit); name == "" {
+				name = maskString(bit)
+			}
+		}
+		s.Bits[name] = n
+	}
+	return s
+}
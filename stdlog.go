@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"log"
+	"runtime"
+	"strings"
+)
+
+// stdlogWriter is the output of the standard logger installed by
+// CaptureStdlog.
+type stdlogWriter struct {
+	d   *DbgLogger
+	bit uint64
+}
+
+// CaptureStdlog redirects the output of the standard log package, e.g.
+// log.Printf in third party libraries, into d as if every message was passed
+// to DebugM with bit.  The prefix and flags of the standard logger are cleared
+// since d renders its own header, the caller is the code that called the log
+// package.  The returned function restores the previous output, prefix and
+// flags of the standard logger.
+//
+// The output of d must not be the output of the standard logger.
+func (d *DbgLogger) CaptureStdlog(bit uint64) (restore func()) {
+	out, prefix, flags := log.Writer(), log.Prefix(), log.Flags()
+	log.SetOutput(&stdlogWriter{d: d, bit: bit})
+	log.SetPrefix("")
+	log.SetFlags(0)
+	return func() {
+		log.SetOutput(out)
+		log.SetPrefix(prefix)
+		log.SetFlags(flags)
+	}
+}
+
+// Write prints a single message of the standard logger.  Package log calls
+// Write once per message, always newline terminated.
+func (w *stdlogWriter) Write(p []byte) (int, error) {
+	if !w.d.wantedM(w.bit) {
+		return len(p), nil
+	}
+	e := &Entry{
+		Level:   LevelDebug,
+		Bit:     w.bit,
+		Message: strings.TrimSuffix(string(p), "\n"),
+	}
+	if w.d.Flags()&(log.Lshortfile|log.Llongfile) != 0 {
+		e.File, e.Line = stdlogCaller()
+	}
+	w.d.emit(2, e)
+	return len(p), nil
+}
+
+// stdlogCaller returns the file and line of the first caller outside of
+// package log.
+func stdlogCaller() (string, int) {
+	pc := make([]uintptr, 16)
+	n := runtime.Callers(3, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "log.") {
+			return f.File, f.Line
+		}
+		if !more {
+			return "???", 0
+		}
+	}
+}
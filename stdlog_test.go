@@ -0,0 +1,60 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestCaptureStdlog(t *testing.T) {
+	out, prefix, flags := log.Writer(), log.Prefix(), log.Flags()
+	defer func() {
+		log.SetOutput(out)
+		log.SetPrefix(prefix)
+		log.SetFlags(flags)
+	}()
+	var prev bytes.Buffer
+	log.SetOutput(&prev)
+	log.SetPrefix("std ")
+	log.SetFlags(log.Lmicroseconds)
+
+	d, b := newTestLogger("", log.Lshortfile, 1)
+	restore := d.CaptureStdlog(1)
+	log.Print("printed")
+	d.SetMask(2)
+	log.Print("masked")
+	restore()
+
+	want := "stdlog_test.go:"
+	if got := b.String(); !strings.HasPrefix(got, want) ||
+		!strings.HasSuffix(got, ": printed\n") {
+		t.Fatalf("got %q", got)
+	}
+	if prev.Len() != 0 {
+		t.Fatalf("written to the previous output: %q", prev.String())
+	}
+	if log.Writer() != &prev || log.Prefix() != "std " ||
+		log.Flags() != log.Lmicroseconds {
+		t.Fatalf("not restored: %v %q %v", log.Writer(), log.Prefix(),
+			log.Flags())
+	}
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"log"
+)
+
+// parentWriter writes to the current output of a parent logger.
+type parentWriter struct {
+	p *DbgLogger
+}
+
+// Write writes b to the output of the parent, serialized with the writes of
+// the parent itself.  It only receives the output of the embedded log.Logger,
+// the Debug functions go through the routes of the parent, see deliver.
+func (w parentWriter) Write(b []byte) (int, error) {
+	w.p.mu.Lock()
+	defer w.p.mu.Unlock()
+	return w.p.Logger.Writer().Write(b)
+}
+
+// Sub returns a logger derived from d whose prefix is the prefix of d followed
+// by prefix.  The derived logger shares the enabled state, mask, level and
+// registered bit names with d, changing them on either affects the whole
+// hierarchy.  Its messages go through whatever the routes, outputs and output
+// of d are at the time of writing, rules added with SetRoute or SetBitOutput
+// and outputs added with AddOutput on the derived logger apply in addition.
+// It starts out with a copy of the remaining configuration of d, i.e. flags
+// and format.
+func (d *DbgLogger) Sub(prefix string) *DbgLogger {
+	d.mu.Lock()
+	c := d.config.clone()
+	d.mu.Unlock()
+	c.routes = nil
+	c.outputs = nil
+
+	s := &DbgLogger{
+		shared: d.shared,
+		parent: d,
+		config: c,
+	}
+	s.Logger = log.New(parentWriter{p: d}, d.Prefix()+prefix, d.Flags())
+	s.depth.Store(d.depth.Load())
+	return s
+}
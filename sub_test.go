@@ -0,0 +1,76 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubParentRoutes(t *testing.T) {
+	d, b := newTestLogger("", 0, 1|2)
+	s := d.Sub("sub ")
+
+	// Routes and outputs added to the parent after Sub apply to s.
+	var routed, copied, own bytes.Buffer
+	d.SetBitOutput(2, &routed)
+	d.AddOutput(&copied)
+	s.SetBitOutput(1, &own)
+	s.DebugfM(1, "one")
+	s.DebugfM(2, "two")
+	s.Debugf("none")
+
+	for _, tt := range []struct {
+		name string
+		b    *bytes.Buffer
+		want []string
+	}{
+		{"output", b, []string{"sub none"}},
+		{"parent route", &routed, []string{"sub two"}},
+		{"own route", &own, []string{"sub one"}},
+		{"parent output", &copied,
+			[]string{"sub one", "sub two", "sub none"}},
+	} {
+		if got := lines(tt.b); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSubFatalfFlushes(t *testing.T) {
+	code := -1
+	osExit = func(c int) { code = c }
+	defer func() { osExit = os.Exit }()
+
+	w := &slowWriter{delay: 20 * time.Millisecond}
+	a := NewAsyncWriter(w, 0)
+	defer a.Close()
+	d := New(a, "", 0)
+	d.Sub("sub ").Fatalf("bye")
+	if code != 1 {
+		t.Fatalf("exit code %v", code)
+	}
+	if got := w.String(); !strings.Contains(got, "bye") {
+		t.Fatalf("message lost: %q", got)
+	}
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// allow records an emission for key and reports whether it is within the
+// first n.  Emissions past n are counted as suppressed.
+func (d *DbgLogger) allow(key string, n int) bool {
+	d.smu.Lock()
+	defer d.smu.Unlock()
+
+	if d.emitted == nil {
+		d.emitted = make(map[string]int)
+	}
+	if d.emitted[key] < n {
+		d.emitted[key]++
+		return true
+	}
+	d.suppress(key)
+	return false
+}
+
+// suppress counts a suppressed message for key.  Must be called with smu held.
+func (d *DbgLogger) suppress(key string) {
+	if d.suppressed == nil {
+		d.suppressed = make(map[string]uint64)
+	}
+	d.suppressed[key]++
+}
+
+// DebugfN is log.Printf equivalent but only prints the first n messages for
+// key when debug is enabled.  Later messages for key are suppressed until
+// FlushSuppressed is called.
+func (d *DbgLogger) DebugfN(key string, n int, format string,
+	v ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wanted() && d.allow(key, n) {
+		d.output(2, LevelDebug, 0, fmt.Sprintf(format, v...))
+	}
+}
+
+// DebugfOnce is log.Printf equivalent but only prints the first message for
+// key when debug is enabled.
+func (d *DbgLogger) DebugfOnce(key string, format string, v ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wanted() && d.allow(key, 1) {
+		d.output(2, LevelDebug, 0, fmt.Sprintf(format, v...))
+	}
+}
+
+// callSite returns the file:line of the caller skip frames up, it is used as
+// the suppression key of the call site variants.
+func (d *DbgLogger) callSite(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1 + int(d.depth.Load()))
+	if !ok {
+		return "???"
+	}
+	return file + ":" + itoa10(line)
+}
+
+// DebugOnce is log.Print equivalent but only prints the first message from
+// its call site when debug is enabled.
+func (d *DbgLogger) DebugOnce(v ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wanted() && d.allow(d.callSite(1), 1) {
+		d.output(2, LevelDebug, 0, fmt.Sprint(v...))
+	}
+}
+
+// DebugOncef is log.Printf equivalent but only prints the first message from
+// its call site when debug is enabled.
+func (d *DbgLogger) DebugOncef(format string, v ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wanted() && d.allow(d.callSite(1), 1) {
+		d.output(2, LevelDebug, 0, fmt.Sprintf(format, v...))
+	}
+}
+
+// DebugEvery is log.Printf equivalent but prints at most one message per
+// interval from its call site when debug is enabled.  Messages in between are
+// suppressed.
+func (d *DbgLogger) DebugEvery(interval time.Duration, format string,
+	v ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wanted() && d.due(d.callSite(1), interval) {
+		d.output(2, LevelDebug, 0, fmt.Sprintf(format, v...))
+	}
+}
+
+// due reports whether interval has passed since the last emission for key
+// and records the emission if so.
+func (d *DbgLogger) due(key string, interval time.Duration) bool {
+	now := d.clock()
+
+	d.smu.Lock()
+	defer d.smu.Unlock()
+
+	if t, ok := d.every[key]; ok && now.Sub(t) < interval {
+		d.suppress(key)
+		return false
+	}
+	if d.every == nil {
+		d.every = make(map[string]time.Time)
+	}
+	d.every[key] = now
+	return true
+}
+
+// FlushSuppressed prints a single report of all keys that had messages
+// suppressed along with their counts and then clears the suppression state so
+// that DebugfOnce, DebugfN, DebugOnce, DebugEvery and DebugfIfChanged print
+// again.  The pending "last message repeated N times" summary of SetDedup is
+// printed first.  Nothing is printed if no messages were suppressed.
+func (d *DbgLogger) FlushSuppressed() {
+	d.flushDedup()
+
+	d.smu.Lock()
+	suppressed := d.suppressed
+	d.suppressed = nil
+	d.emitted = nil
+	d.last = nil
+	d.every = nil
+	d.smu.Unlock()
+
+	if !d.wanted() || len(suppressed) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(suppressed))
+	for k := range suppressed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	r := make([]string, 0, len(keys))
+	for _, k := range keys {
+		r = append(r, fmt.Sprintf("%v=%v", k, suppressed[k]))
+	}
+	d.output(2, LevelDebug, 0, "suppressed: "+strings.Join(r, " "))
+}
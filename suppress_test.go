@@ -0,0 +1,166 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlushSuppressed(t *testing.T) {
+	d, b := newTestLogger("", 0, 0)
+	for i := 0; i < 5; i++ {
+		d.DebugfOnce("once", "once %v", i)
+		d.DebugfN("n", 2, "n %v", i)
+	}
+	d.DebugfN("single", 1, "single")
+	d.FlushSuppressed()
+
+	want := []string{
+		"once 0",
+		"n 0",
+		"n 1",
+		"single",
+		"suppressed: n=3 once=4",
+	}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// The state is cleared, the keys print again and a second flush has
+	// nothing to report.
+	b.Reset()
+	d.DebugfOnce("once", "again")
+	d.FlushSuppressed()
+	if got := lines(b); !reflect.DeepEqual(got, []string{"again"}) {
+		t.Fatalf("got %q after flush", got)
+	}
+}
+
+func TestFlushSuppressedDedup(t *testing.T) {
+	d, b := newTestLogger("", 0, 0)
+	d.SetDedup(true)
+	for i := 0; i < 3; i++ {
+		d.Debugf("same")
+	}
+	d.FlushSuppressed()
+	want := []string{"same", "last message repeated 2 times"}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// The repeats are reported once and the message prints again.
+	d.Debugf("same")
+	d.FlushSuppressed()
+	want = append(want, "same")
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestDebugOnce(t *testing.T) {
+	d, b := newTestLogger("", 0, 0)
+
+	// Nothing is recorded while debug is disabled.
+	d.Disable()
+	d.DebugOnce("disabled")
+	d.Enable()
+
+	for i := 0; i < 3; i++ {
+		d.DebugOnce("once ", i)
+		d.DebugOncef("oncef %v", i)
+	}
+	d.DebugOnce("other site")
+	want := []string{"once 0", "oncef 0", "other site"}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// Each call site was suppressed twice.
+	b.Reset()
+	d.FlushSuppressed()
+	got := strings.TrimPrefix(strings.TrimSuffix(b.String(), "\n"),
+		"suppressed: ")
+	sites := strings.Fields(got)
+	if len(sites) != 2 {
+		t.Fatalf("got %q, want two call sites", b.String())
+	}
+	for _, s := range sites {
+		if !strings.Contains(s, "suppress_test.go:") ||
+			!strings.HasSuffix(s, "=2") {
+			t.Fatalf("got %q, want suppress_test.go:N=2", s)
+		}
+	}
+}
+
+func TestDebugEvery(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []time.Duration // advance before each pair of messages
+		want  []string
+	}{
+		{
+			name:  "first",
+			steps: []time.Duration{0},
+			want:  []string{"every 0", "hourly 0"},
+		},
+		{
+			name: "rate limited",
+			steps: []time.Duration{
+				0,
+				500 * time.Millisecond,
+				500 * time.Millisecond,
+				999 * time.Millisecond,
+				time.Millisecond,
+			},
+			want: []string{
+				"every 0", "hourly 0", "every 2", "every 4",
+			},
+		},
+		{
+			name: "slow",
+			steps: []time.Duration{
+				0,
+				2 * time.Second,
+				time.Hour,
+			},
+			want: []string{
+				"every 0", "hourly 0", "every 1", "every 2",
+				"hourly 2",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 0)
+			advance := fakeClock(d)
+			for i, step := range tt.steps {
+				advance(step)
+				d.DebugEvery(time.Second, "every %v", i)
+				// Another call site is limited separately.
+				d.DebugEvery(time.Hour, "hourly %v", i)
+			}
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
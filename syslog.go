@@ -0,0 +1,252 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// facilities are the syslog facility codes by name.
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// errSyslogClosed is returned when writing to a closed Syslog.
+var errSyslogClosed = errors.New("dbglog: write to closed Syslog")
+
+// Syslog severities.
+const (
+	sevCrit    = 2
+	sevErr     = 3
+	sevWarning = 4
+	sevInfo    = 6
+	sevDebug   = 7
+)
+
+// severity returns the syslog severity of l.
+func severity(l Level) int {
+	switch l {
+	case LevelTrace, LevelDebug:
+		return sevDebug
+	case LevelInfo:
+		return sevInfo
+	case LevelWarn:
+		return sevWarning
+	case LevelError:
+		return sevErr
+	}
+	return sevCrit
+}
+
+// Syslog is a Backend that sends messages to a local or remote syslog daemon.
+// Debug messages are sent as LOG_DEBUG and the other levels are mapped to the
+// matching severity.  Syslog adds its own time stamp so the logger is best
+// created with flags 0.
+type Syslog struct {
+	network  string
+	raddr    string
+	facility int
+	tag      string
+	hostname string
+	pid      int
+
+	mu       sync.Mutex // protects everything below
+	conn     net.Conn
+	localNet string // network of the local daemon, "unixgram" or "unix"
+	rfc5424  bool
+	closed   bool
+}
+
+var _ Backend = (*Syslog)(nil)
+
+// NewSyslog connects to the local syslog daemon.  priority is the facility
+// name, i.e. "daemon" or "local0", and tag is the program name sent with every
+// message.
+func NewSyslog(priority, tag string) (*Syslog, error) {
+	return DialSyslog("", "", priority, tag)
+}
+
+// DialSyslog connects to the syslog daemon at raddr on network, i.e. "udp" or
+// "tcp".  An empty network connects to the local syslog daemon.  See NewSyslog
+// for priority and tag.  Messages sent over TCP are framed with octet counting
+// as described in RFC 6587, so they may contain newlines.
+func DialSyslog(network, raddr, priority, tag string) (*Syslog, error) {
+	facility, ok := facilities[strings.ToLower(priority)]
+	if !ok {
+		return nil, fmt.Errorf("dbglog: unknown syslog facility %q",
+			priority)
+	}
+	if tag == "" {
+		tag = os.Args[0]
+	}
+	hostname, _ := os.Hostname()
+	s := &Syslog{
+		network:  network,
+		raddr:    raddr,
+		facility: facility,
+		tag:      tag,
+		hostname: hostname,
+		pid:      os.Getpid(),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetRFC5424 selects the RFC 5424 message format instead of the traditional
+// RFC 3164 one.
+func (s *Syslog) SetRFC5424(on bool) {
+	s.mu.Lock()
+	s.rfc5424 = on
+	s.mu.Unlock()
+}
+
+// syslogPaths are the sockets of the local syslog daemon.
+var syslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// connect (re)connects to the daemon.  Must be called with mu held.
+func (s *Syslog) connect() error {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	if s.network != "" {
+		c, err := net.Dial(s.network, s.raddr)
+		if err != nil {
+			return err
+		}
+		s.conn = c
+		return nil
+	}
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range syslogPaths {
+			c, err := net.Dial(network, path)
+			if err == nil {
+				s.conn = c
+				s.localNet = network
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("dbglog: no local syslog daemon")
+}
+
+// format returns the syslog message for msg at severity.  Must be called with
+// mu held.
+func (s *Syslog) format(sev int, t time.Time, msg []byte) []byte {
+	msg = bytes.TrimRight(msg, "\n")
+	pri := s.facility*8 + sev
+
+	var b []byte
+	if s.rfc5424 {
+		hostname := s.hostname
+		if hostname == "" {
+			hostname = "-"
+		}
+		b = fmt.Appendf(b, "<%d>1 %s %s %s %d - - ", pri,
+			t.Format(RFC3339Micro), hostname,
+			s.tag, s.pid)
+	} else {
+		b = fmt.Appendf(b, "<%d>%s ", pri, t.Format(time.Stamp))
+		if s.network != "" {
+			b = append(b, s.hostname...)
+			b = append(b, ' ')
+		}
+		b = fmt.Appendf(b, "%s[%d]: ", s.tag, s.pid)
+	}
+	b = append(b, msg...)
+	network := s.network
+	if network == "" {
+		network = s.localNet
+	}
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		b = append([]byte(strconv.Itoa(len(b))+" "), b...)
+	case "unix":
+		if s.rfc5424 {
+			b = append([]byte(strconv.Itoa(len(b))+" "), b...)
+			break
+		}
+		// Newline framing, embedded newlines are escaped the way
+		// rsyslog escapes control characters.
+		b = bytes.ReplaceAll(b, []byte{'\n'}, []byte("#012"))
+		b = append(b, '\n')
+	}
+	return b
+}
+
+// send sends msg at severity with timestamp t, reconnecting once if the
+// connection failed.  It fails after Close.
+func (s *Syslog) send(sev int, t time.Time, msg []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errSyslogClosed
+	}
+	if s.conn != nil {
+		if _, err := s.conn.Write(s.format(sev, t, msg)); err == nil {
+			return nil
+		}
+	}
+	if err := s.connect(); err != nil {
+		return err
+	}
+	// The framing depends on the network, which may have changed.
+	_, err := s.conn.Write(s.format(sev, t, msg))
+	return err
+}
+
+// Write sends p as an informational message.
+func (s *Syslog) Write(p []byte) (int, error) {
+	if err := s.send(sevInfo, time.Now(), p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteEntry sends line with the severity of the level and the time of e.
+func (s *Syslog) WriteEntry(e *Entry, line []byte) error {
+	return s.send(severity(e.Level), e.Time, line)
+}
+
+// Close closes the connection to the daemon.  Later writes fail.
+func (s *Syslog) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
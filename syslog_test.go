@@ -0,0 +1,190 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogLocalStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		rfc5424 bool
+		multi   string // how "three\nfour" is received
+	}{
+		{"rfc3164", false, "three#012four"},
+		{"rfc5424", true, "three\nfour"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "log")
+			l, err := net.Listen("unix", path)
+			if err != nil {
+				t.Skip(err)
+			}
+			defer l.Close()
+			old := syslogPaths
+			syslogPaths = []string{path}
+			defer func() { syslogPaths = old }()
+
+			s, err := NewSyslog("user", "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			s.SetRFC5424(tt.rfc5424)
+			s.Write([]byte("one\n"))
+			s.Write([]byte("two"))
+			s.Write([]byte("three\nfour\n"))
+
+			c, err := l.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			c.SetReadDeadline(time.Now().Add(5 * time.Second))
+			r := bufio.NewReader(c)
+			for _, want := range []string{"one", "two", tt.multi} {
+				msg := readSyslogFrame(t, r, tt.rfc5424)
+				if !strings.HasSuffix(msg, ": "+want) &&
+					!strings.HasSuffix(msg, " - - "+want) {
+					t.Fatalf("got %q, want %q", msg, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	tests := []struct {
+		name    string
+		rfc5424 bool
+		prefix  string // start of the header
+	}{
+		{"rfc3164", false, "<14>"},
+		{"rfc5424", true, "<14>1 "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Skip(err)
+			}
+			defer l.Close()
+
+			s, err := DialSyslog("tcp", l.Addr().String(), "user",
+				"test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			s.SetRFC5424(tt.rfc5424)
+			s.Write([]byte("one\ntwo\n"))
+			s.Write([]byte("three"))
+
+			c, err := l.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			c.SetReadDeadline(time.Now().Add(5 * time.Second))
+			r := bufio.NewReader(c)
+			for _, want := range []string{"one\ntwo", "three"} {
+				msg := readSyslogFrame(t, r, true)
+				if !strings.HasPrefix(msg, tt.prefix) ||
+					!strings.Contains(msg, "test") ||
+					!strings.HasSuffix(msg, " "+want) {
+					t.Fatalf("got %q, want %q", msg, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSyslogClosed(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+
+	s, err := DialSyslog("tcp", l.Addr().String(), "user", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Write([]byte("msg")); err != errSyslogClosed {
+		t.Fatalf("got %v, want %v", err, errSyslogClosed)
+	}
+	e := &Entry{Level: LevelError, Time: time.Now()}
+	if err := s.WriteEntry(e, []byte("msg")); err != errSyslogClosed {
+		t.Fatalf("got %v, want %v", err, errSyslogClosed)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("second close: %v", err)
+	}
+
+	// Only the connection of DialSyslog was made.
+	c, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	l.(*net.TCPListener).SetDeadline(time.Now().Add(100 *
+		time.Millisecond))
+	if c, err := l.Accept(); err == nil {
+		c.Close()
+		t.Fatal("reconnected after Close")
+	}
+}
+
+// readSyslogFrame reads a newline or, if counted, an octet count framed
+// message.
+func readSyslogFrame(t *testing.T, r *bufio.Reader, counted bool) string {
+	t.Helper()
+	if !counted {
+		msg, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSuffix(msg, "\n")
+	}
+	n, err := r.ReadString(' ')
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, err := strconv.Atoi(strings.TrimSuffix(n, " "))
+	if err != nil {
+		t.Fatalf("no octet count: %q", n)
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SetTagColor sets the color used for tag by DebugfTagged when color is
+// enabled.  The color only applies to text output.  ColorNone removes the
+// color.
+func (d *DbgLogger) SetTagColor(tag string, c Color) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if c == ColorNone {
+		delete(d.tagColors, tag)
+		return
+	}
+	if d.tagColors == nil {
+		d.tagColors = make(map[string]Color)
+	}
+	d.tagColors[tag] = c
+}
+
+// appendMessage appends msg, the message of e, to buf.  The leading "[tag]" of
+// a DebugfTagged message is colored with the color of the tag after which c,
+// the color of the line, is restored.  The message is escaped if enabled.
+// Must be called with mu held.
+func (d *DbgLogger) appendMessage(e *Entry, msg string, c Color) {
+	t := "[" + e.tag + "] "
+	tc := d.tagColors[e.tag]
+	if e.tag != "" && d.color && tc != ColorNone &&
+		strings.HasPrefix(msg, t) {
+		tag := t[:len(t)-1]
+		if d.escape {
+			tag = escaped(tag)
+		}
+		d.buf = append(d.buf, tc.colorize(tag)...)
+		if c != ColorNone {
+			d.buf = append(d.buf, "\x1b["...)
+			itoa(&d.buf, int(c), -1)
+			d.buf = append(d.buf, 'm')
+		}
+		d.buf = append(d.buf, ' ')
+		msg = msg[len(t):]
+	}
+	if d.escape {
+		msg = escaped(msg)
+	}
+	d.buf = append(d.buf, msg...)
+}
+
+// DebugfTagged is log.Printf equivalent but prepends "[tag] " to the message.
+// Like DebugfM it only prints when debug is enabled and bit is enabled in the
+// mask.  Tags are free form, i.e. "SLOW" or "DEPRECATED", and make lines easy
+// to grep.
+func (d *DbgLogger) DebugfTagged(bit uint64, tag string, format string,
+	v ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wantedM(bit) {
+		d.emit(2, &Entry{
+			Level:   LevelDebug,
+			Bit:     bit,
+			Message: "[" + tag + "] " + fmt.Sprintf(format, v...),
+			tag:     tag,
+		})
+	}
+}
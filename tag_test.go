@@ -0,0 +1,101 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import "testing"
+
+func TestDebugfTagged(t *testing.T) {
+	const red, blue, reset = "\x1b[31m", "\x1b[34m", "\x1b[0m"
+	tests := []struct {
+		name     string
+		color    bool
+		escape   bool
+		tag      string
+		tagColor Color
+		bitColor Color
+		want     string
+	}{
+		{name: "plain", want: "p [SLOW] took 5s\n"},
+		{
+			name:     "color disabled",
+			tagColor: ColorRed,
+			want:     "p [SLOW] took 5s\n",
+		},
+		{
+			name:  "no tag color",
+			color: true,
+			want:  "p [SLOW] took 5s\n",
+		},
+		{
+			name:     "tag color",
+			color:    true,
+			tagColor: ColorRed,
+			want:     "p " + red + "[SLOW]" + reset + " took 5s\n",
+		},
+		{
+			name:     "tag and line color",
+			color:    true,
+			tagColor: ColorRed,
+			bitColor: ColorBlue,
+			want: "p " + blue + red + "[SLOW]" + reset + blue +
+				" took 5s" + reset + "\n",
+		},
+		{
+			name:     "escaped",
+			color:    true,
+			escape:   true,
+			tagColor: ColorRed,
+			want:     "p " + red + "[SLOW]" + reset + " took 5s\n",
+		},
+		{
+			name:     "escaped tag",
+			color:    true,
+			escape:   true,
+			tag:      "SL\aOW",
+			tagColor: ColorRed,
+			want: "p " + red + `[SL\aOW]` + reset +
+				" took 5s\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("p ", 0, 1)
+			d.SetColor(tt.color)
+			d.SetEscape(tt.escape)
+			tag := tt.tag
+			if tag == "" {
+				tag = "SLOW"
+			}
+			d.SetTagColor(tag, tt.tagColor)
+			d.SetBitColor(1, tt.bitColor)
+			d.DebugfTagged(1, tag, "took %v", "5s")
+			if got := b.String(); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDebugfTaggedMask(t *testing.T) {
+	d, b := newTestLogger("", 0, 1)
+	d.DebugfTagged(2, "SLOW", "masked")
+	if b.Len() != 0 {
+		t.Fatalf("bit not in mask printed %q", b.String())
+	}
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"io"
+)
+
+// tee is an additional output added with AddOutput.
+type tee struct {
+	w         io.Writer
+	mask      uint64 // bits of the messages to write, 0 for all
+	level     Level  // minimum level
+	format    Format // encoding when ownFormat is set
+	ownFormat bool   // use format instead of the format of the logger
+
+	closer io.Closer // set for the outputs opened by a configuration file
+}
+
+// OutputOption configures an output added with AddOutput.
+type OutputOption func(*tee)
+
+// OutputMask makes an output only receive the debug messages with any of the
+// bits in mask set.  Messages without a bit, such as those of Debugf and the
+// leveled functions, are not filtered by the mask.
+func OutputMask(mask uint64) OutputOption {
+	return func(t *tee) {
+		t.mask = mask
+	}
+}
+
+// OutputLevel makes an output only receive messages of level or higher.
+func OutputLevel(l Level) OutputOption {
+	return func(t *tee) {
+		t.level = l
+	}
+}
+
+// OutputFormat makes an output encode messages in format f instead of the
+// format of the logger.
+func OutputFormat(f Format) OutputOption {
+	return func(t *tee) {
+		t.format = f
+		t.ownFormat = true
+	}
+}
+
+// AddOutput adds a destination that receives a copy of every printed message
+// that passes its filters, in addition to the output or route the message is
+// written to anyway, i.e. to write all debug output to a file and only the
+// warnings to stderr:
+//
+//	d := dbglog.New(f, "app ", log.LstdFlags)
+//	d.AddOutput(os.Stderr, dbglog.OutputLevel(dbglog.LevelWarn))
+//
+// Only messages that are printed at all are copied, the filters of an output
+// cannot select messages disabled by Enable, the mask or the level.
+func (d *DbgLogger) AddOutput(w io.Writer, opts ...OutputOption) {
+	t := tee{w: w, level: LevelTrace}
+	for _, o := range opts {
+		o(&t)
+	}
+
+	d.mu.Lock()
+	d.outputs = append(d.outputs, t)
+	d.mu.Unlock()
+}
+
+// ClearOutputs removes all outputs added with AddOutput.  Outputs opened by
+// LoadConfig are closed.
+func (d *DbgLogger) ClearOutputs() {
+	d.mu.Lock()
+	outputs := d.outputs
+	d.outputs = nil
+	d.mu.Unlock()
+
+	for _, t := range outputs {
+		if t.closer != nil {
+			t.closer.Close()
+		}
+	}
+}
+
+// tee writes e to those of outputs, added with AddOutput, whose filters it
+// passes.  The line in buf is reused for the outputs that use the format of the
+// logger.  It returns the write and Sync errors.  Must be called with mu held.
+func (d *DbgLogger) tee(outputs []tee, e *Entry,
+	flag int) (failed, serrs []error) {
+	for _, t := range outputs {
+		if e.Level < t.level {
+			continue
+		}
+		if t.mask != 0 && e.Bit != 0 && e.Bit&t.mask == 0 {
+			continue
+		}
+
+		var err, serr error
+		if t.ownFormat && t.format != d.format {
+			line, bp := d.buf, getBuf()
+			d.buf = *bp
+			d.render(e, flag, t.format)
+			err, serr = d.deliverTo(t.w, e)
+			putBuf(bp, d.buf)
+			d.buf = line
+		} else {
+			err, serr = d.deliverTo(t.w, e)
+		}
+		if err != nil {
+			failed = append(failed, err)
+		} else if serr != nil {
+			serrs = append(serrs, serr)
+		}
+	}
+	return failed, serrs
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"log"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateData is the data a template set with SetTemplate is executed with.
+type TemplateData struct {
+	Time      time.Time // time of the message, in UTC if log.LUTC is set
+	TimeText  string    // Time in the SetTimeFormat layout or RFC 3339
+	Prefix    string    // prefix of the logger without surrounding blanks
+	Level     Level
+	Bit       uint64 // 0 for messages printed without a mask bit
+	BitName   string // registered name of Bit, see RegisterBit
+	Caller    string // file:line if log.Lshortfile or log.Llongfile is set
+	Pid       int
+	Goroutine uint64 // only set when Lgoroutine is set
+	Message   string // without the trailing newline
+	Fields    []Field
+}
+
+// SetTemplate sets the template that lays out the lines of the text format,
+// i.e.
+//
+//	t := template.Must(template.New("line").Parse(
+//		"{{.TimeText}} {{.Level}} [{{.BitName}}] " +
+//			"{{.Caller}} {{.Message}}"))
+//	d.SetTemplate(t)
+//
+// The template is executed with a TemplateData and a newline is added unless
+// the output ends in one.  Lines for which the template fails are printed in
+// the log.Logger layout instead.  A nil template restores that layout.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) SetTemplate(t *template.Template) {
+	d.mu.Lock()
+	d.template = t
+	d.mu.Unlock()
+}
+
+// appendWriter is an io.Writer that appends to a byte slice.
+type appendWriter struct {
+	b *[]byte
+}
+
+func (w appendWriter) Write(p []byte) (int, error) {
+	*w.b = append(*w.b, p...)
+	return len(p), nil
+}
+
+// formatTemplate appends e to buf as laid out by the template and reports
+// whether that succeeded.  Must be called with mu held.
+func (d *DbgLogger) formatTemplate(e *Entry, flag int) bool {
+	t := e.Time
+	if flag&log.LUTC != 0 {
+		t = t.UTC()
+	}
+	data := TemplateData{
+		Time:      t,
+		TimeText:  d.renderTime(e.Time, flag),
+		Prefix:    e.Prefix,
+		Level:     e.Level,
+		Bit:       e.Bit,
+		BitName:   d.BitName(e.Bit),
+		Caller:    caller(e, flag),
+		Pid:       pid,
+		Goroutine: e.Goroutine,
+		Message:   strings.TrimSuffix(e.Message, "\n"),
+		Fields:    e.Fields,
+	}
+	if d.escape {
+		data.Message = escaped(data.Message)
+	}
+	err := d.template.Execute(appendWriter{b: &d.buf}, data)
+	if err != nil {
+		d.buf = d.buf[:0]
+		return false
+	}
+	if len(d.buf) == 0 || d.buf[len(d.buf)-1] != '\n' {
+		d.buf = append(d.buf, '\n')
+	}
+	return true
+}
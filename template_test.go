@@ -0,0 +1,99 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"log"
+	"reflect"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestSetTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		text string // template, empty for none
+		want []string
+	}{
+		{
+			name: "none",
+			want: []string{
+				"p 2024/02/29 13:04:05 dial k=v",
+				"p 2024/02/29 13:04:05 [INFO] up",
+			},
+		},
+		{
+			name: "layout",
+			text: "{{.TimeText}} {{.Prefix}} {{.Level}} " +
+				"[{{.BitName}}] {{.Message}}" +
+				"{{range .Fields}} {{.Key}}:{{.Value}}{{end}}",
+			want: []string{
+				"2024-02-29T13:04:05Z p debug [net] dial k:v",
+				"2024-02-29T13:04:05Z p info [] up",
+			},
+		},
+		{
+			name: "own newline",
+			text: "{{.Message}}\n",
+			want: []string{"dial", "up"},
+		},
+		{
+			name: "execution error",
+			text: "{{.Message}} {{.Message.Nope}}",
+			want: []string{
+				"p 2024/02/29 13:04:05 dial k=v",
+				"p 2024/02/29 13:04:05 [INFO] up",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("p ", log.LstdFlags|log.LUTC, 0)
+			d.SetTimeFunc(func() time.Time {
+				return time.Date(2024, 2, 29, 13, 4, 5, 0,
+					time.UTC)
+			})
+			net := d.RegisterBit("net")
+			d.SetMask(net)
+			if tt.text != "" {
+				d.SetTemplate(template.Must(
+					template.New("line").Parse(tt.text)))
+			}
+			d.DebugwM(net, "dial", "k", "v")
+			d.Infof("up")
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetTemplateNil(t *testing.T) {
+	d, b := newTestLogger("", 0, 0)
+	tmpl := template.Must(template.New("line").Parse("<{{.Message}}>"))
+	d.SetTemplate(tmpl)
+	d.Debugf("a")
+	d.SetTemplate(nil)
+	d.Debugf("b")
+	want := []string{"<a>", "b"}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
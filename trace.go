@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// join renders v comma separated.
+func join(v []interface{}) string {
+	s := make([]string, 0, len(v))
+	for _, x := range v {
+		s = append(s, fmt.Sprintf("%v", x))
+	}
+	return strings.Join(s, ", ")
+}
+
+// DebugfScope prints "> name(args)" and returns a function that prints
+// "< name = (results)" when called.  It is meant to trace function calls:
+//
+//	func add(a, b int) (r int) {
+//		exit := d.DebugfScope(bit, "add", a, b)
+//		defer func() { exit(r) }()
+//		return a + b
+//	}
+//
+// Nothing is printed, and the returned function does nothing, unless debug is
+// enabled and bit is enabled in the mask.
+func (d *DbgLogger) DebugfScope(bit uint64, name string,
+	args ...interface{}) func(results ...interface{}) {
+	if !DebugCompiled {
+		return func(...interface{}) {}
+	}
+	if !d.wantedM(bit) {
+		return func(...interface{}) {}
+	}
+
+	d.output(2, LevelDebug, bit, fmt.Sprintf("> %v(%v)", name, join(args)))
+	return func(results ...interface{}) {
+		d.output(2, LevelDebug, bit,
+			fmt.Sprintf("< %v = (%v)", name, join(results)))
+	}
+}
+
+// Trace prints "enter name" and returns a function that prints
+// "exit name (took 1.2ms)" when called.  It is meant to be deferred:
+//
+//	defer d.Trace(bit, "handle")()
+//
+// Time is obtained from the clock set with SetTimeFunc.  Nothing is printed,
+// and the returned function does nothing, unless debug is enabled and bit is
+// enabled in the mask.
+func (d *DbgLogger) Trace(bit uint64, name string) func() {
+	if !DebugCompiled || !d.wantedM(bit) {
+		return func() {}
+	}
+
+	d.output(2, LevelDebug, bit, "enter "+name)
+	start := d.clock()
+	return func() {
+		took := d.clock().Sub(start)
+		d.output(2, LevelDebug, bit,
+			fmt.Sprintf("exit %v (took %v)", name, took))
+	}
+}
+
+// TimeTrack prints "label took 1.2ms", the time elapsed since start.  It is
+// meant to be deferred with the start time evaluated on entry:
+//
+//	defer d.TimeTrack(time.Now(), bit, "query")
+//
+// The elapsed time is measured with the clock set with SetTimeFunc.  It only
+// prints when debug is enabled and bit is enabled in the mask.
+func (d *DbgLogger) TimeTrack(start time.Time, bit uint64, label string) {
+	if DebugCompiled && d.wantedM(bit) {
+		d.output(2, LevelDebug, bit,
+			fmt.Sprintf("%v took %v", label, d.clock().Sub(start)))
+	}
+}
@@ -0,0 +1,125 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDebugfScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		mask    uint64
+		args    []interface{}
+		results []interface{}
+		want    []string
+	}{
+		{
+			name:    "args and results",
+			mask:    1,
+			args:    []interface{}{1, "two"},
+			results: []interface{}{3, errors.New("boom")},
+			want:    []string{"> f(1, two)", "< f = (3, boom)"},
+		},
+		{
+			name: "no args",
+			mask: 1,
+			want: []string{"> f()", "< f = ()"},
+		},
+		{
+			name:    "masked",
+			args:    []interface{}{1},
+			results: []interface{}{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, tt.mask)
+			exit := d.DebugfScope(1, "f", tt.args...)
+			exit(tt.results...)
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDebugfScopeDeferred(t *testing.T) {
+	d, b := newTestLogger("", 0, 1)
+	add := func(a, b int) (r int) {
+		exit := d.DebugfScope(1, "add", a, b)
+		defer func() { exit(r) }()
+		return a + b
+	}
+	add(1, 2)
+	want := []string{"> add(1, 2)", "< add = (3)"}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+// fakeClock sets the clock of d to one that starts at now and only advances
+// when the returned function is called.
+func fakeClock(d *DbgLogger) (advance func(time.Duration)) {
+	now := time.Date(2024, 2, 29, 13, 4, 5, 0, time.UTC)
+	d.SetTimeFunc(func() time.Time { return now })
+	return func(dur time.Duration) { now = now.Add(dur) }
+}
+
+func TestTrace(t *testing.T) {
+	for _, mask := range []uint64{0, 1} {
+		d, b := newTestLogger("", 0, mask)
+		advance := fakeClock(d)
+		func() {
+			defer d.Trace(1, "handle")()
+			advance(1200 * time.Microsecond)
+		}()
+		var want []string
+		if mask != 0 {
+			want = []string{
+				"enter handle",
+				"exit handle (took 1.2ms)",
+			}
+		}
+		if got := lines(b); !reflect.DeepEqual(got, want) {
+			t.Fatalf("mask %v: got %q, want %q", mask, got, want)
+		}
+	}
+}
+
+func TestTimeTrack(t *testing.T) {
+	for _, mask := range []uint64{0, 1} {
+		d, b := newTestLogger("", 0, mask)
+		advance := fakeClock(d)
+		func() {
+			defer d.TimeTrack(d.clock(), 1, "query")
+			advance(3 * time.Second)
+		}()
+		var want []string
+		if mask != 0 {
+			want = []string{"query took 3s"}
+		}
+		if got := lines(b); !reflect.DeepEqual(got, want) {
+			t.Fatalf("mask %v: got %q, want %q", mask, got, want)
+		}
+	}
+}
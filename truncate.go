@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import "unicode/utf8"
+
+// SetMaxMessageLen limits messages to n bytes.  Longer messages are cut at a
+// rune boundary and "... (truncated, N bytes)" is appended, N being the length
+// of the original message.  The limit applies to every output, backend and
+// hook.  An n of 0 or less removes the limit.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) SetMaxMessageLen(n int) {
+	if n < 0 {
+		n = 0
+	}
+	d.mu.Lock()
+	d.maxMessage = n
+	d.mu.Unlock()
+}
+
+// truncated returns s cut to at most n bytes at a rune boundary followed by
+// the truncation notice, or s if it is short enough.
+func truncated(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	i := n
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return s[:i] + "... (truncated, " + itoa10(len(s)) + " bytes)"
+}
@@ -0,0 +1,90 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetMaxMessageLen(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		msg  string
+		want string
+	}{
+		{name: "short", n: 5, msg: "hello", want: "hello"},
+		{
+			name: "cut",
+			n:    5,
+			msg:  "hello world",
+			want: "hello... (truncated, 11 bytes)",
+		},
+		{
+			name: "rune boundary",
+			n:    5,
+			msg:  "abcd€ef",
+			want: "abcd... (truncated, 9 bytes)",
+		},
+		{
+			name: "rune fits",
+			n:    7,
+			msg:  "abcd€ef",
+			want: "abcd€... (truncated, 9 bytes)",
+		},
+		{name: "zero", n: 0, msg: "hello world", want: "hello world"},
+		{
+			name: "negative",
+			n:    -1,
+			msg:  "hello world",
+			want: "hello world",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 0)
+			var hooked []string
+			d.AddHook(func(e Entry) {
+				hooked = append(hooked, e.Message)
+			})
+			d.SetMaxMessageLen(tt.n)
+			d.Debugf("%v", tt.msg)
+			want := []string{tt.want}
+			if got := lines(b); !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+			if !reflect.DeepEqual(hooked, want) {
+				t.Fatalf("hook got %q, want %q", hooked, want)
+			}
+		})
+	}
+}
+
+func TestSetMaxMessageLenCleared(t *testing.T) {
+	d, b := newTestLogger("", 0, 0)
+	d.SetMaxMessageLen(2)
+	d.Debugf("abc")
+	d.SetMaxMessageLen(0)
+	d.Debugf("abc")
+	want := []string{"ab... (truncated, 3 bytes)", "abc"}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
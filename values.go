@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// binary returns value as 0b prefixed binary, zero padded to width bits and
+// grouped in nibbles separated by underscores.  Values that do not fit in
+// width are not truncated.
+func binary(value uint64, width int) string {
+	if width < 1 {
+		width = 1
+	}
+	if width > 64 {
+		width = 64
+	}
+	if n := bits.Len64(value); n > width {
+		width = n
+	}
+
+	s := strconv.FormatUint(value, 2)
+	for len(s) < width {
+		s = "0" + s
+	}
+
+	b := []byte("0b")
+	for i := 0; i < len(s); i++ {
+		if i != 0 && (len(s)-i)%4 == 0 {
+			b = append(b, '_')
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
+
+// DebugfBinary prints label=value with value rendered in binary, zero padded
+// to width bits and grouped in nibbles, i.e. label=0b0000_1010.  It only prints
+// when debug is enabled and bit is enabled in the mask.
+func (d *DbgLogger) DebugfBinary(bit uint64, label string, value uint64,
+	width int) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wantedM(bit) {
+		d.output(2, LevelDebug, bit, label+"="+binary(value, width))
+	}
+}
+
+// pad pads s with spaces to width runes.  A positive width pads on the right, a
+// negative width pads on the left.  Longer strings are returned unchanged.
+func pad(s string, width int) string {
+	left := width < 0
+	if left {
+		width = -width
+	}
+	n := width - utf8.RuneCountInString(s)
+	if n <= 0 {
+		return s
+	}
+	if left {
+		return strings.Repeat(" ", n) + s
+	}
+	return s + strings.Repeat(" ", n)
+}
+
+// DebugfWidth is log.Printf equivalent but pads the message with spaces to
+// width columns, on the right for a positive width and on the left for a
+// negative width.  Messages that are wider are printed as is.  It only prints
+// when debug is enabled and bit is enabled in the mask.
+func (d *DbgLogger) DebugfWidth(bit uint64, width int, format string,
+	v ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wantedM(bit) {
+		s := strings.TrimSuffix(fmt.Sprintf(format, v...), "\n")
+		d.output(2, LevelDebug, bit, pad(s, width))
+	}
+}
+
+// quoteRune returns r quoted with its code point, i.e. 'A' (U+0041).
+// Non-printable runes are escaped and invalid runes are marked as such.
+func quoteRune(r rune) string {
+	if !utf8.ValidRune(r) {
+		return fmt.Sprintf("invalid (%#x)", r)
+	}
+	return fmt.Sprintf("%v (%U)", strconv.QuoteRune(r), r)
+}
+
+// DebugfRune prints label=r with r quoted and followed by its code point, i.e.
+// label='A' (U+0041).  It only prints when debug is enabled and bit is enabled
+// in the mask.
+func (d *DbgLogger) DebugfRune(bit uint64, label string, r rune) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wantedM(bit) {
+		d.output(2, LevelDebug, bit, label+"="+quoteRune(r))
+	}
+}
+
+// DebugfChar prints label=c with c quoted and followed by its value, i.e.
+// label='A' (0x41).  Bytes that are not printable ASCII are escaped.  It only
+// prints when debug is enabled and bit is enabled in the mask.
+func (d *DbgLogger) DebugfChar(bit uint64, label string, c byte) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wantedM(bit) {
+		q := strconv.QuoteRuneToASCII(rune(c))
+		if c >= utf8.RuneSelf {
+			q = fmt.Sprintf("'\\x%02x'", c)
+		}
+		d.output(2, LevelDebug, bit,
+			fmt.Sprintf("%v=%v (%#02x)", label, q, c))
+	}
+}
+
+// DebugDumpM prints label and the length of data followed by an offset, hex
+// and ASCII dump of data in the format of hexdump -C.  It only prints when
+// debug is enabled and bit is enabled in the mask.
+func (d *DbgLogger) DebugDumpM(bit uint64, label string, data []byte) {
+	if !DebugCompiled {
+		return
+	}
+	if d.wantedM(bit) {
+		d.output(2, LevelDebug, bit, label+" "+dump(data))
+	}
+}
+
+// dump returns the length of data followed by its hex dump.
+func dump(data []byte) string {
+	return fmt.Sprintf("(%v bytes):\n%v", len(data), hex.Dump(data))
+}
@@ -0,0 +1,179 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDebugfBinary(t *testing.T) {
+	ones := strings.Repeat("_1111", 16)[1:]
+	zeros := strings.Repeat("_0000", 16)[1:]
+	tests := []struct {
+		name  string
+		value uint64
+		width int
+		want  string
+	}{
+		{"zero width 0", 0, 0, "v=0b0"},
+		{"zero width 64", 0, 64, "v=0b" + zeros},
+		{"byte", 10, 8, "v=0b0000_1010"},
+		{"partial nibble", 5, 6, "v=0b00_0101"},
+		{"too narrow", 10, 2, "v=0b1010"},
+		{"full width 0", ^uint64(0), 0, "v=0b" + ones},
+		{"full width 64", ^uint64(0), 64, "v=0b" + ones},
+		{"clamped", 1, 100, "v=0b" + zeros[:len(zeros)-1] + "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 1)
+			d.DebugfBinary(1, "v", tt.value, tt.width)
+			if got := b.String(); got != tt.want+"\n" {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDebugfWidth(t *testing.T) {
+	tests := []struct {
+		name  string
+		width int
+		msg   string
+		want  string
+	}{
+		{"under width", 6, "abc", "abc   "},
+		{"under width left", -6, "abc", "   abc"},
+		{"exact width", 3, "abc", "abc"},
+		{"exact width left", -3, "abc", "abc"},
+		{"over width", 2, "abc", "abc"},
+		{"over width left", -2, "abc", "abc"},
+		{"zero width", 0, "abc", "abc"},
+		{"runes", 4, "äöü", "äöü "},
+		{"newline", 5, "abc\n", "abc  "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 1)
+			d.DebugfWidth(1, tt.width, "%v", tt.msg)
+			if got := b.String(); got != tt.want+"\n" {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDebugfRune(t *testing.T) {
+	tests := []struct {
+		name string
+		r    rune
+		want string
+	}{
+		{"ascii", 'A', `r='A' (U+0041)`},
+		{"multibyte", 'é', `r='é' (U+00E9)`},
+		{"cjk", '世', `r='世' (U+4E16)`},
+		{"control", '\x07', `r='\a' (U+0007)`},
+		{"nul", 0, `r='\x00' (U+0000)`},
+		{"rune error", utf8.RuneError, `r='�' (U+FFFD)`},
+		{"surrogate", 0xd800, `r=invalid (0xd800)`},
+		{"out of range", 0x110000, `r=invalid (0x110000)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 1)
+			d.DebugfRune(1, "r", tt.r)
+			if got := b.String(); got != tt.want+"\n" {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDebugfChar(t *testing.T) {
+	tests := []struct {
+		name string
+		c    byte
+		want string
+	}{
+		{"ascii", 'A', `c='A' (0x41)`},
+		{"control", '\n', `c='\n' (0x0a)`},
+		{"high", 0xff, `c='\xff' (0xff)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 1)
+			d.DebugfChar(1, "c", tt.c)
+			if got := b.String(); got != tt.want+"\n" {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDebugDumpM(t *testing.T) {
+	// The ASCII column of a partial row lines up with the full rows.
+	const row0 = "00000000  30 31 32 33 34 35 36 37  " +
+		"38 39 61 62 63 64 65 66  |0123456789abcdef|"
+	pad := strings.Repeat(" ", 39)
+
+	tests := []struct {
+		name string
+		mask uint64
+		data []byte
+		want []string
+	}{
+		{
+			name: "partial last row",
+			mask: 1,
+			data: []byte("0123456789abcdef\x00hi\n"),
+			want: []string{
+				"pkt (20 bytes):",
+				row0,
+				"00000010  00 68 69 0a" + pad + "|.hi.|",
+			},
+		},
+		{
+			name: "full row",
+			mask: 1,
+			data: []byte("0123456789abcdef"),
+			want: []string{
+				"pkt (16 bytes):",
+				row0,
+			},
+		},
+		{
+			name: "empty",
+			mask: 1,
+			want: []string{"pkt (0 bytes):"},
+		},
+		{name: "masked", data: []byte("x")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, tt.mask)
+			d.DebugDumpM(1, "pkt", tt.data)
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import "fmt"
+
+// Verbose prints debug messages of one verbosity level or mask bit, see V and
+// On.  It is a small value that is meant to be used right away, not stored.
+type Verbose struct {
+	d   *DbgLogger
+	bit uint64
+	on  bool
+}
+
+// SetVerbosity sets the verbosity used by V, the default is 0.  Like the mask
+// it is shared with the loggers derived with Sub.
+// This is a runtime function that can be called at any time.
+func (d *DbgLogger) SetVerbosity(level int) {
+	d.verbose.Store(int32(level))
+}
+
+// GetVerbosity returns the verbosity set with SetVerbosity.
+func (d *DbgLogger) GetVerbosity() int {
+	return int(d.verbose.Load())
+}
+
+// V returns a Verbose whose Debug functions print when debug is enabled and
+// the verbosity is at least level, in the style of glog:
+//
+//	d.V(2).Debugf("dialing %v", addr)
+//
+//	if v := d.V(3); v.Enabled() {
+//		v.Debugf("%v", expensive())
+//	}
+//
+// Verbosity is independent of the mask, V messages carry no mask bit.
+func (d *DbgLogger) V(level int) Verbose {
+	if !DebugCompiled {
+		return Verbose{}
+	}
+	if level > d.GetVerbosity() {
+		d.count(LevelDebug, 0, false)
+		return Verbose{d: d}
+	}
+	return Verbose{d: d, on: d.wanted()}
+}
+
+// On returns a Verbose whose Debug functions print when debug is enabled and
+// bit is enabled in the mask, like DebugfM.  Calling a Debug function boxes its
+// arguments even when nothing is printed, guarding the call keeps the
+// disabled path free of allocations in hot loops:
+//
+//	if v := d.On(myDebugNet); v.Enabled() {
+//		v.Debugf("read %v bytes from %v", n, addr)
+//	}
+func (d *DbgLogger) On(bit uint64) Verbose {
+	if !DebugCompiled {
+		return Verbose{}
+	}
+	return Verbose{d: d, bit: bit, on: d.wantedM(bit)}
+}
+
+// Enabled reports whether the Debug functions of v print.
+func (v Verbose) Enabled() bool {
+	return v.on
+}
+
+// log.Printf equivalent but only prints when v is enabled.
+func (v Verbose) Debugf(format string, a ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if v.on {
+		v.d.output(2, LevelDebug, v.bit, fmt.Sprintf(format, a...))
+	}
+}
+
+// log.Print equivalent but only prints when v is enabled.
+func (v Verbose) Debug(a ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if v.on {
+		v.d.output(2, LevelDebug, v.bit, fmt.Sprint(a...))
+	}
+}
+
+// log.Println equivalent but only prints when v is enabled.
+func (v Verbose) Debugln(a ...interface{}) {
+	if !DebugCompiled {
+		return
+	}
+	if v.on {
+		v.d.output(2, LevelDebug, v.bit, fmt.Sprintln(a...))
+	}
+}
@@ -0,0 +1,159 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"io"
+	"testing"
+)
+
+// Arguments that must be boxed to be passed as an interface{}.
+var (
+	allocInt    = 123456
+	allocString = "hello"
+)
+
+// offCalls are Debug calls that print nothing and must not allocate when debug
+// is disabled or, for the ones that are masked, when bit 2 is masked off and
+// the verbosity is below 3.  Calls with boxed arguments are guarded.
+var offCalls = []struct {
+	name   string
+	masked bool
+	f      func(d *DbgLogger)
+}{
+	{"Debugf", false, func(d *DbgLogger) { d.Debugf("constant") }},
+	{"Debug", false, func(d *DbgLogger) { d.Debug("constant") }},
+	{"Debugln", false, func(d *DbgLogger) { d.Debugln("constant") }},
+	{"DebugEnabled", false, func(d *DbgLogger) {
+		if d.DebugEnabled() {
+			d.Debugf("%v %v", allocInt, allocString)
+		}
+	}},
+	{"DebugfM", true, func(d *DbgLogger) { d.DebugfM(2, "constant") }},
+	{"DebugM", true, func(d *DbgLogger) { d.DebugM(2, "constant") }},
+	{"DebuglnM", true, func(d *DbgLogger) { d.DebuglnM(2, "constant") }},
+	{"DebugEnabledM", true, func(d *DbgLogger) {
+		if d.DebugEnabledM(2) {
+			d.DebugfM(2, "%v %v", allocInt, allocString)
+		}
+	}},
+	{"On", true, func(d *DbgLogger) {
+		if v := d.On(2); v.Enabled() {
+			v.Debugf("%v %v", allocInt, allocString)
+		}
+	}},
+	{"On unguarded", true, func(d *DbgLogger) { d.On(2).Debugf("x") }},
+	{"V", true, func(d *DbgLogger) {
+		if v := d.V(3); v.Enabled() {
+			v.Debugf("%v %v", allocInt, allocString)
+		}
+	}},
+	{"V unguarded", true, func(d *DbgLogger) { d.V(3).Debugf("x") }},
+}
+
+// newOffLogger returns a logger with debug disabled or, if masked is set, with
+// debug enabled but bit 2 masked off.
+func newOffLogger(masked bool) *DbgLogger {
+	d := New(io.Discard, "", 0)
+	if masked {
+		d.Enable()
+		d.SetMask(1)
+	}
+	return d
+}
+
+func TestOffAllocs(t *testing.T) {
+	for _, masked := range []bool{false, true} {
+		d := newOffLogger(masked)
+		for _, c := range offCalls {
+			if masked && !c.masked {
+				continue
+			}
+			n := testing.AllocsPerRun(100, func() { c.f(d) })
+			if n != 0 {
+				t.Errorf("%v (masked %v): %v allocs per call",
+					c.name, masked, n)
+			}
+		}
+	}
+}
+
+// The unguarded benchmarks with arguments measure the boxing that the guarded
+// ones, which must report 0 allocs/op, avoid.
+func BenchmarkDisabledDebugf(b *testing.B) {
+	d := newOffLogger(false)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d.Debugf("%v %v", allocInt, allocString)
+	}
+}
+
+func BenchmarkDisabledDebugfConstant(b *testing.B) {
+	d := newOffLogger(false)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d.Debugf("constant")
+	}
+}
+
+func BenchmarkDisabledDebugfM(b *testing.B) {
+	d := newOffLogger(true)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d.DebugfM(2, "%v %v", allocInt, allocString)
+	}
+}
+
+func BenchmarkDisabledDebugfMConstant(b *testing.B) {
+	d := newOffLogger(true)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d.DebugfM(2, "constant")
+	}
+}
+
+func BenchmarkDisabledOn(b *testing.B) {
+	d := newOffLogger(true)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if v := d.On(2); v.Enabled() {
+			v.Debugf("%v %v", allocInt, allocString)
+		}
+	}
+}
+
+func BenchmarkDisabledV(b *testing.B) {
+	d := newOffLogger(true)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if v := d.V(3); v.Enabled() {
+			v.Debugf("%v %v", allocInt, allocString)
+		}
+	}
+}
+
+func BenchmarkDisabledDebugEnabledM(b *testing.B) {
+	d := newOffLogger(true)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if d.DebugEnabledM(2) {
+			d.DebugfM(2, "%v %v", allocInt, allocString)
+		}
+	}
+}
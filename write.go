@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bytes"
+	"io"
+	"time"
+)
+
+// SetWriteRetry makes a failed write of a line be retried up to attempts
+// times.  The delay between attempts starts at backoff and doubles after every
+// attempt.  Only the part of the line that was not written is retried so a
+// line is never duplicated or reordered.  Note that other messages are held
+// up while a line is being retried.  An attempts of 0 disables retries.
+func (d *DbgLogger) SetWriteRetry(attempts int, backoff time.Duration) {
+	if attempts < 0 {
+		attempts = 0
+	}
+	d.mu.Lock()
+	d.attempts = attempts
+	d.backoff = backoff
+	d.mu.Unlock()
+}
+
+// SetErrorHandler sets a function that is called with the error when a line
+// could not be written, after all retries failed.  The handler must not block
+// for long; it may use the logger.
+func (d *DbgLogger) SetErrorHandler(f func(error)) {
+	d.mu.Lock()
+	d.errorHandler = f
+	d.mu.Unlock()
+}
+
+// DroppedCount returns the number of lines that could not be written.
+func (d *DbgLogger) DroppedCount() uint64 {
+	return d.dropped.Load()
+}
+
+// syncer is implemented by outputs that can commit written data to stable
+// storage, such as *os.File.
+type syncer interface {
+	Sync() error
+}
+
+// SetSyncWrites makes every message be followed by a call to the Sync method
+// of the output, if it has one, so that the message is on disk before the
+// program continues.  This is useful when debugging crashes but it is very
+// expensive.  Sync errors are passed to the error handler.
+func (d *DbgLogger) SetSyncWrites(on bool) {
+	d.mu.Lock()
+	d.syncWrites = on
+	d.mu.Unlock()
+}
+
+// SetLineBuffered controls how messages are handed to the output.  Every
+// message is fully assembled before it is written.  By default a message is
+// written with a single Write, even when it spans multiple lines such as a
+// stack dump.  With line buffering enabled every line of a message is written
+// with its own single Write instead.  A line is never split across Writes,
+// unlike with a bufio.Writer, which matters for sinks that treat every Write as
+// a record and for pipes shared by several processes since writes up to
+// PIPE_BUF bytes are atomic.  Retries after a failed Write are the exception.
+func (d *DbgLogger) SetLineBuffered(on bool) {
+	d.mu.Lock()
+	d.lineBuffered = on
+	d.mu.Unlock()
+}
+
+// writeLines writes p to w with one write per line if line buffering is
+// enabled.  Must be called with mu held.
+func (d *DbgLogger) writeLines(w io.Writer, p []byte) error {
+	if !d.lineBuffered {
+		return d.write(w, p)
+	}
+	var err error
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n') + 1
+		if i == 0 {
+			i = len(p)
+		}
+		if e := d.write(w, p[:i]); e != nil && err == nil {
+			err = e
+		}
+		p = p[i:]
+	}
+	return err
+}
+
+// write writes p to w retrying as configured by SetWriteRetry.  Must be called
+// with mu held.
+func (d *DbgLogger) write(w io.Writer, p []byte) error {
+	backoff := d.backoff
+	for i := 0; ; i++ {
+		n, err := w.Write(p)
+		if err == nil && n < len(p) {
+			err = io.ErrShortWrite
+		}
+		if err == nil {
+			return nil
+		}
+		if i >= d.attempts {
+			return err
+		}
+		if n > 0 && n <= len(p) {
+			p = p[n:]
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
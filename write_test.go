@@ -0,0 +1,217 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"errors"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var errFlaky = errors.New("flaky")
+
+// flakyWriter fails the first fail writes and records the rest.
+type flakyWriter struct {
+	fail   int
+	writes []string
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	if w.fail != 0 {
+		w.fail--
+		return 0, errFlaky
+	}
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func TestSetWriteRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		fail     int
+		attempts int
+		dropped  uint64
+	}{
+		{name: "no failure", attempts: 3},
+		{name: "fail then succeed", fail: 2, attempts: 3},
+		{name: "always fails", fail: 1000, attempts: 3, dropped: 1},
+		{name: "no retries", fail: 1, dropped: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &flakyWriter{fail: tt.fail}
+			d := New(w, "", 0)
+			d.Enable()
+			d.SetWriteRetry(tt.attempts, 0)
+			var handled []error
+			d.SetErrorHandler(func(err error) {
+				handled = append(handled, err)
+			})
+
+			d.Debugf("msg")
+			if d.DroppedCount() != tt.dropped {
+				t.Fatalf("dropped %v, want %v",
+					d.DroppedCount(), tt.dropped)
+			}
+			if tt.dropped != 0 {
+				if len(handled) != 1 ||
+					!errors.Is(handled[0], errFlaky) {
+					t.Fatalf("handler got %v", handled)
+				}
+				return
+			}
+			if len(handled) != 0 {
+				t.Fatalf("handler called: %v", handled)
+			}
+			if len(w.writes) != 1 || w.writes[0] != "msg\n" {
+				t.Fatalf("got %q", w.writes)
+			}
+		})
+	}
+}
+
+// shortWriter writes at most n bytes per call.
+type shortWriter struct {
+	n   int
+	out []byte
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		p = p[:w.n]
+	}
+	w.out = append(w.out, p...)
+	return len(p), nil
+}
+
+func TestSetWriteRetryShortWrite(t *testing.T) {
+	w := &shortWriter{n: 3}
+	d := New(w, "p ", log.Lmsgprefix)
+	d.Enable()
+	d.SetWriteRetry(10, 0)
+	d.Debugf("message")
+	if got := string(w.out); got != "p message\n" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestSetLineBuffered(t *testing.T) {
+	tests := []struct {
+		name     string
+		buffered bool
+		msgs     []string
+		want     []string
+	}{
+		{
+			name: "one write per message",
+			msgs: []string{"a", "b\nc"},
+			want: []string{"a\n", "b\nc\n"},
+		},
+		{
+			name:     "one write per line",
+			buffered: true,
+			msgs: []string{"a", "b\nc",
+				strings.Repeat("x", 8192)},
+			want: []string{
+				"a\n",
+				"b\n",
+				"c\n",
+				strings.Repeat("x", 8192) + "\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &flakyWriter{}
+			d := New(w, "", 0)
+			d.Enable()
+			d.SetLineBuffered(tt.buffered)
+			for _, m := range tt.msgs {
+				d.Debugf("%v", m)
+			}
+			if !reflect.DeepEqual(w.writes, tt.want) {
+				t.Fatalf("got %q, want %q", w.writes, tt.want)
+			}
+		})
+	}
+}
+
+// syncCounter is a file that counts the calls to Sync.
+type syncCounter struct {
+	*os.File
+	syncs int
+}
+
+func (s *syncCounter) Sync() error {
+	s.syncs++
+	return s.File.Sync()
+}
+
+func TestSetSyncWrites(t *testing.T) {
+	tests := []struct {
+		name string
+		on   bool
+		want int
+	}{
+		{"off", false, 0},
+		{"on", true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.CreateTemp(t.TempDir(), "sync")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			w := &syncCounter{File: f}
+			d := New(w, "", 0)
+			d.Enable()
+			d.SetSyncWrites(tt.on)
+			for i := 0; i < 3; i++ {
+				d.Debugf("line %v", i)
+			}
+			if w.syncs != tt.want {
+				t.Fatalf("got %v syncs, want %v", w.syncs,
+					tt.want)
+			}
+			b, err := os.ReadFile(f.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != "line 0\nline 1\nline 2\n" {
+				t.Fatalf("got %q", b)
+			}
+		})
+	}
+}
+
+func TestSetSyncWritesNoSyncer(t *testing.T) {
+	w := &flakyWriter{}
+	d := New(w, "", 0)
+	d.Enable()
+	d.SetSyncWrites(true)
+	d.SetErrorHandler(func(err error) { t.Fatal(err) })
+	d.Debugf("msg")
+	if len(w.writes) != 1 {
+		t.Fatalf("got %q", w.writes)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"unicode/utf8"
+)
+
+// defaultMaxPartial is the default limit of a partial line held by a Writer.
+const defaultMaxPartial = 64 * 1024
+
+// lineWriter is the io.Writer returned by Writer.
+type lineWriter struct {
+	d        *DbgLogger
+	bit      uint64
+	classify func(l []byte) uint64 // selects the bit of a line if set
+
+	mu      sync.Mutex // protects partial
+	partial []byte     // input not yet terminated by a newline
+}
+
+// Writer returns an io.Writer that prints every line written to it as if it
+// was passed to DebugM with bit.  This allows the output of code that only
+// accepts an io.Writer to be controlled by the mask.  Input that is not newline
+// terminated is held until the rest of the line arrives or until it exceeds
+// the limit set by SetWriterMaxPartial.
+//
+// Lines ending in "\r\n" are printed without the "\r".  The returned writer
+// also implements io.Closer, Close prints a pending partial line.
+//
+// Note that this method hides the Writer method of log.Logger, use
+// d.Logger.Writer() to obtain the output.
+func (d *DbgLogger) Writer(bit uint64) io.Writer {
+	return &lineWriter{d: d, bit: bit}
+}
+
+// SetWriterMaxPartial sets the number of bytes a Writer holds while waiting for
+// the newline of a line.  Once exceeded the held input is printed as a line
+// marked with "(no newline)", cut before a rune that does not fit.  This
+// protects against sources that never write a newline.  A limit of 0 or less
+// means no limit, the default is 64KiB.
+func (d *DbgLogger) SetWriterMaxPartial(bytes int) {
+	d.mu.Lock()
+	d.maxPartial = bytes
+	d.mu.Unlock()
+}
+
+// Write splits p into lines and prints them.  It always consumes all of p.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.d.mu.Lock()
+	max := w.d.maxPartial
+	w.d.mu.Unlock()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.partial = append(w.partial, p...)
+			break
+		}
+		w.partial = append(w.partial, p[:i]...)
+		w.line(w.partial, false)
+		w.partial = w.partial[:0]
+		p = p[i+1:]
+	}
+	for max > 0 && len(w.partial) > max {
+		// Do not split a rune unless it is longer than the limit.
+		i := max
+		for i > 0 && !utf8.RuneStart(w.partial[i]) {
+			i--
+		}
+		if i == 0 {
+			i = max
+		}
+		w.line(w.partial[:i], true)
+		w.partial = append(w.partial[:0], w.partial[i:]...)
+	}
+	return n, nil
+}
+
+// Close prints a pending partial line.  The writer remains usable.
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) != 0 {
+		w.line(w.partial, true)
+		w.partial = w.partial[:0]
+	}
+	return nil
+}
+
+// line prints a single line.  Must be called with mu held.
+func (w *lineWriter) line(l []byte, forced bool) {
+	bit := w.bit
+	if w.classify != nil {
+		bit = w.classify(l)
+	}
+	if !w.d.wantedM(bit) {
+		return
+	}
+	if !forced {
+		l = bytes.TrimSuffix(l, []byte{'\r'})
+	}
+	s := string(l)
+	if forced {
+		s += " (no newline)"
+	}
+	w.d.output(3, LevelDebug, bit, s)
+}
@@ -0,0 +1,102 @@
+//go:build !dbglog_disabled
+
+/*
+ * Copyright (c) 2013 Marco Peereboom <marco@conformal.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package dbglog
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriterMaxPartial(t *testing.T) {
+	tests := []struct {
+		name   string
+		max    int
+		writes []string
+		want   []string
+	}{
+		{
+			name:   "lines",
+			max:    8,
+			writes: []string{"a\nb", "c\r\n"},
+			want:   []string{"a", "bc"},
+		},
+		{
+			name:   "forced at limit",
+			max:    4,
+			writes: []string{"abcdefghij"},
+			want: []string{
+				"abcd (no newline)",
+				"efgh (no newline)",
+			},
+		},
+		{
+			name:   "forced across writes",
+			max:    4,
+			writes: []string{"ab", "cde", "f\n"},
+			want:   []string{"abcd (no newline)", "ef"},
+		},
+		{
+			name:   "forced at rune boundary",
+			max:    4,
+			writes: []string{"abc€d", "\n"},
+			want:   []string{"abc (no newline)", "€d"},
+		},
+		{
+			name:   "forced rune longer than limit",
+			max:    2,
+			writes: []string{"€", "\n"},
+			want:   []string{"\xe2\x82 (no newline)", "\xac"},
+		},
+		{
+			name:   "unlimited",
+			max:    0,
+			writes: []string{strings.Repeat("x", 100), "\n"},
+			want:   []string{strings.Repeat("x", 100)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, b := newTestLogger("", 0, 1)
+			d.SetWriterMaxPartial(tt.max)
+			w := d.Writer(1)
+			for _, s := range tt.writes {
+				n, err := io.WriteString(w, s)
+				if n != len(s) || err != nil {
+					t.Fatalf("write %v %v", n, err)
+				}
+			}
+			if got := lines(b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriterClose(t *testing.T) {
+	d, b := newTestLogger("", 0, 1)
+	w := d.Writer(1)
+	io.WriteString(w, "partial")
+	w.(io.Closer).Close()
+	want := []string{"partial (no newline)"}
+	if got := lines(b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}